@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// The counterparty classes of the coreum multichain account txs.
+const (
+	CounterpartyFoundation = "foundation"
+	CounterpartyOperator   = "operator"
+	CounterpartyBridgeUser = "bridge-user"
+	CounterpartyThirdParty = "third-party"
+)
+
+var (
+	counterpartyClasses = []string{
+		CounterpartyFoundation,
+		CounterpartyOperator,
+		CounterpartyBridgeUser,
+		CounterpartyThirdParty,
+	}
+
+	xrplAddressRegexp = regexp.MustCompile(`^r[1-9A-HJ-NP-Za-km-z]{24,34}$`)
+)
+
+// AddressBookEntry is the known coreum or xrpl address with its counterparty class and label, both are optional.
+type AddressBookEntry struct {
+	Address string `json:"address" yaml:"address"`
+	Class   string `json:"class,omitempty" yaml:"class"`
+	Label   string `json:"label,omitempty" yaml:"label"`
+}
+
+// AddressLabels are the labels of the addresses, the empty labels mean the exports are written without the label
+// columns.
+type AddressLabels map[string]string
+
+// AddressBook classifies the counterparties of the coreum multichain account txs and labels the addresses.
+type AddressBook struct {
+	classes   map[string]string
+	labels    AddressLabels
+	memoCodec MemoCodec
+}
+
+// NewAddressBook returns new instance of the AddressBook with the foundation account and the entries, the entries
+// override the foundation account class. The memoCodec decodes the coreum memos, the nil one decodes the
+// colon-delimited memos.
+func NewAddressBook(foundationAccount string, memoCodec MemoCodec, entries ...AddressBookEntry) (AddressBook, error) {
+	classes := make(map[string]string, len(entries)+1)
+	addresses := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		if entry.Address == "" {
+			return AddressBook{}, errors.New("empty address in the address book")
+		}
+		if entry.Class == "" && entry.Label == "" {
+			return AddressBook{}, errors.Errorf("address %s has neither class nor label", entry.Address)
+		}
+		if entry.Class != "" && !lo.Contains(counterpartyClasses, entry.Class) {
+			return AddressBook{}, errors.Errorf(
+				"invalid class %q of the address %s, must be one of %v", entry.Class, entry.Address, counterpartyClasses,
+			)
+		}
+		if _, ok := addresses[entry.Address]; ok {
+			return AddressBook{}, errors.Errorf("duplicated address %s in the address book", entry.Address)
+		}
+		addresses[entry.Address] = struct{}{}
+		if entry.Class != "" {
+			classes[entry.Address] = entry.Class
+		}
+	}
+	if _, ok := classes[foundationAccount]; !ok && foundationAccount != "" {
+		classes[foundationAccount] = CounterpartyFoundation
+	}
+
+	return AddressBook{
+		classes:   classes,
+		labels:    NewAddressLabels(entries),
+		memoCodec: memoCodec,
+	}, nil
+}
+
+// NewAddressLabels returns the labels of the address book entries.
+func NewAddressLabels(entries []AddressBookEntry) AddressLabels {
+	labels := make(AddressLabels)
+	for _, entry := range entries {
+		if entry.Label != "" {
+			labels[entry.Address] = entry.Label
+		}
+	}
+
+	return labels
+}
+
+// ReadAddressBookEntries reads and validates the address book YAML file with the list of the addresses.
+func ReadAddressBookEntries(path string) ([]AddressBookEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("can't read file, path: %s, err: %s", path, err)
+	}
+	var file struct {
+		Addresses []AddressBookEntry `yaml:"addresses"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Errorf("can't decode address book, path: %s, err: %s", path, err)
+	}
+
+	if _, err := NewAddressBook("", nil, file.Addresses...); err != nil {
+		return nil, errors.Errorf("invalid address book, path: %s, err: %s", path, err)
+	}
+
+	return file.Addresses, nil
+}
+
+// Labels returns the labels of the addresses.
+func (b AddressBook) Labels() AddressLabels {
+	return b.labels
+}
+
+// ClassifyCoreumIncomingTx returns the counterparty class of the tx received by the multichain account. The unknown
+// sender with the xrpl address in the memo is the bridge user returning to xrpl.
+func (b AddressBook) ClassifyCoreumIncomingTx(tx AuditTx) string {
+	if class, ok := b.classes[tx.FromAddress]; ok {
+		return class
+	}
+	if xrplAddressRegexp.MatchString(tx.Memo) {
+		return CounterpartyBridgeUser
+	}
+
+	return CounterpartyThirdParty
+}
+
+// ClassifyCoreumOutgoingTx returns the counterparty class of the tx sent by the multichain account. The unknown
+// recipient with the xrpl tx hash in the memo is the bridge user.
+func (b AddressBook) ClassifyCoreumOutgoingTx(tx AuditTx) string {
+	if class, ok := b.classes[tx.ToAddress]; ok {
+		return class
+	}
+	if decodeXrplTxHashFromCoreumMemo(b.memoCodec, tx.Memo) != "" {
+		return CounterpartyBridgeUser
+	}
+
+	return CounterpartyThirdParty
+}
+
+// SumAuditTxsByCounterparty returns the sum of the tx amounts per counterparty class, all the classes are included.
+func SumAuditTxsByCounterparty(txs []AuditTx, classify func(AuditTx) string) map[string]*big.Int {
+	amounts := make(map[string]*big.Int, len(counterpartyClasses))
+	for _, class := range counterpartyClasses {
+		amounts[class] = big.NewInt(0)
+	}
+	for _, tx := range txs {
+		class := classify(tx)
+		amounts[class] = big.NewInt(0).Add(amounts[class], bigIntOrZero(tx.Amount))
+	}
+
+	return amounts
+}
+
+// SumAuditTxsByLabel returns the sum of the tx amounts per label of the counterparty address, the txs with the
+// unlabeled counterparty are skipped.
+func SumAuditTxsByLabel(txs []AuditTx, labels AddressLabels, counterparty func(AuditTx) string) map[string]*big.Int {
+	amounts := make(map[string]*big.Int)
+	for _, tx := range txs {
+		label, ok := labels[counterparty(tx)]
+		if !ok {
+			continue
+		}
+		amounts[label] = big.NewInt(0).Add(bigIntOrZero(amounts[label]), bigIntOrZero(tx.Amount))
+	}
+
+	return amounts
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAddressBookEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "address-book.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`addresses:
+  - address: `+fakeCoreumAddress(1)+`
+    class: operator
+    label: Multichain operator
+  - address: `+fakeCoreumAddress(2)+`
+    class: foundation
+  - address: `+defaultXrplAccount+`
+    label: Multichain MPC
+`), 0o600))
+
+	entries, err := ReadAddressBookEntries(path)
+	require.NoError(t, err)
+	addressBook, err := NewAddressBook(defaultCoreumFoundationAccount, nil, entries...)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		fakeCoreumAddress(1):           CounterpartyOperator,
+		fakeCoreumAddress(2):           CounterpartyFoundation,
+		defaultCoreumFoundationAccount: CounterpartyFoundation,
+	}, addressBook.classes)
+	require.Equal(t, AddressLabels{
+		fakeCoreumAddress(1): "Multichain operator",
+		defaultXrplAccount:   "Multichain MPC",
+	}, addressBook.Labels())
+
+	require.NoError(t, os.WriteFile(path, []byte(`addresses:
+  - address: `+fakeCoreumAddress(1)+`
+    class: exchange
+`), 0o600))
+	_, err = ReadAddressBookEntries(path)
+	require.ErrorContains(t, err, `invalid class "exchange"`)
+
+	require.NoError(t, os.WriteFile(path, []byte(`addresses:
+  - address: `+fakeCoreumAddress(1)+`
+`), 0o600))
+	_, err = ReadAddressBookEntries(path)
+	require.ErrorContains(t, err, "has neither class nor label")
+
+	_, err = ReadAddressBookEntries(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "can't read file")
+}
+
+func TestNewAddressBook(t *testing.T) {
+	// the entry overrides the foundation account class
+	addressBook, err := NewAddressBook(defaultCoreumFoundationAccount, nil, AddressBookEntry{
+		Address: defaultCoreumFoundationAccount,
+		Class:   CounterpartyOperator,
+	})
+	require.NoError(t, err)
+	require.Equal(t, CounterpartyOperator, addressBook.ClassifyCoreumIncomingTx(AuditTx{FromAddress: defaultCoreumFoundationAccount}))
+
+	_, err = NewAddressBook(defaultCoreumFoundationAccount, nil,
+		AddressBookEntry{Address: fakeCoreumAddress(1), Class: CounterpartyOperator},
+		AddressBookEntry{Address: fakeCoreumAddress(1), Class: CounterpartyThirdParty},
+	)
+	require.ErrorContains(t, err, "duplicated address")
+
+	_, err = NewAddressBook(defaultCoreumFoundationAccount, nil, AddressBookEntry{Class: CounterpartyOperator})
+	require.ErrorContains(t, err, "empty address")
+}
+
+func TestSumAuditTxsByCounterparty(t *testing.T) {
+	addressBook, err := NewAddressBook(defaultCoreumFoundationAccount, nil, AddressBookEntry{
+		Address: fakeCoreumAddress(1),
+		Class:   CounterpartyOperator,
+	})
+	require.NoError(t, err)
+
+	incomingTxs := []AuditTx{
+		{FromAddress: defaultCoreumFoundationAccount, Amount: big.NewInt(1000)},
+		{FromAddress: fakeCoreumAddress(1), Amount: big.NewInt(20)},
+		{FromAddress: fakeCoreumAddress(2), Amount: big.NewInt(30), Memo: "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"},
+		{FromAddress: fakeCoreumAddress(3), Amount: big.NewInt(40), Memo: "top up"},
+	}
+	require.Equal(t, map[string]*big.Int{
+		CounterpartyFoundation: big.NewInt(1000),
+		CounterpartyOperator:   big.NewInt(20),
+		CounterpartyBridgeUser: big.NewInt(30),
+		CounterpartyThirdParty: big.NewInt(40),
+	}, SumAuditTxsByCounterparty(incomingTxs, addressBook.ClassifyCoreumIncomingTx))
+
+	outgoingTxs := []AuditTx{
+		{ToAddress: fakeCoreumAddress(1), Amount: big.NewInt(5)},
+		{ToAddress: fakeCoreumAddress(2), Amount: big.NewInt(6), Memo: "1:0xABCD:0"},
+		{ToAddress: fakeCoreumAddress(3), Amount: big.NewInt(7), Memo: "invalid memo"},
+	}
+	require.Equal(t, map[string]*big.Int{
+		CounterpartyFoundation: big.NewInt(0),
+		CounterpartyOperator:   big.NewInt(5),
+		CounterpartyBridgeUser: big.NewInt(6),
+		CounterpartyThirdParty: big.NewInt(7),
+	}, SumAuditTxsByCounterparty(outgoingTxs, addressBook.ClassifyCoreumOutgoingTx))
+}
+
+func TestSumAuditTxsByLabel(t *testing.T) {
+	labels := AddressLabels{
+		fakeCoreumAddress(1): "Distribution address",
+		fakeCoreumAddress(2): "Distribution address",
+		fakeCoreumAddress(3): "Coreum Foundation",
+	}
+	txs := []AuditTx{
+		{FromAddress: fakeCoreumAddress(1), Amount: big.NewInt(10)},
+		{FromAddress: fakeCoreumAddress(2), Amount: big.NewInt(20)},
+		{FromAddress: fakeCoreumAddress(3), Amount: big.NewInt(30)},
+		{FromAddress: fakeCoreumAddress(4), Amount: big.NewInt(40)},
+	}
+	require.Equal(t, map[string]*big.Int{
+		"Distribution address": big.NewInt(30),
+		"Coreum Foundation":    big.NewInt(30),
+	}, SumAuditTxsByLabel(txs, labels, func(tx AuditTx) string { return tx.FromAddress }))
+}
@@ -5,6 +5,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/samber/lo"
 )
 
 const (
@@ -14,11 +16,31 @@ const (
 	DiscrepancyDifferentTargetAddressesOnXrplAndCoreum = "different target addresses on xrpl and coreum"
 	DiscrepancyDifferentAmountOnXrplAndCoreum          = "different amount on xrpl and coreum"
 	DiscrepancyOrphanCoreumTx                          = "orphan coreum tx"
+	DiscrepancyUnvalidatedXrplTx                       = "unvalidated xrpl tx"
+	DiscrepancyUnexpectedSourceChainInMemoOnCoreum     = "unexpected source chain in memo on coreum"
+	DiscrepancyUnexpectedAdditionalPayoutOnCoreum      = "unexpected additional payout on coreum"
 
-	InfoAmountOutOfRange = "not a discrepancy: amount out of range"
+	InfoAmountOutOfRange              = "not a discrepancy: amount out of range"
+	InfoRoundingDifference            = "not a discrepancy: rounding difference"
+	InfoAdditionalPayout              = "not a discrepancy: additional payout"
+	InfoAdditionalPayoutOfDiscrepancy = "not a discrepancy: additional payout of discrepancy"
 )
 
-var thousandInt = big.NewInt(1000)
+var (
+	oneMillionInt = big.NewInt(1_000_000)
+
+	allDiscrepancies = []string{
+		DiscrepancyInvalidMemoOnCoreum,
+		DiscrepancyDuplicatedXrplTxHashInMemoOnCoreum,
+		DiscrepancyOrphanXrplTx,
+		DiscrepancyDifferentTargetAddressesOnXrplAndCoreum,
+		DiscrepancyDifferentAmountOnXrplAndCoreum,
+		DiscrepancyOrphanCoreumTx,
+		DiscrepancyUnvalidatedXrplTx,
+		DiscrepancyUnexpectedSourceChainInMemoOnCoreum,
+		DiscrepancyUnexpectedAdditionalPayoutOnCoreum,
+	}
+)
 
 // AuditTx represents chain agnostic unified format of the bridge transaction.
 type AuditTx struct {
@@ -29,6 +51,16 @@ type AuditTx struct {
 	Amount        *big.Int
 	Memo          string
 	Timestamp     time.Time
+	Height        int64    // the coreum block height or the xrpl ledger index
+	Fee           *big.Int // the coreum gas fee paid by the sender, nil if there is no fee
+	Result        string   // the xrpl TransactionResult, empty if it isn't recorded (coreum txs)
+	Unvalidated   bool     // the xrpl tx isn't in a validated ledger
+}
+
+// coreumPayout is the coreum tx paying out the xrpl tx with the log index of the memo.
+type coreumPayout struct {
+	tx       AuditTx
+	logIndex int64
 }
 
 // TxDiscrepancy represent discrepancy of the xrpl and coreum transactions.
@@ -37,24 +69,59 @@ type TxDiscrepancy struct {
 	CoreumTx AuditTx
 
 	ExpectedAmount *big.Int
+	AmountDelta    *big.Int // the coreum amount minus expected amount
+	ImpliedFee     *big.Int // the xrpl amount minus coreum amount
 	BridgingTime   time.Duration
 	Discrepancy    string
 }
 
-// FindAuditTxDiscrepancies find the discrepancies between coreum and XRPL transactions.
+// AmountTolerance is the max difference between the expected and received amounts which is treated as rounding.
+// The difference is allowed if it is within the absolute or the relative tolerance.
+type AmountTolerance struct {
+	Absolute    *big.Int // in the smallest denomination
+	RelativePPM *big.Int // parts per million of the expected amount
+}
+
+// Allows returns true if the delta is within the tolerance for the expected amount.
+func (t AmountTolerance) Allows(delta, expectedAmount *big.Int) bool {
+	absDelta := big.NewInt(0).Abs(delta)
+	if t.Absolute != nil && absDelta.Cmp(t.Absolute) != 1 {
+		return true
+	}
+	if t.RelativePPM != nil && t.RelativePPM.Sign() == 1 {
+		// delta / expected <= ppm / 1_000_000, multiplied to keep the integer math
+		maxDelta := big.NewInt(0).Mul(big.NewInt(0).Abs(expectedAmount), t.RelativePPM)
+		return big.NewInt(0).Mul(absDelta, oneMillionInt).Cmp(maxDelta) != 1
+	}
+
+	return false
+}
+
+// FindAuditTxDiscrepancies find the discrepancies between coreum and XRPL transactions. The coreum payouts with the
+// other source chain ID in the memo than the non-empty sourceChainID are reported separately. The xrpl tx might be paid
+// out by several coreum txs with the different log indexes, the first one is matched and the rest are additional if
+// they pay out to the same target address, otherwise they are unexpected. The coreum memos are decoded by the
+// memoCodec, the nil one decodes the colon-delimited memos.
 func FindAuditTxDiscrepancies(
 	xrplTxs, coreumTxs []AuditTx,
 	feeConfigs []FeeConfig,
+	amountTolerance AmountTolerance,
+	memoCodec MemoCodec,
+	sourceChainID string,
 	includeAll bool,
 	beforeDateTime, afterDateTime time.Time,
 ) []TxDiscrepancy {
+	memoCodec = memoCodecOrColon(memoCodec)
 	discrepancies := make([]TxDiscrepancy, 0)
 	xrplTxsMap := make(map[string]AuditTx)
 	for _, xrplTx := range xrplTxs {
+		// the nil amount is treated as zero to compare it
+		xrplTx.Amount = bigIntOrZero(xrplTx.Amount)
 		xrplTxsMap[strings.ToUpper(xrplTx.Hash)] = xrplTx
 	}
 
-	xrplTxHashToCoreumTxMap := make(map[string]AuditTx)
+	// the payouts are ordered by the log index
+	xrplTxHashToCoreumTxsMap := make(map[string][]coreumPayout)
 
 	// we sort the configs to find first which is before
 	sort.Slice(feeConfigs, func(i, j int) bool {
@@ -62,20 +129,74 @@ func FindAuditTxDiscrepancies(
 	})
 
 	for _, coreumTx := range coreumTxs {
-		xrplTxHash := decodeXrplTxHashFromCoreumMemo(coreumTx.Memo)
-		if xrplTxHash == "" {
+		coreumTx.Amount = bigIntOrZero(coreumTx.Amount)
+		memo, err := memoCodec.DecodeCoreumMemo(coreumTx.Memo)
+		if err != nil {
 			discrepancies = append(discrepancies, fillDiscrepancy(AuditTx{}, coreumTx, DiscrepancyInvalidMemoOnCoreum, nil))
 			continue
 		}
+		if sourceChainID != "" && memo.SourceChainID != sourceChainID {
+			discrepancies = append(discrepancies, fillDiscrepancy(AuditTx{}, coreumTx, DiscrepancyUnexpectedSourceChainInMemoOnCoreum, nil))
+			continue
+		}
 
-		if _, ok := xrplTxHashToCoreumTxMap[xrplTxHash]; ok {
+		payouts := xrplTxHashToCoreumTxsMap[memo.XrplTxHash]
+		if lo.ContainsBy(payouts, func(payout coreumPayout) bool { return payout.logIndex == memo.LogIndex }) {
 			discrepancies = append(discrepancies, fillDiscrepancy(AuditTx{}, coreumTx, DiscrepancyDuplicatedXrplTxHashInMemoOnCoreum, nil))
 			continue
 		}
-		xrplTxHashToCoreumTxMap[xrplTxHash] = coreumTx
+		payouts = append(payouts, coreumPayout{tx: coreumTx, logIndex: memo.LogIndex})
+		sort.SliceStable(payouts, func(i, j int) bool {
+			return payouts[i].logIndex < payouts[j].logIndex
+		})
+		xrplTxHashToCoreumTxsMap[memo.XrplTxHash] = payouts
+	}
+
+	// takePayouts removes the payouts of the xrpl tx and returns the first one and the rest.
+	takePayouts := func(xrplTxHash string) (AuditTx, []coreumPayout, bool) {
+		payouts, ok := xrplTxHashToCoreumTxsMap[xrplTxHash]
+		if !ok {
+			return AuditTx{}, nil, false
+		}
+		delete(xrplTxHashToCoreumTxsMap, xrplTxHash)
+
+		return payouts[0].tx, payouts[1:], true
+	}
+	// reportAdditionalPayouts reports the rest payouts of the xrpl tx and returns the amount of the additional ones. The
+	// payouts of the xrpl tx whose first payout isn't matched are explained by the discrepancy of the xrpl tx, and the
+	// payouts to the other target address are unexpected.
+	reportAdditionalPayouts := func(xrplTx AuditTx, payouts []coreumPayout, firstPayoutMatched bool) *big.Int {
+		additionalAmount := big.NewInt(0)
+		for _, payout := range payouts {
+			if !firstPayoutMatched {
+				if includeAll {
+					discrepancies = append(discrepancies, fillDiscrepancy(AuditTx{}, payout.tx, InfoAdditionalPayoutOfDiscrepancy, nil))
+				}
+				continue
+			}
+			if payout.tx.TargetAddress != xrplTx.TargetAddress {
+				discrepancies = append(discrepancies, fillDiscrepancy(AuditTx{}, payout.tx, DiscrepancyUnexpectedAdditionalPayoutOnCoreum, nil))
+				continue
+			}
+			additionalAmount.Add(additionalAmount, payout.tx.Amount)
+			if includeAll {
+				discrepancies = append(discrepancies, fillDiscrepancy(AuditTx{}, payout.tx, InfoAdditionalPayout, nil))
+			}
+		}
+
+		return additionalAmount
 	}
 
 	for xrplTxHash, xrplTx := range xrplTxsMap {
+		// the tx which isn't final can't be audited, it's reported with the coreum tx if there is one
+		if isUnvalidatedXrplTx(xrplTx) {
+			coreumTx, additionalPayouts, _ := takePayouts(xrplTxHash)
+			discrepancies = append(discrepancies, fillDiscrepancy(xrplTx, coreumTx, DiscrepancyUnvalidatedXrplTx, nil))
+			reportAdditionalPayouts(xrplTx, additionalPayouts, false)
+			delete(xrplTxsMap, xrplTxHash)
+			continue
+		}
+
 		var feeConfig FeeConfig
 		for _, config := range feeConfigs {
 			if xrplTx.Timestamp.After(config.StartTime) {
@@ -84,8 +205,9 @@ func FindAuditTxDiscrepancies(
 			}
 		}
 
-		// the tx is out of range for the current min/max we can skip it
-		if feeConfig.MinAmount.Cmp(xrplTx.Amount) == 1 || feeConfig.MaxAmount.Cmp(xrplTx.Amount) == -1 {
+		// the tx is out of range for the current min/max we can skip it, the nil min/max means no limit
+		if (feeConfig.MinAmount != nil && feeConfig.MinAmount.Cmp(xrplTx.Amount) == 1) ||
+			(feeConfig.MaxAmount != nil && feeConfig.MaxAmount.Cmp(xrplTx.Amount) == -1) {
 			if includeAll {
 				discrepancies = append(discrepancies, fillDiscrepancy(xrplTx, AuditTx{}, InfoAmountOutOfRange, nil))
 			}
@@ -93,7 +215,7 @@ func FindAuditTxDiscrepancies(
 			continue
 		}
 
-		coreumTx, ok := xrplTxHashToCoreumTxMap[xrplTxHash]
+		coreumTx, additionalPayouts, ok := takePayouts(xrplTxHash)
 		if !ok {
 			discrepancies = append(discrepancies, fillDiscrepancy(xrplTx, AuditTx{}, DiscrepancyOrphanXrplTx, nil))
 			delete(xrplTxsMap, xrplTxHash)
@@ -101,16 +223,22 @@ func FindAuditTxDiscrepancies(
 		}
 		if xrplTx.TargetAddress != coreumTx.TargetAddress {
 			discrepancies = append(discrepancies, fillDiscrepancy(xrplTx, coreumTx, DiscrepancyDifferentTargetAddressesOnXrplAndCoreum, nil))
+			reportAdditionalPayouts(xrplTx, additionalPayouts, false)
 			delete(xrplTxsMap, xrplTxHash)
-			delete(xrplTxHashToCoreumTxMap, xrplTxHash)
 			continue
 		}
 
-		amountWithoutFee := computeAmountWithoutFee(xrplTx.Amount, feeConfig)
+		// the additional payouts are expected to pay out the rest of the amount
+		additionalAmount := reportAdditionalPayouts(xrplTx, additionalPayouts, true)
+		amountWithoutFee := big.NewInt(0).Sub(computeAmountWithoutFee(xrplTx.Amount, feeConfig), additionalAmount)
 		if amountWithoutFee.Cmp(coreumTx.Amount) != 0 {
-			discrepancies = append(discrepancies, fillDiscrepancy(xrplTx, coreumTx, DiscrepancyDifferentAmountOnXrplAndCoreum, amountWithoutFee))
+			discrepancy := DiscrepancyDifferentAmountOnXrplAndCoreum
+			if amountTolerance.Allows(big.NewInt(0).Sub(coreumTx.Amount, amountWithoutFee), amountWithoutFee) {
+				discrepancy = InfoRoundingDifference
+			}
+			// the rounding difference is reported even without includeAll, so the tolerated amounts are visible
+			discrepancies = append(discrepancies, fillDiscrepancy(xrplTx, coreumTx, discrepancy, amountWithoutFee))
 			delete(xrplTxsMap, xrplTxHash)
-			delete(xrplTxHashToCoreumTxMap, xrplTxHash)
 			continue
 		}
 
@@ -119,15 +247,14 @@ func FindAuditTxDiscrepancies(
 		}
 
 		delete(xrplTxsMap, xrplTxHash)
-		delete(xrplTxHashToCoreumTxMap, xrplTxHash)
 	}
-	for _, coreumTx := range xrplTxHashToCoreumTxMap {
-		discrepancies = append(discrepancies, fillDiscrepancy(AuditTx{}, coreumTx, DiscrepancyOrphanCoreumTx, nil))
+	for _, payouts := range xrplTxHashToCoreumTxsMap {
+		for _, payout := range payouts {
+			discrepancies = append(discrepancies, fillDiscrepancy(AuditTx{}, payout.tx, DiscrepancyOrphanCoreumTx, nil))
+		}
 	}
 
-	sort.Slice(discrepancies, func(i, j int) bool {
-		return discrepancies[i].XrplTx.Timestamp.After(discrepancies[j].XrplTx.Timestamp)
-	})
+	sortTxDiscrepancies(discrepancies)
 
 	// filter by xrpl timestamp
 	filteredDiscrepancies := make([]TxDiscrepancy, 0)
@@ -150,42 +277,75 @@ func FindAuditTxDiscrepancies(
 	return filteredDiscrepancies
 }
 
+// isUnvalidatedXrplTx returns true if the xrpl tx isn't in a validated ledger or its result isn't success. The empty
+// result isn't recorded, e.g. the tx is built by hand.
+func isUnvalidatedXrplTx(xrplTx AuditTx) bool {
+	return xrplTx.Unvalidated || (xrplTx.Result != "" && xrplTx.Result != xrplTxResultSuccess)
+}
+
+// filterAuditTxsByTime returns the txs with the timestamp within the time window.
+func filterAuditTxsByTime(txs []AuditTx, beforeDateTime, afterDateTime time.Time) []AuditTx {
+	filteredTxs := make([]AuditTx, 0, len(txs))
+	for _, tx := range txs {
+		if tx.Timestamp.After(beforeDateTime) || tx.Timestamp.Before(afterDateTime) {
+			continue
+		}
+		filteredTxs = append(filteredTxs, tx)
+	}
+
+	return filteredTxs
+}
+
+// sortAuditTxs sorts the txs by the timestamp descending and then by the hash, so the order is deterministic.
+func sortAuditTxs(txs []AuditTx) {
+	sort.Slice(txs, func(i, j int) bool {
+		if !txs[i].Timestamp.Equal(txs[j].Timestamp) {
+			return txs[i].Timestamp.After(txs[j].Timestamp)
+		}
+		return txs[i].Hash < txs[j].Hash
+	})
+}
+
+// sortTxDiscrepancies sorts the discrepancies by the xrpl timestamp descending, then by the coreum timestamp
+// descending, then by the xrpl and coreum hashes, so the order is deterministic.
+func sortTxDiscrepancies(discrepancies []TxDiscrepancy) {
+	sort.Slice(discrepancies, func(i, j int) bool {
+		left, right := discrepancies[i], discrepancies[j]
+		if !left.XrplTx.Timestamp.Equal(right.XrplTx.Timestamp) {
+			return left.XrplTx.Timestamp.After(right.XrplTx.Timestamp)
+		}
+		if !left.CoreumTx.Timestamp.Equal(right.CoreumTx.Timestamp) {
+			return left.CoreumTx.Timestamp.After(right.CoreumTx.Timestamp)
+		}
+		if left.XrplTx.Hash != right.XrplTx.Hash {
+			return left.XrplTx.Hash < right.XrplTx.Hash
+		}
+		if left.CoreumTx.Hash != right.CoreumTx.Hash {
+			return left.CoreumTx.Hash < right.CoreumTx.Hash
+		}
+		return left.Discrepancy < right.Discrepancy
+	})
+}
+
 func fillDiscrepancy(xrplTx, coreumTx AuditTx, discrepancy string, expectedAmount *big.Int) TxDiscrepancy {
 	bridgingTime := time.Duration(0)
 	if !xrplTx.Timestamp.IsZero() && !coreumTx.Timestamp.IsZero() {
 		bridgingTime = coreumTx.Timestamp.Sub(xrplTx.Timestamp)
 	}
 
+	var amountDelta, impliedFee *big.Int
+	if expectedAmount != nil {
+		amountDelta = big.NewInt(0).Sub(coreumTx.Amount, expectedAmount)
+		impliedFee = big.NewInt(0).Sub(xrplTx.Amount, coreumTx.Amount)
+	}
+
 	return TxDiscrepancy{
 		XrplTx:         xrplTx,
 		CoreumTx:       coreumTx,
 		ExpectedAmount: expectedAmount,
+		AmountDelta:    amountDelta,
+		ImpliedFee:     impliedFee,
 		BridgingTime:   bridgingTime,
 		Discrepancy:    discrepancy,
 	}
 }
-
-func decodeXrplTxHashFromCoreumMemo(memo string) string {
-	memoFragments := strings.Split(memo, ":")
-	if len(memoFragments) != 3 {
-		return ""
-	}
-
-	return strings.ToUpper(strings.ReplaceAll(memoFragments[1], "0x", ""))
-}
-
-// computeAmountWithoutFee computes the correct fee based on the fee config
-// fee = amount * feeRatio/1000
-// if fee <= minFee, fee = minFee
-// if fee >= maxFee, fee = maxFee
-func computeAmountWithoutFee(amount *big.Int, config FeeConfig) *big.Int {
-	fee := big.NewInt(0).Div(big.NewInt(0).Mul(amount, config.FeeRatio), thousandInt)
-	if fee.Cmp(config.MinFee) == -1 {
-		fee = config.MinFee
-	}
-	if fee.Cmp(config.MaxFee) == 1 {
-		fee = config.MaxFee
-	}
-
-	return big.NewInt(0).Sub(amount, fee)
-}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"encoding/hex"
+	"fmt"
 	"math/big"
+	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,17 +18,13 @@ func TestFindAuditTxDiscrepancies(t *testing.T) {
 
 	onePercentFeeConfigWithMinAndMaxLimits := FeeConfig{
 		StartTime: time.Date(2022, time.Month(6), 1, 0, 0, 0, 0, time.UTC),
-		FeeRatio:  big.NewInt(0),
-		MinFee:    big.NewInt(1000),
-		MaxFee:    big.NewInt(0),
+		FeeModel:  NewPerMilleFeeModel(big.NewInt(0), big.NewInt(1000), big.NewInt(0)),
 		MinAmount: big.NewInt(1000),
 		MaxAmount: big.NewInt(10000),
 	}
 	zeroFeeConfig := FeeConfig{
 		StartTime: time.Date(2022, time.Month(0), 1, 0, 0, 0, 0, time.UTC),
-		FeeRatio:  big.NewInt(0),
-		MinFee:    big.NewInt(0),
-		MaxFee:    big.NewInt(0),
+		FeeModel:  NewPerMilleFeeModel(big.NewInt(0), big.NewInt(0), big.NewInt(0)),
 		MinAmount: big.NewInt(0),
 		MaxAmount: big.NewInt(1_000_000),
 	}
@@ -299,6 +300,8 @@ func TestFindAuditTxDiscrepancies(t *testing.T) {
 						Timestamp:     time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC),
 					},
 					ExpectedAmount: big.NewInt(123),
+					AmountDelta:    big.NewInt(1),
+					ImpliedFee:     big.NewInt(-1),
 					Discrepancy:    DiscrepancyDifferentAmountOnXrplAndCoreum,
 				},
 			},
@@ -336,57 +339,373 @@ func TestFindAuditTxDiscrepancies(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			beforeDateTime := time.Date(2030, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
 			afterDateTime := time.Date(2020, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
-			got := FindAuditTxDiscrepancies(tt.args.xrplTxs, tt.args.coreumTxs, tt.args.feeConfigs, false, beforeDateTime, afterDateTime)
+			got := FindAuditTxDiscrepancies(tt.args.xrplTxs, tt.args.coreumTxs, tt.args.feeConfigs, AmountTolerance{}, nil, "", false, beforeDateTime, afterDateTime)
 			require.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func Test_computeAmountWithFees(t *testing.T) {
+func TestFindAuditTxDiscrepanciesWithAmountTolerance(t *testing.T) {
+	const bridgeChainIndex = "1111"
+
 	feeConfig := FeeConfig{
-		FeeRatio: big.NewInt(1),     // 0.1%
-		MinFee:   big.NewInt(7000),  // 0.007 CORE
-		MaxFee:   big.NewInt(50000), // 0.05 CORE
+		StartTime: time.Date(2022, time.Month(0), 1, 0, 0, 0, 0, time.UTC),
+		FeeModel:  NewPerMilleFeeModel(big.NewInt(1), big.NewInt(0), nil),
+		MinAmount: big.NewInt(0),
+		MaxAmount: big.NewInt(1_000_000_000),
 	}
-
-	type args struct {
-		amount *big.Int
-		config FeeConfig
+	xrplTx := AuditTx{
+		Hash:          "xrplHash1",
+		TargetAddress: "core1",
+		Amount:        big.NewInt(10_000_000),
+		Memo:          "core1:" + bridgeChainIndex,
+		Timestamp:     time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC),
+	}
+	coreumTx := func(amount int64) AuditTx {
+		return AuditTx{
+			Hash:          "coreHash1",
+			TargetAddress: "core1",
+			Amount:        big.NewInt(amount),
+			Memo:          bridgeChainIndex + ":" + "xrplHash1" + ":0",
+			Timestamp:     time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC),
+		}
 	}
+
 	tests := []struct {
-		name string
-		args args
-		want *big.Int
+		name       string
+		coreumTx   AuditTx
+		tolerance  AmountTolerance
+		includeAll bool
+		want       []TxDiscrepancy
 	}{
 		{
-			name: "min_fee",
-			args: args{
-				amount: big.NewInt(1_000_000),
-				config: feeConfig,
+			name:       "rounding_difference_within_absolute_tolerance",
+			coreumTx:   coreumTx(9_989_999),
+			tolerance:  AmountTolerance{Absolute: big.NewInt(1)},
+			includeAll: true,
+			want: []TxDiscrepancy{
+				{
+					XrplTx:         xrplTx,
+					CoreumTx:       coreumTx(9_989_999),
+					ExpectedAmount: big.NewInt(9_990_000),
+					AmountDelta:    big.NewInt(-1),
+					ImpliedFee:     big.NewInt(10_001),
+					Discrepancy:    InfoRoundingDifference,
+				},
 			},
-			want: big.NewInt(993000),
 		},
 		{
-			name: "max_fee",
-			args: args{
-				amount: big.NewInt(100_000_000_000),
-				config: feeConfig,
+			name:       "rounding_difference_within_relative_tolerance",
+			coreumTx:   coreumTx(9_990_009),
+			tolerance:  AmountTolerance{Absolute: big.NewInt(1), RelativePPM: big.NewInt(1)},
+			includeAll: true,
+			want: []TxDiscrepancy{
+				{
+					XrplTx:         xrplTx,
+					CoreumTx:       coreumTx(9_990_009),
+					ExpectedAmount: big.NewInt(9_990_000),
+					AmountDelta:    big.NewInt(9),
+					ImpliedFee:     big.NewInt(9_991),
+					Discrepancy:    InfoRoundingDifference,
+				},
 			},
-			want: big.NewInt(99999950000),
 		},
 		{
-			name: "fee_percent",
-			args: args{
-				amount: big.NewInt(10_000_000),
-				config: feeConfig,
+			name:      "rounding_difference_is_reported_without_include_all",
+			coreumTx:  coreumTx(9_989_999),
+			tolerance: AmountTolerance{Absolute: big.NewInt(1)},
+			want: []TxDiscrepancy{
+				{
+					XrplTx:         xrplTx,
+					CoreumTx:       coreumTx(9_989_999),
+					ExpectedAmount: big.NewInt(9_990_000),
+					AmountDelta:    big.NewInt(-1),
+					ImpliedFee:     big.NewInt(10_001),
+					Discrepancy:    InfoRoundingDifference,
+				},
+			},
+		},
+		{
+			name:      "difference_above_tolerance",
+			coreumTx:  coreumTx(9_989_000),
+			tolerance: AmountTolerance{Absolute: big.NewInt(1), RelativePPM: big.NewInt(1)},
+			want: []TxDiscrepancy{
+				{
+					XrplTx:         xrplTx,
+					CoreumTx:       coreumTx(9_989_000),
+					ExpectedAmount: big.NewInt(9_990_000),
+					AmountDelta:    big.NewInt(-1_000),
+					ImpliedFee:     big.NewInt(11_000),
+					Discrepancy:    DiscrepancyDifferentAmountOnXrplAndCoreum,
+				},
 			},
-			want: big.NewInt(9990000),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := computeAmountWithoutFee(tt.args.amount, tt.args.config)
+			beforeDateTime := time.Date(2030, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
+			afterDateTime := time.Date(2020, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
+			got := FindAuditTxDiscrepancies(
+				[]AuditTx{xrplTx}, []AuditTx{tt.coreumTx}, []FeeConfig{feeConfig}, tt.tolerance, nil, "", tt.includeAll, beforeDateTime, afterDateTime,
+			)
 			require.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func TestFindAuditTxDiscrepanciesWithNilAmountsAndEmptyFeeConfigs(t *testing.T) {
+	xrplTxs := []AuditTx{
+		{Hash: "xrplHash1", TargetAddress: "core1"},
+		{Hash: "xrplHash2", TargetAddress: "core2", Amount: big.NewInt(10)},
+	}
+	coreumTxs := []AuditTx{
+		{Hash: "coreHash1", TargetAddress: "core1", Memo: "1111:xrplHash1:0"},
+		{Hash: "coreHash2", TargetAddress: "core2", Memo: "1111:xrplHash2:0"},
+	}
+
+	discrepancies := FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "", true, selfCheckBeforeDateTime, time.Time{},
+	)
+	require.Len(t, discrepancies, 2)
+	require.Empty(t, CheckAuditInvariants(xrplTxs, coreumTxs, discrepancies, AmountTolerance{}, nil))
+
+	// the config without min and max amounts doesn't limit the amount
+	discrepancies = FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, []FeeConfig{{}}, AmountTolerance{}, nil, "", false, selfCheckBeforeDateTime, time.Time{},
+	)
+	require.Len(t, discrepancies, 1)
+	require.Equal(t, DiscrepancyDifferentAmountOnXrplAndCoreum, discrepancies[0].Discrepancy)
+	require.Equal(t, big.NewInt(-10), discrepancies[0].AmountDelta)
+}
+
+func TestFindAuditTxDiscrepanciesWithUnvalidatedXrplTxs(t *testing.T) {
+	xrplTxs := []AuditTx{
+		{Hash: "xrplHash1", TargetAddress: "core1", Amount: big.NewInt(10), Result: xrplTxResultSuccess},
+		{Hash: "xrplHash2", TargetAddress: "core2", Amount: big.NewInt(10), Result: xrplTxResultSuccess, Unvalidated: true},
+		{Hash: "xrplHash3", TargetAddress: "core3", Amount: big.NewInt(10), Result: xrplTxResultUnknown},
+	}
+	coreumTxs := []AuditTx{
+		{Hash: "coreHash1", TargetAddress: "core1", Amount: big.NewInt(10), Memo: "1111:xrplHash1:0"},
+		{Hash: "coreHash2", TargetAddress: "core2", Amount: big.NewInt(10), Memo: "1111:xrplHash2:0"},
+	}
+
+	discrepancies := FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "", true, selfCheckBeforeDateTime, time.Time{},
+	)
+	require.Empty(t, CheckAuditInvariants(xrplTxs, coreumTxs, discrepancies, AmountTolerance{}, nil))
+	categories := make(map[string]string)
+	for _, discrepancy := range discrepancies {
+		categories[discrepancy.XrplTx.Hash+"/"+discrepancy.CoreumTx.Hash] = discrepancy.Discrepancy
+	}
+	// the unvalidated txs aren't matched even if the coreum tx is found
+	require.Equal(t, map[string]string{
+		"xrplHash1/coreHash1": "",
+		"xrplHash2/coreHash2": DiscrepancyUnvalidatedXrplTx,
+		"xrplHash3/":          DiscrepancyUnvalidatedXrplTx,
+	}, categories)
+}
+
+func TestFindAuditTxDiscrepanciesWithMemoLogIndexesAndSourceChain(t *testing.T) {
+	xrplTxs := []AuditTx{
+		{Hash: "xrplHash1", TargetAddress: "core1", Amount: big.NewInt(10)},
+		{Hash: "xrplHash2", TargetAddress: "core2", Amount: big.NewInt(10)},
+		{Hash: "xrplHash3", TargetAddress: "core3", Amount: big.NewInt(10)},
+		{Hash: "xrplHash4", TargetAddress: "core4", Amount: big.NewInt(10)},
+		{Hash: "xrplHash5", TargetAddress: "core5", Amount: big.NewInt(10), Unvalidated: true},
+		{Hash: "xrplHash6", TargetAddress: "core6", Amount: big.NewInt(10)},
+	}
+	coreumTxs := []AuditTx{
+		// the payouts are ordered by the log index, not by the input order
+		{Hash: "coreHash2", TargetAddress: "core1", Amount: big.NewInt(4), Memo: "1111:xrplHash1:1"},
+		{Hash: "coreHash1", TargetAddress: "core1", Amount: big.NewInt(6), Memo: "1111:xrplHash1:0"},
+		{Hash: "coreHash3", TargetAddress: "core2", Amount: big.NewInt(10), Memo: "1111:xrplHash2:0"},
+		{Hash: "coreHash4", TargetAddress: "core2", Amount: big.NewInt(10), Memo: "1111:xrplHash2:0"},
+		{Hash: "coreHash5", TargetAddress: "core3", Amount: big.NewInt(10), Memo: "9999:xrplHash3:0"},
+		// the additional payout to the other address doesn't pay out the rest of the amount
+		{Hash: "coreHash6", TargetAddress: "core4", Amount: big.NewInt(6), Memo: "1111:xrplHash4:0"},
+		{Hash: "coreHash7", TargetAddress: "core1", Amount: big.NewInt(4), Memo: "1111:xrplHash4:1"},
+		// the additional payout of the unvalidated tx
+		{Hash: "coreHash8", TargetAddress: "core5", Amount: big.NewInt(6), Memo: "1111:xrplHash5:0"},
+		{Hash: "coreHash9", TargetAddress: "core5", Amount: big.NewInt(4), Memo: "1111:xrplHash5:1"},
+		// the additional payout of the tx paid out to the other address
+		{Hash: "coreHash10", TargetAddress: "core1", Amount: big.NewInt(6), Memo: "1111:xrplHash6:0"},
+		{Hash: "coreHash11", TargetAddress: "core6", Amount: big.NewInt(4), Memo: "1111:xrplHash6:1"},
+	}
+
+	discrepancies := FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "1111", true, selfCheckBeforeDateTime, time.Time{},
+	)
+	require.Empty(t, CheckAuditInvariants(xrplTxs, coreumTxs, discrepancies, AmountTolerance{}, nil))
+	categories := make(map[string]string)
+	for _, discrepancy := range discrepancies {
+		categories[discrepancy.XrplTx.Hash+"/"+discrepancy.CoreumTx.Hash] = discrepancy.Discrepancy
+	}
+	require.Equal(t, map[string]string{
+		"xrplHash1/coreHash1":  "",
+		"/coreHash2":           InfoAdditionalPayout,
+		"xrplHash2/coreHash3":  "",
+		"/coreHash4":           DiscrepancyDuplicatedXrplTxHashInMemoOnCoreum,
+		"xrplHash3/":           DiscrepancyOrphanXrplTx,
+		"/coreHash5":           DiscrepancyUnexpectedSourceChainInMemoOnCoreum,
+		"xrplHash4/coreHash6":  DiscrepancyDifferentAmountOnXrplAndCoreum,
+		"/coreHash7":           DiscrepancyUnexpectedAdditionalPayoutOnCoreum,
+		"xrplHash5/coreHash8":  DiscrepancyUnvalidatedXrplTx,
+		"/coreHash9":           InfoAdditionalPayoutOfDiscrepancy,
+		"xrplHash6/coreHash10": DiscrepancyDifferentTargetAddressesOnXrplAndCoreum,
+		"/coreHash11":          InfoAdditionalPayoutOfDiscrepancy,
+	}, categories)
+
+	// the additional payouts are hidden without the includeAll, the unexpected one is reported
+	discrepancies = FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "1111", false, selfCheckBeforeDateTime, time.Time{},
+	)
+	require.Len(t, discrepancies, 7)
+	require.Equal(t, 1, lo.CountBy(discrepancies, func(discrepancy TxDiscrepancy) bool {
+		return discrepancy.Discrepancy == DiscrepancyUnexpectedAdditionalPayoutOnCoreum
+	}))
+
+	// the empty source chain ID accepts any
+	discrepancies = FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "", false, selfCheckBeforeDateTime, time.Time{},
+	)
+	require.Len(t, discrepancies, 5)
+	require.False(t, lo.ContainsBy(discrepancies, func(discrepancy TxDiscrepancy) bool {
+		return discrepancy.Discrepancy == DiscrepancyUnexpectedSourceChainInMemoOnCoreum
+	}))
+}
+
+func FuzzFindAuditTxDiscrepancies(f *testing.F) {
+	f.Add([]byte{0, 1, 1, 0, 10, 1, 1, 1, 0, 10}, uint8(0), int64(0))
+	f.Add([]byte{0, 1, 1, 0, 10, 1, 1, 1, 0, 9, 3, 1, 2, 0, 0, 5, 2, 0, 0, 1}, uint8(1), int64(1))
+	f.Add([]byte{0, 0, 0, 0, 0, 7, 0, 0, 255, 255}, uint8(2), int64(0))
+
+	f.Fuzz(func(t *testing.T, data []byte, feeConfigsKind uint8, absoluteTolerance int64) {
+		xrplTxs, coreumTxs := decodeFuzzAuditTxs(data)
+		var feeConfigs []FeeConfig
+		switch feeConfigsKind % 3 {
+		case 1:
+			feeConfigs = []FeeConfig{{}}
+		case 2:
+			feeConfigs = []FeeConfig{
+				{
+					StartTime: time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC),
+					FeeModel:  NewPerMilleFeeModel(big.NewInt(1), big.NewInt(2), big.NewInt(100)),
+					MinAmount: big.NewInt(5),
+					MaxAmount: big.NewInt(60_000),
+				},
+				{
+					StartTime: time.Date(2022, time.Month(1), 1, 0, 0, 0, 0, time.UTC),
+					FeeModel:  FixedFeeModel{Amount: big.NewInt(1)},
+				},
+			}
+		}
+		tolerance := AmountTolerance{Absolute: big.NewInt(absoluteTolerance % 100), RelativePPM: big.NewInt(absoluteTolerance % 1000)}
+
+		violations := SelfCheckAudit(xrplTxs, coreumTxs, feeConfigs, tolerance, nil, "")
+		require.Empty(t, violations)
+	})
+}
+
+// decodeFuzzAuditTxs decodes the xrpl and coreum txs from the fuzz data, each tx is encoded with 5 bytes.
+// The hashes and the target addresses are taken from the small sets to make the collisions likely.
+func decodeFuzzAuditTxs(data []byte) ([]AuditTx, []AuditTx) {
+	const txSize = 5
+	xrplTxs := make([]AuditTx, 0)
+	coreumTxs := make([]AuditTx, 0)
+	for i := 0; i+txSize <= len(data); i += txSize {
+		kind, hashIndex, targetIndex := data[i], data[i+1]%8, data[i+2]%3
+		var amount *big.Int
+		if rawAmount := int64(data[i+3])<<8 | int64(data[i+4]); rawAmount != 0 {
+			amount = big.NewInt(rawAmount)
+		}
+		xrplTxHash := fmt.Sprintf("xrplHash%d", hashIndex)
+		targetAddress := fmt.Sprintf("core%d", targetIndex)
+		timestamp := time.Date(2021, time.Month(1), 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(data[i+2]) * 24 * time.Hour)
+
+		if kind%2 == 0 {
+			xrplTxs = append(xrplTxs, AuditTx{
+				Hash:          xrplTxHash,
+				TargetAddress: targetAddress,
+				Amount:        amount,
+				Memo:          targetAddress + ":1111",
+				Timestamp:     timestamp,
+			})
+			continue
+		}
+
+		memo := fmt.Sprintf("1111:0x%s:0", xrplTxHash)
+		switch kind % 8 {
+		case 3:
+			memo = "invalid memo"
+		case 5:
+			memo = fmt.Sprintf("1111:%s:0", strings.ToLower(xrplTxHash))
+		}
+		coreumTxs = append(coreumTxs, AuditTx{
+			Hash:          fmt.Sprintf("coreHash%d", i/txSize),
+			TargetAddress: targetAddress,
+			Amount:        amount,
+			Memo:          memo,
+			Timestamp:     timestamp,
+		})
+	}
+
+	return xrplTxs, coreumTxs
+}
+
+func FuzzDecodeXrplTxHashFromCoreumMemo(f *testing.F) {
+	f.Add("1111:0xABCD:0")
+	f.Add("1111:abcd:0")
+	f.Add("invalid memo")
+	f.Add("::")
+
+	f.Fuzz(func(t *testing.T, memo string) {
+		hash := decodeXrplTxHashFromCoreumMemo(nil, memo)
+		require.Equal(t, strings.ToUpper(hash), hash)
+		require.NotContains(t, hash, "0x")
+		if strings.Count(memo, ":") != 2 {
+			require.Empty(t, hash)
+		}
+
+		// the hash of the memo built by the bridge is decoded back
+		xrplTxHash := strings.ToUpper(hex.EncodeToString([]byte(memo)))
+		require.Equal(t, xrplTxHash, decodeXrplTxHashFromCoreumMemo(nil, fmt.Sprintf("1111:0x%s:0", xrplTxHash)))
+	})
+}
+
+func TestFindAuditTxDiscrepanciesIsDeterministic(t *testing.T) {
+	txTime := time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
+	xrplTxs := make([]AuditTx, 0)
+	coreumTxs := make([]AuditTx, 0)
+	for i := 0; i < 20; i++ {
+		// the txs have the same timestamps
+		xrplTxs = append(xrplTxs, AuditTx{
+			Hash:          fmt.Sprintf("xrplHash%d", i),
+			TargetAddress: "core1",
+			Amount:        big.NewInt(10),
+			Timestamp:     txTime,
+		})
+		// the orphan coreum txs have zero xrpl timestamps
+		coreumTxs = append(coreumTxs, AuditTx{
+			Hash:          fmt.Sprintf("coreHash%d", i),
+			TargetAddress: "core1",
+			Amount:        big.NewInt(10),
+			Memo:          fmt.Sprintf("1111:unknownHash%d:0", i),
+			Timestamp:     txTime.Add(time.Duration(i%2) * time.Hour),
+		})
+	}
+
+	want := FindAuditTxDiscrepancies(xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "", true, selfCheckBeforeDateTime, time.Time{})
+	require.Len(t, want, 40)
+	require.Equal(t, "xrplHash0", want[0].XrplTx.Hash)
+	require.Equal(t, "xrplHash1", want[1].XrplTx.Hash)
+	require.Equal(t, "xrplHash10", want[2].XrplTx.Hash)
+	require.Equal(t, "coreHash1", want[20].CoreumTx.Hash)
+	require.Equal(t, "coreHash0", want[30].CoreumTx.Hash)
+
+	for i := 0; i < 10; i++ {
+		rand.Shuffle(len(xrplTxs), func(i, j int) { xrplTxs[i], xrplTxs[j] = xrplTxs[j], xrplTxs[i] })
+		rand.Shuffle(len(coreumTxs), func(i, j int) { coreumTxs[i], coreumTxs[j] = coreumTxs[j], coreumTxs[i] })
+		got := FindAuditTxDiscrepancies(xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "", true, selfCheckBeforeDateTime, time.Time{})
+		require.Equal(t, want, got)
+	}
+}
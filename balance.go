@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/pkg/errors"
+)
+
+// BalanceHistoryEntry is the account balance change by the tx, the balance is reconstructed from the txs and
+// optionally checked against the balance queried at the tx height.
+type BalanceHistoryEntry struct {
+	Account        string    `json:"account"`
+	AccountLabel   string    `json:"accountLabel,omitempty"`
+	Height         int64     `json:"height"`
+	Timestamp      time.Time `json:"timestamp"`
+	Hash           string    `json:"hash"`
+	Amount         *big.Int  `json:"amount"`                   // the balance change, negative for the outgoing tx with its fee
+	Balance        *big.Int  `json:"balance"`                  // the reconstructed balance after the tx
+	QueriedBalance *big.Int  `json:"queriedBalance,omitempty"` // the balance queried at the height, nil if not sampled
+	Gap            *big.Int  `json:"gap,omitempty"`            // the queried minus reconstructed balance
+}
+
+// BuildBalanceHistory reconstructs the account balance timeline from its incoming and outgoing txs with the gas fees,
+// the balance is accumulated from the opening balance. The entries are ordered by height.
+func BuildBalanceHistory(account string, openingBalance *big.Int, incomingTxs, outgoingTxs []AuditTx) []BalanceHistoryEntry {
+	history := make([]BalanceHistoryEntry, 0, len(incomingTxs)+len(outgoingTxs))
+	for _, tx := range incomingTxs {
+		history = append(history, BalanceHistoryEntry{
+			Account:   account,
+			Height:    tx.Height,
+			Timestamp: tx.Timestamp,
+			Hash:      tx.Hash,
+			Amount:    bigIntOrZero(tx.Amount),
+		})
+	}
+	for _, tx := range outgoingTxs {
+		history = append(history, BalanceHistoryEntry{
+			Account:   account,
+			Height:    tx.Height,
+			Timestamp: tx.Timestamp,
+			Hash:      tx.Hash,
+			Amount:    big.NewInt(0).Neg(big.NewInt(0).Add(bigIntOrZero(tx.Amount), bigIntOrZero(tx.Fee))),
+		})
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		if history[i].Height != history[j].Height {
+			return history[i].Height < history[j].Height
+		}
+		return history[i].Hash < history[j].Hash
+	})
+
+	balance := bigIntOrZero(openingBalance)
+	for i := range history {
+		balance = big.NewInt(0).Add(balance, history[i].Amount)
+		history[i].Balance = balance
+	}
+
+	return history
+}
+
+// CheckBalanceHistory queries the account balance at the sampled heights of the history and sets the queried balance
+// and the gap. The gap which changes between the samples means that some transfers are missed by the tx filters.
+func CheckBalanceHistory(
+	ctx context.Context,
+	clientCtx client.Context,
+	history []BalanceHistoryEntry,
+	denom string,
+	samples int,
+) error {
+	for _, i := range sampleBalanceHistory(history, samples) {
+		queriedBalance, err := GetCoreumAccountBalance(
+			ctx, clientCtx.WithHeight(history[i].Height), history[i].Account, denom,
+		)
+		if err != nil {
+			return err
+		}
+		history[i].QueriedBalance = queriedBalance
+		history[i].Gap = big.NewInt(0).Sub(queriedBalance, history[i].Balance)
+	}
+
+	return nil
+}
+
+// sampleBalanceHistory returns the indexes of the evenly spaced entries including the last one, the entry is the last
+// one at its height, so the reconstructed balance includes all the txs of the height.
+func sampleBalanceHistory(history []BalanceHistoryEntry, samples int) []int {
+	lastAtHeight := make([]int, 0)
+	for i := range history {
+		if i == len(history)-1 || history[i].Height != history[i+1].Height {
+			lastAtHeight = append(lastAtHeight, i)
+		}
+	}
+	if samples <= 0 || len(lastAtHeight) == 0 {
+		return nil
+	}
+	if samples >= len(lastAtHeight) {
+		return lastAtHeight
+	}
+
+	indexes := make([]int, 0, samples)
+	for sample := 1; sample <= samples; sample++ {
+		indexes = append(indexes, lastAtHeight[sample*len(lastAtHeight)/samples-1])
+	}
+
+	return indexes
+}
+
+// WriteBalanceHistoryToJSON writes the balance history with the account labels.
+func WriteBalanceHistoryToJSON(history []BalanceHistoryEntry, labels AddressLabels, path string) error {
+	labeledHistory := make([]BalanceHistoryEntry, 0, len(history))
+	for _, entry := range history {
+		entry.AccountLabel = labels[entry.Account]
+		labeledHistory = append(labeledHistory, entry)
+	}
+	data, err := json.MarshalIndent(labeledHistory, "", "  ")
+	if err != nil {
+		return errors.Errorf("can't encode balance history, err: %s", err)
+	}
+
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return errors.Errorf("can't write file, path: %s, err: %s", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildBalanceHistory(t *testing.T) {
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	history := BuildBalanceHistory(
+		defaultCoreumAccount,
+		big.NewInt(1000),
+		[]AuditTx{
+			{Hash: "IN2", Amount: big.NewInt(5), Height: 103, Timestamp: txTime.Add(3 * time.Minute)},
+			{Hash: "IN1", Amount: big.NewInt(100), Height: 100, Timestamp: txTime},
+		},
+		[]AuditTx{
+			{Hash: "OUT1", Amount: big.NewInt(30), Fee: big.NewInt(2), Height: 101, Timestamp: txTime.Add(time.Minute)},
+		},
+	)
+	require.Equal(t, []BalanceHistoryEntry{
+		{Account: defaultCoreumAccount, Height: 100, Timestamp: txTime, Hash: "IN1", Amount: big.NewInt(100), Balance: big.NewInt(1100)},
+		{Account: defaultCoreumAccount, Height: 101, Timestamp: txTime.Add(time.Minute), Hash: "OUT1", Amount: big.NewInt(-32), Balance: big.NewInt(1068)},
+		{Account: defaultCoreumAccount, Height: 103, Timestamp: txTime.Add(3 * time.Minute), Hash: "IN2", Amount: big.NewInt(5), Balance: big.NewInt(1073)},
+	}, history)
+}
+
+func Test_sampleBalanceHistory(t *testing.T) {
+	history := make([]BalanceHistoryEntry, 0)
+	for _, height := range []int64{100, 101, 101, 102, 103, 103, 104} {
+		history = append(history, BalanceHistoryEntry{Height: height})
+	}
+
+	require.Nil(t, sampleBalanceHistory(history, 0))
+	require.Nil(t, sampleBalanceHistory(nil, 3))
+	// the last entry of each height
+	require.Equal(t, []int{0, 2, 3, 5, 6}, sampleBalanceHistory(history, 10))
+	require.Equal(t, []int{0, 3, 6}, sampleBalanceHistory(history, 3))
+	require.Equal(t, []int{6}, sampleBalanceHistory(history, 1))
+}
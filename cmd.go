@@ -3,9 +3,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
 )
@@ -28,6 +35,52 @@ const (
 	outputDocumentFlag          = "output-document"
 	includeAllFlag              = "include-all"
 	multichainRescanAPIURLFlag  = "multichain-rescan-api-url"
+	httpRequestTimeoutFlag      = "http-request-timeout"
+	httpMaxRetriesFlag          = "http-max-retries"
+	httpMinBackoffFlag          = "http-min-backoff"
+	httpMaxBackoffFlag          = "http-max-backoff"
+	httpRateLimitFlag           = "http-rate-limit"
+	httpRateBurstFlag           = "http-rate-burst"
+	endpointMaxLagFlag          = "endpoint-max-lag"
+	endpointCooldownFlag        = "endpoint-cooldown"
+	endpointHealthCheckFlag     = "endpoint-health-check-interval"
+	xrplCrossCheckSampleFlag    = "xrpl-cross-check-sample"
+	dryRunFlag                  = "dry-run"
+	rescanPoolSizeFlag          = "rescan-pool-size"
+	rescanFailedFromFlag        = "rescan-failed-from"
+	rescanDiscrepanciesFlag     = "rescan-discrepancies"
+	rescanHashesFileFlag        = "hashes-file"
+	rescanSrcChainIDFlag        = "src-chain-id"
+	rescanDestChainIDFlag       = "dest-chain-id"
+	rescanResultsFlag           = "rescan-results"
+	trackIntervalFlag           = "track-interval"
+	trackIterationsFlag         = "track-iterations"
+	trackOrphanAfterFlag        = "orphan-after"
+	recordFlag                  = "record"
+	replayFlag                  = "replay"
+	amountToleranceFlag         = "amount-tolerance"
+	amountRelativeToleranceFlag = "amount-relative-tolerance-ppm"
+	xrplTxsFileFlag             = "xrpl-txs-file"
+	coreumTxsFileFlag           = "coreum-txs-file"
+	bundleDirFlag               = "bundle-dir"
+	refetchFlag                 = "refetch"
+	coreumHeightFlag            = "coreum-height"
+	xrplLedgerIndexFlag         = "xrpl-ledger-index"
+	xrplSupplySourceFlag        = "xrpl-supply-source"
+	balanceSamplesFlag          = "balance-samples"
+	coreumGenesisFileFlag       = "coreum-genesis-file"
+	coreumOpeningBalanceFlag    = "coreum-opening-balance"
+	addressBookFlag             = "address-book"
+	coreumMemoSourceChainIDFlag = "coreum-memo-source-chain-id"
+	memoCodecFlag               = "memo-codec"
+	xrplMemoTypeFlag            = "xrpl-memo-type"
+	xrplMemoFormatFlag          = "xrpl-memo-format"
+	coreumMemoFormatFlag        = "coreum-memo-format"
+	xrplDestinationTagsFlag     = "xrpl-destination-tags"
+	routesFileFlag              = "routes-file"
+	feeScheduleFileFlag         = "fee-schedule-file"
+	routeFlag                   = "route"
+	allRoutesFlag               = "all-routes"
 )
 
 const (
@@ -40,11 +93,27 @@ const (
 	defaultXrplHistoricalAPIURL = "https://data.ripple.com"
 	defaultXrplScanAPIURL       = "https://api.xrpscan.com"
 
-	defaultXrplAccount            = "rcoreNywaoz2ZCQ8Lg2EbSLnGuRBmun6D"
-	defaultXrplCurrency           = "434F524500000000000000000000000000000000"
-	defaultXrplIssuer             = "rcoreNywaoz2ZCQ8Lg2EbSLnGuRBmun6D"
-	defaultBridgeChainIndex       = "1007961752909"
-	defaultMultichainRescanAPIURL = "https://scanapi.multichain.org"
+	defaultXrplAccount             = "rcoreNywaoz2ZCQ8Lg2EbSLnGuRBmun6D"
+	defaultXrplCurrency            = "434F524500000000000000000000000000000000"
+	defaultXrplIssuer              = "rcoreNywaoz2ZCQ8Lg2EbSLnGuRBmun6D"
+	defaultBridgeChainIndex        = "1007961752909"
+	defaultCoreumMemoSourceChainID = "1000005788240"
+	defaultMultichainRescanAPIURL  = "https://scanapi.multichain.org"
+	defaultRescanPoolSize          = 5
+	defaultRescanSrcChainID        = "XRP"
+	defaultRescanDestChainID       = "ATOM_DCORE"
+	defaultTrackInterval           = 10 * time.Minute
+	defaultTrackOrphanAfter        = 24 * time.Hour
+
+	defaultHTTPRequestTimeout = 10 * time.Second
+	defaultHTTPMaxRetries     = 10
+	defaultHTTPMinBackoff     = 500 * time.Millisecond
+	defaultHTTPMaxBackoff     = 30 * time.Second
+	defaultHTTPRateBurst      = 10
+
+	defaultEndpointMaxLag              = 10
+	defaultEndpointCooldown            = 30 * time.Second
+	defaultEndpointHealthCheckInterval = time.Minute
 )
 
 var (
@@ -55,30 +124,124 @@ var (
 func rootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Short: "Multichain Auditor",
+		// the HTTP client and its health checks are shared by all the runs of the command in the process
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			cmd.SetContext(withHTTPClientCache(ctx))
+		},
 	}
 
 	cmd.AddCommand(coreumCmd())
 	cmd.AddCommand(xrplCmd())
 	cmd.AddCommand(discrepancyCmd())
 	cmd.AddCommand(summaryCmd())
+	cmd.AddCommand(auditCmd())
+	cmd.AddCommand(reportCmd())
+	runPerRoute(cmd)
 
-	cmd.PersistentFlags().String(coreumNodeFlag, defaultCoreumRPC, "coreum rpc address")
+	cmd.PersistentFlags().StringSlice(coreumNodeFlag, []string{defaultCoreumRPC}, "coreum rpc addresses, the requests are distributed across all of them")
 	cmd.PersistentFlags().String(coreumAccountFlag, defaultCoreumAccount, "multichain account on coreum")
 	cmd.PersistentFlags().String(coreumFoundationAccountFlag, defaultCoreumFoundationAccount, "foundation account on coreum")
 	cmd.PersistentFlags().String(beforeDateTimeFlag, defaultBeforeDateTime.Format(time.DateTime), fmt.Sprintf("UTC date and time to fetch from, format: %s", time.DateTime))
 	cmd.PersistentFlags().String(afterDateTimeFlag, defaultAfterDateTime.Format(time.DateTime), fmt.Sprintf("UTC date and time to fetch to, format: %s", time.DateTime))
-	cmd.PersistentFlags().String(xrplRPCAPIURLFlag, defaultXrplRPCAPIURL, "xrpl RPC address")
+	cmd.PersistentFlags().StringSlice(xrplRPCAPIURLFlag, []string{defaultXrplRPCAPIURL}, "xrpl RPC addresses, the requests are distributed across all of them")
+	cmd.PersistentFlags().String(coreumGenesisFileFlag, "", "coreum genesis file to read the opening balances of the multichain and foundation accounts from")
+	cmd.PersistentFlags().Int64(coreumOpeningBalanceFlag, 0, "opening balance of the multichain coreum account not explained by the txs, in the smallest denomination, can't be used with the genesis file")
+	cmd.PersistentFlags().String(addressBookFlag, "", "address book YAML file with the counterparty classes and labels of the known coreum and xrpl addresses")
+	cmd.PersistentFlags().Int64(coreumHeightFlag, 0, "coreum height to read the txs and balances as of, zero means the latest")
+	cmd.PersistentFlags().Int64(xrplLedgerIndexFlag, 0, "xrpl ledger index to read the txs and supply as of, zero means the latest validated")
+	cmd.PersistentFlags().Int(xrplCrossCheckSampleFlag, 0, "number of the fetched xrpl txs to cross-check between all xrpl RPC addresses")
 	cmd.PersistentFlags().Int(xrplFetchPoolSizeFlag, defaultXrplFetchPoolSize, "xrpl fetch pool size")
 	cmd.PersistentFlags().String(xrplHistoricalAPIURLFlag, defaultXrplHistoricalAPIURL, "xrpl historical API address")
 	cmd.PersistentFlags().String(xrplScanAPIURLFlag, defaultXrplScanAPIURL, "xrpl scan API address")
+	cmd.PersistentFlags().String(xrplSupplySourceFlag, XrplSupplySourceXrpscan, fmt.Sprintf("source of the xrpl currency supply, one of: %s", strings.Join(xrplSupplySources, ", ")))
 	cmd.PersistentFlags().String(xrplAccountFlag, defaultXrplAccount, "xrpl account")
 	cmd.PersistentFlags().String(xrplCurrencyFlag, defaultXrplCurrency, "xrpl hex currency")
 	cmd.PersistentFlags().String(xrplIssuerFlag, defaultXrplIssuer, "xrpl issuer")
 	cmd.PersistentFlags().String(bridgeChainIndexFlag, defaultBridgeChainIndex, "xrpl chain index")
+	cmd.PersistentFlags().String(coreumMemoSourceChainIDFlag, defaultCoreumMemoSourceChainID, "expected source chain ID in the memos of the coreum payouts, empty to accept any")
+	cmd.PersistentFlags().String(memoCodecFlag, MemoCodecColon, fmt.Sprintf("codec of the xrpl and coreum bridge memos, one of: %s", strings.Join(memoCodecs, ", ")))
+	cmd.PersistentFlags().String(xrplMemoTypeFlag, "", "MemoType of the xrpl bridge memos decoded by the typed memo codec")
+	cmd.PersistentFlags().String(xrplMemoFormatFlag, "", "MemoFormat of the xrpl bridge memos decoded by the typed memo codec, empty to accept any")
+	cmd.PersistentFlags().String(coreumMemoFormatFlag, MemoCodecColon, fmt.Sprintf("format of the coreum payout memos decoded by the typed memo codec, one of: %s", strings.Join(coreumMemoFormats, ", ")))
+	cmd.PersistentFlags().String(routesFileFlag, "", "YAML file with the named routes of the audited bridge pairs")
+	cmd.PersistentFlags().String(feeScheduleFileFlag, "", "YAML file with the fee schedule, the default one is the schedule of the CORE bridge")
+	cmd.PersistentFlags().String(routeFlag, "", "name of the route of the routes file to audit")
+	cmd.PersistentFlags().Bool(allRoutesFlag, false, "run the command for each route of the routes file, the output file names get the route name")
+	cmd.PersistentFlags().String(xrplDestinationTagsFlag, "", "YAML file with the coreum addresses of the xrpl destination tags decoded by the destination-tag memo codec")
+	cmd.PersistentFlags().Duration(httpRequestTimeoutFlag, defaultHTTPRequestTimeout, "timeout of a single http request attempt")
+	cmd.PersistentFlags().Int(httpMaxRetriesFlag, defaultHTTPMaxRetries, "max number of the http request retries")
+	cmd.PersistentFlags().Duration(httpMinBackoffFlag, defaultHTTPMinBackoff, "initial delay of the exponential backoff between the http request retries")
+	cmd.PersistentFlags().Duration(httpMaxBackoffFlag, defaultHTTPMaxBackoff, "max delay of the exponential backoff between the http request retries")
+	cmd.PersistentFlags().Float64(httpRateLimitFlag, 0, "max number of the http requests per second per host, zero means unlimited")
+	cmd.PersistentFlags().Int(httpRateBurstFlag, defaultHTTPRateBurst, "max burst of the http requests per host")
+	cmd.PersistentFlags().Int64(endpointMaxLagFlag, defaultEndpointMaxLag, "max number of blocks or ledgers the endpoint might be behind the others to stay in the rotation")
+	cmd.PersistentFlags().Duration(endpointCooldownFlag, defaultEndpointCooldown, "duration the failed endpoint is excluded from the rotation")
+	cmd.PersistentFlags().Duration(endpointHealthCheckFlag, defaultEndpointHealthCheckInterval, "interval of the endpoints health checks, zero disables the periodic checks")
+	cmd.PersistentFlags().String(recordFlag, "", "dir to record all the http and rpc responses to")
+	cmd.PersistentFlags().String(replayFlag, "", "dir to replay the recorded http and rpc responses from without network")
+	cmd.PersistentFlags().Int64(amountToleranceFlag, 0, "max absolute difference of the expected and received amounts treated as rounding, in the smallest denomination")
+	cmd.PersistentFlags().Int64(amountRelativeToleranceFlag, 0, "max difference of the expected and received amounts treated as rounding, in parts per million of the expected amount")
 
 	return cmd
 }
 
+const (
+	// aggregatesRoutesAnnotation marks the command which runs all the routes itself to aggregate their results.
+	aggregatesRoutesAnnotation = "aggregates-routes"
+	// routeIndependentAnnotation marks the command which doesn't read the route config, e.g. the command which reads
+	// the files or the bundle written before.
+	routeIndependentAnnotation = "route-independent"
+)
+
+// runPerRoute wraps the commands to run them once per route with the --all-routes. The commands with the
+// aggregatesRoutesAnnotation run all the routes themselves, and the commands with the routeIndependentAnnotation
+// reject the route flags.
+func runPerRoute(cmd *cobra.Command) {
+	for _, subCmd := range cmd.Commands() {
+		runPerRoute(subCmd)
+	}
+	runE := cmd.RunE
+	if runE == nil {
+		return
+	}
+	if _, ok := cmd.Annotations[aggregatesRoutesAnnotation]; ok {
+		return
+	}
+	if _, ok := cmd.Annotations[routeIndependentAnnotation]; ok {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed(routeFlag) || cmd.Flags().Changed(allRoutesFlag) {
+				return errors.Errorf("--%s and --%s aren't supported by the command, it doesn't use the route config", routeFlag, allRoutesFlag)
+			}
+			return runE(cmd, args)
+		}
+		return
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		routeNames, err := getAllRouteNames(cmd)
+		if err != nil {
+			return err
+		}
+		if routeNames == nil {
+			return runE(cmd, args)
+		}
+		for _, routeName := range routeNames {
+			if err := cmd.Flags().Set(routeFlag, routeName); err != nil {
+				return err
+			}
+			if err := runE(cmd, args); err != nil {
+				return errors.Wrapf(err, "route %s failed", routeName)
+			}
+		}
+
+		return nil
+	}
+}
+
 func coreumCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "coreum",
@@ -88,6 +251,7 @@ func coreumCmd() *cobra.Command {
 	cmd.AddCommand(
 		coreumOutgoingCmd(),
 		coreumIncomingCmd(),
+		coreumBalanceHistoryCmd(),
 	)
 
 	return cmd
@@ -102,7 +266,11 @@ func coreumOutgoingCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			clientCtx := createClientContext(config)
+			httpClient, err := SetupHTTPClient(ctx, config)
+			if err != nil {
+				return err
+			}
+			clientCtx := createClientContext(config, httpClient)
 			log.Info("Fetching outgoing transactions from multichain's coreum wallet")
 			coreumAuditTxs, err := GetCoreumAuditTransactions(
 				ctx,
@@ -116,7 +284,7 @@ func coreumOutgoingCmd() *cobra.Command {
 				return err
 			}
 
-			err = WriteAuditTxsToCSV(coreumAuditTxs, config.OutputDocument)
+			err = WriteAuditTxsToCSV(coreumAuditTxs, NewAddressLabels(config.AddressBookEntries), config.OutputDocument)
 			if err != nil {
 				return err
 			}
@@ -140,7 +308,11 @@ func coreumIncomingCmd() *cobra.Command {
 				return err
 			}
 
-			clientCtx := createClientContext(config)
+			httpClient, err := SetupHTTPClient(ctx, config)
+			if err != nil {
+				return err
+			}
+			clientCtx := createClientContext(config, httpClient)
 
 			log.Info("Fetching incoming transactions to multichain coreum wallet")
 			coreumAuditTxs, err := GetCoreumAuditTransactions(
@@ -155,7 +327,7 @@ func coreumIncomingCmd() *cobra.Command {
 				return err
 			}
 
-			err = WriteAuditTxsToCSV(coreumAuditTxs, config.OutputDocument)
+			err = WriteAuditTxsToCSV(coreumAuditTxs, NewAddressLabels(config.AddressBookEntries), config.OutputDocument)
 			if err != nil {
 				return err
 			}
@@ -169,6 +341,108 @@ func coreumIncomingCmd() *cobra.Command {
 	return cmd
 }
 
+func coreumBalanceHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "balance-history",
+		Short: "Write the balance history of multichain's and foundation's coreum wallets to csv or json file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, ctx, log, err := Setup(cmd)
+			if err != nil {
+				return err
+			}
+
+			httpClient, err := SetupHTTPClient(ctx, config)
+			if err != nil {
+				return err
+			}
+			clientCtx := createClientContext(config, httpClient)
+
+			openingBalances, err := getCoreumOpeningBalances(config)
+			if err != nil {
+				return err
+			}
+
+			history := make([]BalanceHistoryEntry, 0)
+			for _, account := range []string{config.CoreumAccount, config.CoreumFoundationAccount} {
+				accountHistory, err := fetchCoreumBalanceHistory(ctx, config, clientCtx, account, openingBalances[account])
+				if err != nil {
+					return err
+				}
+				history = append(history, accountHistory...)
+			}
+
+			if strings.HasSuffix(config.OutputDocument, ".json") {
+				err = WriteBalanceHistoryToJSON(history, NewAddressLabels(config.AddressBookEntries), config.OutputDocument)
+			} else {
+				err = WriteBalanceHistoryToCSV(history, NewAddressLabels(config.AddressBookEntries), config.OutputDocument)
+			}
+			if err != nil {
+				return err
+			}
+			log.Info(fmt.Sprintf("Balance history of %d txs is saved to %s", len(history), config.OutputDocument))
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().String(outputDocumentFlag, "datafiles/balance-history.csv", "output file, the json is written if the file has .json extension")
+	cmd.PersistentFlags().Int(balanceSamplesFlag, 10, "number of the heights to check the reconstructed balance against the queried one")
+
+	return cmd
+}
+
+// fetchCoreumBalanceHistory fetches the full history of the account txs, reconstructs the balance from the opening
+// balance and checks it at the sampled heights.
+func fetchCoreumBalanceHistory(
+	ctx context.Context,
+	config Config,
+	clientCtx client.Context,
+	account string,
+	openingBalance *big.Int,
+) ([]BalanceHistoryEntry, error) {
+	log := logger.Get(ctx)
+	log.Info(fmt.Sprintf("Fetching incoming and outgoing transactions of %s coreum wallet", account))
+	// the balance is read from the bank events, so the txs of any messages are included
+	incomingTxs, outgoingTxs, err := GetCoreumBalanceTransactions(
+		ctx,
+		clientCtx,
+		account,
+		config.Denom,
+		config.Now, // the balance is accumulated from the first tx
+		defaultAfterDateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the balance is reconstructed from the full history, but only the time window is exported
+	history := lo.Filter(BuildBalanceHistory(account, openingBalance, incomingTxs, outgoingTxs), func(entry BalanceHistoryEntry, _ int) bool {
+		return !entry.Timestamp.After(config.BeforeDateTime) && !entry.Timestamp.Before(config.AfterDateTime)
+	})
+	if err := CheckBalanceHistory(ctx, clientCtx, history, config.Denom, config.BalanceSamples); err != nil {
+		return nil, err
+	}
+
+	var prevGap *big.Int
+	for _, entry := range history {
+		if entry.Gap == nil {
+			continue
+		}
+		if prevGap != nil && prevGap.Cmp(entry.Gap) != 0 {
+			log.Warn(
+				"Balance gap is changed, some transfers are missed.",
+				zap.String("Account", account),
+				zap.Int64("Height", entry.Height),
+				zap.String("PrevGap", convertFloatToSixDecimalsFloatText(prevGap)),
+				zap.String("Gap", convertFloatToSixDecimalsFloatText(entry.Gap)),
+			)
+		}
+		prevGap = entry.Gap
+	}
+
+	return history, nil
+}
+
 func xrplCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "xrpl",
@@ -192,16 +466,28 @@ func xrplIncomingCmd() *cobra.Command {
 				return err
 			}
 
+			httpClient, err := SetupHTTPClient(ctx, config)
+			if err != nil {
+				return err
+			}
+			memoCodec, err := getMemoCodec(config)
+			if err != nil {
+				return err
+			}
+
 			log.Info(fmt.Sprintf("Fetching incoming transactions for %s xrpl account", config.XrplAccount))
 			xrplAuditTxs, err := GetXRPLAuditTransactions(
 				ctx,
+				httpClient,
 				config.XrplFetchPoolSize,
-				config.XrplRPCAPIURL,
+				config.XrplCrossCheckSample,
+				config.XrplRPCAPIURLs,
 				config.XrplHistoricalAPIURL,
 				config.XrplAccount,
 				config.XrplCurrency,
 				config.XrplIssuer,
-				config.BridgeChainIndex,
+				memoCodec,
+				config.XrplLedgerIndex,
 				config.BeforeDateTime,
 				config.AfterDateTime,
 			)
@@ -209,7 +495,7 @@ func xrplIncomingCmd() *cobra.Command {
 				return err
 			}
 
-			err = WriteAuditTxsToCSV(xrplAuditTxs, config.OutputDocument)
+			err = WriteAuditTxsToCSV(xrplAuditTxs, NewAddressLabels(config.AddressBookEntries), config.OutputDocument)
 			if err != nil {
 				return err
 			}
@@ -232,6 +518,7 @@ func discrepancyCmd() *cobra.Command {
 	cmd.AddCommand(
 		discrepancyExportCmd(),
 		discrepancyRescanCmd(),
+		discrepancyDiffCmd(),
 	)
 
 	return cmd
@@ -246,13 +533,17 @@ func discrepancyExportCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			httpClient, err := SetupHTTPClient(ctx, config)
+			if err != nil {
+				return err
+			}
 			log.Info("Exporting discrepancies.")
-			discrepancies, err := findTxDiscrepancies(ctx, config)
+			discrepancies, err := findTxDiscrepancies(ctx, config, httpClient)
 			if err != nil {
 				return err
 			}
 
-			err = WriteTxsDiscrepancyToCSV(discrepancies, config.OutputDocument)
+			err = WriteTxsDiscrepancyToCSV(discrepancies, NewAddressLabels(config.AddressBookEntries), config.OutputDocument)
 			if err != nil {
 				return err
 			}
@@ -270,31 +561,280 @@ func discrepancyExportCmd() *cobra.Command {
 func discrepancyRescanCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "rescan",
-		Short: "Rescans all transactions orphan xrpl txs",
+		Short: "Rescans the txs of the selected discrepancies (orphan xrpl txs by default) or the provided txs",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config, ctx, log, err := Setup(cmd)
 			if err != nil {
 				return err
 			}
 
-			log.Info("Rescanning orphan discrepancies.")
-			discrepancies, err := findTxDiscrepancies(ctx, config)
+			httpClient, err := SetupHTTPClient(ctx, config)
 			if err != nil {
 				return err
 			}
 
-			txHashes := make([]string, 0)
-			for _, discrepancy := range discrepancies {
-				if discrepancy.Discrepancy == DiscrepancyOrphanXrplTx {
-					txHashes = append(txHashes, discrepancy.XrplTx.Hash)
+			var (
+				txHashes        []string
+				previousResults []RescanResult
+			)
+			switch {
+			case config.RescanFailedFrom != "":
+				log.Info(fmt.Sprintf("Rescanning failed txs from %s.", config.RescanFailedFrom))
+				previousResults, err = ReadRescanResultsFromCSV(config.RescanFailedFrom)
+				if err != nil {
+					return err
 				}
+				txHashes = make([]string, 0)
+				for _, result := range previousResults {
+					if result.Status == RescanStatusFailed {
+						txHashes = append(txHashes, result.Hash)
+					}
+				}
+			case config.RescanHashesFile != "":
+				log.Info(fmt.Sprintf("Rescanning txs from %s.", config.RescanHashesFile))
+				txHashes, err = readRescanHashesFile(cmd, config.RescanHashesFile)
+				if err != nil {
+					return err
+				}
+			default:
+				for _, kind := range config.RescanDiscrepancies {
+					if !lo.Contains(allDiscrepancies, kind) {
+						return errors.Errorf("unknown discrepancy %q, allowed: %q", kind, allDiscrepancies)
+					}
+				}
+				log.Info(fmt.Sprintf("Rescanning discrepancies: %q.", config.RescanDiscrepancies))
+				discrepancies, err := findTxDiscrepancies(ctx, config, httpClient)
+				if err != nil {
+					return err
+				}
+				memoCodec, err := getMemoCodec(config)
+				if err != nil {
+					return err
+				}
+				txHashes = SelectRescanTxHashes(discrepancies, config.RescanDiscrepancies, memoCodec)
+			}
+
+			results := RescanMultichainTxs(
+				ctx,
+				httpClient,
+				config.MultichainRescanAPIURL,
+				config.RescanSrcChainID,
+				config.RescanDestChainID,
+				txHashes,
+				config.RescanPoolSize,
+				config.DryRun,
+			)
+			// the results file is written even if the rescan is interrupted to keep the submitted txs
+			if err := WriteRescanResultsToCSV(MergeRescanResults(previousResults, results), config.OutputDocument); err != nil {
+				return err
+			}
+
+			failedCount := 0
+			for _, result := range results {
+				if result.Status == RescanStatusFailed {
+					failedCount++
+				}
+			}
+			if failedCount != 0 {
+				return errors.Errorf("%d of %d txs failed to rescan, see %s", failedCount, len(results), config.OutputDocument)
 			}
+			log.Info(fmt.Sprintf("Rescan results are written to %s", config.OutputDocument))
 
-			return RescanMultichainTxs(ctx, config.MultichainRescanAPIURL, txHashes)
+			return nil
 		},
 	}
 
 	cmd.PersistentFlags().String(multichainRescanAPIURLFlag, defaultMultichainRescanAPIURL, "multichain rescan API url")
+	cmd.PersistentFlags().String(outputDocumentFlag, "datafiles/rescan-results.csv", "rescan results file")
+	cmd.PersistentFlags().Bool(dryRunFlag, false, "find the txs to rescan without submitting them")
+	cmd.PersistentFlags().Int(rescanPoolSizeFlag, defaultRescanPoolSize, "number of txs submitted to rescan in parallel")
+	cmd.PersistentFlags().String(rescanFailedFromFlag, "", "rescan only the failed txs from the rescan results file")
+	cmd.PersistentFlags().StringSlice(rescanDiscrepanciesFlag, []string{DiscrepancyOrphanXrplTx}, fmt.Sprintf("discrepancies to rescan, allowed: %q", allDiscrepancies))
+	cmd.PersistentFlags().String(rescanHashesFileFlag, "", "file with the tx hashes to rescan, one per line, use - to read from stdin")
+	cmd.PersistentFlags().String(rescanSrcChainIDFlag, defaultRescanSrcChainID, "multichain source chain ID of the rescanned txs")
+	cmd.PersistentFlags().String(rescanDestChainIDFlag, defaultRescanDestChainID, "multichain destination chain ID of the rescanned txs")
+
+	cmd.AddCommand(
+		discrepancyRescanTrackCmd(),
+	)
+
+	return cmd
+}
+
+func discrepancyRescanTrackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "track",
+		Short: "Tracks whether the rescanned txs are matched with the coreum txs",
+		// the rescanned txs are read from the rescan results file
+		Annotations: map[string]string{routeIndependentAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, ctx, log, err := Setup(cmd)
+			if err != nil {
+				return err
+			}
+
+			httpClient, err := SetupHTTPClient(ctx, config)
+			if err != nil {
+				return err
+			}
+			clientCtx := createClientContext(config, httpClient)
+
+			results, err := ReadRescanResultsFromCSV(config.RescanResultsFile)
+			if err != nil {
+				return err
+			}
+			tracks, err := ReadRescanTracksFromJSON(config.OutputDocument)
+			if err != nil {
+				return err
+			}
+			tracks = StartRescanTracks(tracks, results)
+
+			for iteration := 1; ; iteration++ {
+				pendingTracks := PendingRescanTracks(tracks)
+				log.Info(fmt.Sprintf("Tracking %d of %d rescanned txs, iteration: %d", len(pendingTracks), len(tracks), iteration))
+				if len(pendingTracks) != 0 {
+					tracks, err = trackRescannedTxs(ctx, config, httpClient, clientCtx, tracks, pendingTracks)
+					if err != nil {
+						return err
+					}
+					if err := WriteRescanTracksToJSON(tracks, config.OutputDocument); err != nil {
+						return err
+					}
+				}
+				log.Info(fmt.Sprintf("Rescan tracking report:\n%s", BuildRescanTrackReport(tracks, time.Now().UTC())))
+
+				if len(PendingRescanTracks(tracks)) == 0 ||
+					(config.TrackIterations > 0 && iteration >= config.TrackIterations) {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return errors.Wrap(ctx.Err(), "rescan tracking is interrupted")
+				case <-time.After(config.TrackInterval):
+				}
+			}
+			log.Info(fmt.Sprintf("Rescan tracking history is written to %s", config.OutputDocument))
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().String(rescanResultsFlag, "datafiles/rescan-results.csv", "rescan results file with the txs to track")
+	cmd.PersistentFlags().String(outputDocumentFlag, "datafiles/rescan-track.json", "tracking history file, updated on each run")
+	cmd.PersistentFlags().Duration(trackIntervalFlag, defaultTrackInterval, "interval between the tracking iterations")
+	cmd.PersistentFlags().Int(trackIterationsFlag, 1, "number of the tracking iterations, zero means until all txs are matched")
+	cmd.PersistentFlags().Duration(trackOrphanAfterFlag, defaultTrackOrphanAfter, "duration since the rescan after which the not matched tx is reported as orphaned")
+
+	return cmd
+}
+
+// trackRescannedTxs fetches the pending txs and the coreum txs sent after the earliest of them and matches them.
+func trackRescannedTxs(
+	ctx context.Context,
+	config Config,
+	httpClient *HTTPClient,
+	clientCtx client.Context,
+	tracks, pendingTracks []RescanTrack,
+) ([]RescanTrack, error) {
+	txHashes := make([]string, 0, len(pendingTracks))
+	for _, track := range pendingTracks {
+		txHashes = append(txHashes, track.Hash)
+	}
+	memoCodec, err := getMemoCodec(config)
+	if err != nil {
+		return nil, err
+	}
+	xrplAuditTxs, err := GetXRPLAuditTransactionsByHashes(
+		ctx, httpClient, config.XrplRPCAPIURLs[0], memoCodec, txHashes,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the coreum tx can't be sent before the xrpl tx
+	afterDateTime := time.Now().UTC()
+	for _, xrplAuditTx := range xrplAuditTxs {
+		if xrplAuditTx.Timestamp.Before(afterDateTime) {
+			afterDateTime = xrplAuditTx.Timestamp
+		}
+	}
+	coreumAuditTxs, err := GetCoreumAuditTransactions(
+		ctx,
+		clientCtx,
+		fmt.Sprintf("coin_spent.spender='%s'", config.CoreumAccount),
+		config.Denom,
+		time.Now().UTC(),
+		afterDateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return TrackRescannedTxs(
+		tracks,
+		xrplAuditTxs,
+		coreumAuditTxs,
+		config.FeeConfigs,
+		config.AmountTolerance,
+		memoCodec,
+		config.CoreumMemoSourceChainID,
+		config.TrackOrphanAfter,
+		time.Now().UTC(),
+	), nil
+}
+
+func readRescanHashesFile(cmd *cobra.Command, path string) ([]string, error) {
+	if path == "-" {
+		return ReadTxHashes(cmd.InOrStdin())
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Errorf("can't open file, path: %s, err: %s", path, err)
+	}
+	defer file.Close()
+
+	return ReadTxHashes(file)
+}
+
+func discrepancyDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff old.csv new.csv",
+		Short: "Compare two discrepancy exports and write the resolved, new and changed discrepancies to csv file",
+		Args:  cobra.ExactArgs(2),
+		// the discrepancies are read from the exports
+		Annotations: map[string]string{routeIndependentAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, _, log, err := Setup(cmd)
+			if err != nil {
+				return err
+			}
+			oldDiscrepancies, err := ReadTxsDiscrepancyFromCSV(args[0])
+			if err != nil {
+				return err
+			}
+			newDiscrepancies, err := ReadTxsDiscrepancyFromCSV(args[1])
+			if err != nil {
+				return err
+			}
+
+			diffs := DiffTxDiscrepancies(oldDiscrepancies, newDiscrepancies)
+			counts := CountTxDiscrepancyDiffs(diffs)
+			log.Info(fmt.Sprintf(
+				"Discrepancies resolved: %d, changed: %d, new: %d",
+				counts[DiffStatusResolved], counts[DiffStatusChanged], counts[DiffStatusNew],
+			))
+
+			if err := WriteTxDiscrepancyDiffsToCSV(diffs, config.OutputDocument); err != nil {
+				return err
+			}
+			log.Info(fmt.Sprintf("Diff is written to %s", config.OutputDocument))
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().String(outputDocumentFlag, "datafiles/discrepancies-diff.csv", "output file")
 
 	return cmd
 }
@@ -307,6 +847,7 @@ func summaryCmd() *cobra.Command {
 
 	cmd.AddCommand(
 		summaryPrintCmd(),
+		summarySupplyHistoryCmd(),
 	)
 
 	return cmd
@@ -316,102 +857,634 @@ func summaryPrintCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "print",
 		Short: "Get and print summary report.",
+		// the summaries of all the routes are aggregated
+		Annotations: map[string]string{aggregatesRoutesAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			routeNames, err := getAllRouteNames(cmd)
+			if err != nil {
+				return err
+			}
+			if routeNames == nil {
+				_, _, err := printSummary(cmd)
+				return err
+			}
+
+			var log *zap.Logger
+			summaries := make([]RouteSummary, 0, len(routeNames))
+			for _, routeName := range routeNames {
+				if err := cmd.Flags().Set(routeFlag, routeName); err != nil {
+					return err
+				}
+				var summary RouteSummary
+				summary, log, err = printSummary(cmd)
+				if err != nil {
+					return errors.Wrapf(err, "route %s failed", routeName)
+				}
+				summaries = append(summaries, summary)
+			}
+
+			// the amounts of the different assets can't be added up, so the routes are aggregated per asset
+			for _, assetSummary := range AggregateSummaries(summaries) {
+				log.Info(fmt.Sprintf(
+					"Aggregated summary report of the %s asset, routes: %s:",
+					assetSummary.Asset, strings.Join(assetSummary.Routes, ", "),
+				))
+				log.Info(fmt.Sprintf("\n%s", assetSummary.Summary.String()))
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// printSummary fetches and prints the summary of the route selected by the flags.
+func printSummary(cmd *cobra.Command) (RouteSummary, *zap.Logger, error) {
+	config, ctx, log, err := Setup(cmd)
+	if err != nil {
+		return RouteSummary{}, nil, err
+	}
+	log.Info("Fetching data for the report.")
+
+	httpClient, err := SetupHTTPClient(ctx, config)
+	if err != nil {
+		return RouteSummary{}, nil, err
+	}
+	summary, err := fetchSummary(ctx, config, httpClient)
+	if err != nil {
+		return RouteSummary{}, nil, err
+	}
+
+	if config.Route != "" {
+		log.Info(fmt.Sprintf("Summary report of the %s route:", config.Route))
+	} else {
+		log.Info("Summary report:")
+	}
+	log.Info(fmt.Sprintf("\n%s", summary.String()))
+
+	return RouteSummary{
+		Route: config.Route,
+		Asset: SummaryAsset{
+			Denom:        config.Denom,
+			XrplCurrency: config.XrplCurrency,
+			XrplIssuer:   config.XrplIssuer,
+		},
+		CoreumAccount: config.CoreumAccount,
+		Summary:       summary,
+	}, log, nil
+}
+
+func summarySupplyHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "supply-history",
+		Short: "Export or plot the daily xrpl supply and coreum locked balance.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config, ctx, log, err := Setup(cmd)
 			if err != nil {
 				return err
 			}
-			log.Info("Fetching data for the report.")
 
-			xrplSupply, err := GetXrplCurrencySupply(ctx, config.XrplScanAPIURL, config.XrplIssuer, config.XrplCurrency)
+			httpClient, err := SetupHTTPClient(ctx, config)
+			if err != nil {
+				return err
+			}
+			history, err := fetchSupplyHistory(ctx, config, httpClient)
 			if err != nil {
 				return err
 			}
-			clientCtx := createClientContext(config)
 
-			coreumBalance, err := GetCoreumAccountBalance(ctx, clientCtx, config.CoreumAccount, config.Denom)
+			if strings.HasSuffix(config.OutputDocument, ".svg") {
+				err = WriteSupplyHistoryToSVG(history, config.OutputDocument)
+			} else {
+				err = WriteSupplyHistoryToCSV(history, config.OutputDocument)
+			}
 			if err != nil {
 				return err
 			}
+			log.Info(fmt.Sprintf("Supply history of %d days is saved to %s", len(history), config.OutputDocument))
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().String(outputDocumentFlag, "datafiles/supply-history.csv", "output file, the SVG plot is written if the file has .svg extension")
+
+	return cmd
+}
+
+func reportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Write and verify the report bundle",
+	}
+
+	cmd.AddCommand(
+		reportBundleCmd(),
+		reportVerifyCmd(),
+	)
+
+	return cmd
+}
 
-			config.IncludeAll = true
-			discrepancies, err := findTxDiscrepancies(ctx, config)
+func reportBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Write all exports with the manifest of their hashes, config and heights to the bundle dir",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, ctx, log, err := Setup(cmd)
+			if err != nil {
+				return err
+			}
+			httpClient, err := SetupHTTPClient(ctx, config)
 			if err != nil {
 				return err
 			}
 
-			coreumIncomingAuditTxs, err := GetCoreumAuditTransactions(
-				ctx,
-				clientCtx,
-				fmt.Sprintf("coin_received.receiver='%s'", config.CoreumAccount),
-				config.Denom,
-				config.BeforeDateTime,
-				config.AfterDateTime,
-			)
+			manifest, err := WriteReportBundle(ctx, config, httpClient, config.BundleDir)
+			if err != nil {
+				return err
+			}
+			log.Info(fmt.Sprintf("Report bundle with %d files is written to %s", len(manifest.Files), config.BundleDir))
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().String(bundleDirFlag, "datafiles/bundle", "report bundle dir")
+	cmd.PersistentFlags().Bool(includeAllFlag, false, "add all tx to discrepancies file even if no discrepancies are found")
+
+	return cmd
+}
+
+func reportVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the hashes of the bundle files and optionally re-fetch the data to confirm them",
+		// the bundle is re-fetched with the config recorded in the bundle
+		Annotations: map[string]string{routeIndependentAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, ctx, log, err := Setup(cmd)
 			if err != nil {
 				return err
 			}
-			foundationCoreumIncomingAuditTxs := make([]AuditTx, 0)
-			for _, auditTx := range coreumIncomingAuditTxs {
-				if auditTx.FromAddress == config.CoreumFoundationAccount {
-					foundationCoreumIncomingAuditTxs = append(foundationCoreumIncomingAuditTxs, auditTx)
+			manifest, err := ReadReportBundleManifest(config.BundleDir)
+			if err != nil {
+				return err
+			}
+			log.Info(fmt.Sprintf(
+				"Verifying report bundle created at %s by revision %q, coreum height: %d, xrpl ledger: %d",
+				manifest.CreatedAt.Format(time.DateTime), manifest.GitRevision, manifest.CoreumHeight, manifest.XrplLedgerIndex,
+			))
+
+			mismatches := VerifyReportBundleFiles(config.BundleDir, manifest)
+			if config.Refetch {
+				httpClient, err := SetupHTTPClient(ctx, config)
+				if err != nil {
+					return err
+				}
+				refetchDir, err := os.MkdirTemp("", "report-bundle")
+				if err != nil {
+					return errors.Errorf("can't create temp dir, err: %s", err)
+				}
+				defer os.RemoveAll(refetchDir)
+
+				log.Info("Re-fetching the report bundle data.")
+				refetchConfig, err := manifest.Config.Apply(config)
+				if err != nil {
+					return err
+				}
+				refetchedManifest, err := WriteReportBundle(ctx, refetchConfig, httpClient, refetchDir)
+				if err != nil {
+					return err
 				}
+				mismatches = append(mismatches, CompareReportBundleManifests(manifest, refetchedManifest)...)
 			}
 
-			summary := BuildSummary(discrepancies, foundationCoreumIncomingAuditTxs, coreumBalance, xrplSupply)
+			for _, mismatch := range mismatches {
+				log.Error("Report bundle mismatch.", zap.Error(mismatch))
+			}
+			if len(mismatches) > 0 {
+				return errors.Errorf("report bundle verification failed, %d mismatches", len(mismatches))
+			}
+			log.Info("Report bundle is verified.")
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().String(bundleDirFlag, "datafiles/bundle", "report bundle dir")
+	cmd.PersistentFlags().Bool(refetchFlag, false, "re-fetch the data with the bundle config and compare it with the bundle files")
+
+	return cmd
+}
+
+func auditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Check the audit itself",
+	}
+
+	cmd.AddCommand(
+		auditSelfCheckCmd(),
+	)
 
-			log.Info("Summary report:")
-			log.Info(fmt.Sprintf("\n%s", summary.String()))
+	return cmd
+}
+
+func auditSelfCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self-check",
+		Short: "Check that each tx appears in exactly one discrepancy row and the amounts are conserved",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, ctx, log, err := Setup(cmd)
+			if err != nil {
+				return err
+			}
+
+			var xrplAuditTxs, coreumAuditTxs []AuditTx
+			if config.XrplTxsFile != "" || config.CoreumTxsFile != "" {
+				if config.XrplTxsFile == "" || config.CoreumTxsFile == "" {
+					return errors.Errorf("both --%s and --%s must be set", xrplTxsFileFlag, coreumTxsFileFlag)
+				}
+				log.Info("Reading exported transactions.")
+				xrplAuditTxs, coreumAuditTxs, err = readExportedAuditTxs(config)
+			} else {
+				var httpClient *HTTPClient
+				httpClient, err = SetupHTTPClient(ctx, config)
+				if err != nil {
+					return err
+				}
+				xrplAuditTxs, coreumAuditTxs, err = fetchBridgeAuditTxs(ctx, config, httpClient)
+			}
+			if err != nil {
+				return err
+			}
+
+			memoCodec, err := getMemoCodec(config)
+			if err != nil {
+				return err
+			}
+
+			log.Info(fmt.Sprintf("Checking %d xrpl and %d coreum transactions", len(xrplAuditTxs), len(coreumAuditTxs)))
+			violations := SelfCheckAudit(
+				xrplAuditTxs, coreumAuditTxs, config.FeeConfigs, config.AmountTolerance, memoCodec, config.CoreumMemoSourceChainID,
+			)
+			for _, violation := range violations {
+				log.Error("Invariant violated.", zap.Error(violation))
+			}
+			if len(violations) > 0 {
+				return errors.Errorf("%d invariants violated", len(violations))
+			}
+			log.Info("All invariants hold.")
 
 			return nil
 		},
 	}
 
+	cmd.PersistentFlags().String(xrplTxsFileFlag, "", "exported incoming on xrpl txs file, the txs are fetched if not set")
+	cmd.PersistentFlags().String(coreumTxsFileFlag, "", "exported outgoing on coreum txs file, the txs are fetched if not set")
+
 	return cmd
 }
 
-func findTxDiscrepancies(ctx context.Context, config Config) ([]TxDiscrepancy, error) {
+// readExportedAuditTxs reads the exported xrpl and coreum txs with their target addresses. The older exports don't
+// have the target addresses, so they are restored from the colon memos, the exports of the other memo codecs must be
+// re-exported.
+func readExportedAuditTxs(config Config) ([]AuditTx, []AuditTx, error) {
+	xrplAuditTxs, err := ReadAuditTxsFromCSV(config.XrplTxsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range xrplAuditTxs {
+		if xrplAuditTxs[i].TargetAddress != "" {
+			continue
+		}
+		if config.MemoCodec.Name != MemoCodecColon {
+			return nil, nil, errors.Errorf(
+				"empty target address of the xrpl tx %s, the txs exported without the target addresses can be read with the %s memo codec only, path: %s",
+				xrplAuditTxs[i].Hash, MemoCodecColon, config.XrplTxsFile,
+			)
+		}
+		// the exported colon memo is decoded, so it's the "address:chainIndex"
+		xrplAuditTxs[i].TargetAddress = strings.Split(xrplAuditTxs[i].Memo, ":")[0]
+	}
+
+	coreumAuditTxs, err := ReadAuditTxsFromCSV(config.CoreumTxsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range coreumAuditTxs {
+		if coreumAuditTxs[i].TargetAddress == "" {
+			coreumAuditTxs[i].TargetAddress = coreumAuditTxs[i].ToAddress
+		}
+	}
+
+	return xrplAuditTxs, coreumAuditTxs, nil
+}
+
+// fetchSummary fetches the data for the summary report and builds it.
+func fetchSummary(ctx context.Context, config Config, httpClient *HTTPClient) (Summary, error) {
+	xrplAuditTxs, coreumOutgoingAuditTxs, err := fetchBridgeAuditTxs(ctx, config, httpClient)
+	if err != nil {
+		return Summary{}, err
+	}
+	coreumIncomingAuditTxs, err := fetchCoreumIncomingAuditTxs(ctx, config, httpClient)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	return buildFetchedSummary(ctx, config, httpClient, xrplAuditTxs, coreumOutgoingAuditTxs, coreumIncomingAuditTxs)
+}
+
+// buildFetchedSummary fetches the supply and the balance and builds the summary report of the fetched full history of
+// the bridge txs and the incoming coreum txs.
+func buildFetchedSummary(
+	ctx context.Context,
+	config Config,
+	httpClient *HTTPClient,
+	xrplAuditTxs, coreumOutgoingAuditTxs, coreumIncomingAuditTxs []AuditTx,
+) (Summary, error) {
+	xrplSupply, err := getXrplCurrencySupply(ctx, config, httpClient)
+	if err != nil {
+		return Summary{}, err
+	}
+	clientCtx := createClientContext(config, httpClient)
+
+	coreumBalance, err := GetCoreumAccountBalance(ctx, clientCtx, config.CoreumAccount, config.Denom)
+	if err != nil {
+		return Summary{}, err
+	}
+	coreumOpeningBalances, err := getCoreumOpeningBalances(config)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	discrepancies, err := buildTxDiscrepancies(ctx, config, xrplAuditTxs, coreumOutgoingAuditTxs, true)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	addressBook, err := getAddressBook(config)
+	if err != nil {
+		return Summary{}, err
+	}
+	windowCoreumIncomingAuditTxs := filterAuditTxsByTime(coreumIncomingAuditTxs, config.BeforeDateTime, config.AfterDateTime)
+	foundationCoreumIncomingAuditTxs := make([]AuditTx, 0)
+	for _, auditTx := range windowCoreumIncomingAuditTxs {
+		if auditTx.FromAddress == config.CoreumFoundationAccount {
+			foundationCoreumIncomingAuditTxs = append(foundationCoreumIncomingAuditTxs, auditTx)
+		}
+	}
+
+	summary := BuildSummary(
+		discrepancies,
+		foundationCoreumIncomingAuditTxs,
+		coreumBalance,
+		xrplSupply,
+		CoreumBalanceSources{
+			OpeningBalance: coreumOpeningBalances[config.CoreumAccount],
+			IncomingTxs:    coreumIncomingAuditTxs,
+			OutgoingTxs:    coreumOutgoingAuditTxs,
+		},
+	)
+	windowCoreumOutgoingAuditTxs := filterAuditTxsByTime(coreumOutgoingAuditTxs, config.BeforeDateTime, config.AfterDateTime)
+	summary.CoreumIncomeByCounterparty = SumAuditTxsByCounterparty(
+		windowCoreumIncomingAuditTxs, addressBook.ClassifyCoreumIncomingTx,
+	)
+	summary.CoreumOutcomeByCounterparty = SumAuditTxsByCounterparty(
+		windowCoreumOutgoingAuditTxs, addressBook.ClassifyCoreumOutgoingTx,
+	)
+	summary.CoreumIncomeByLabel = SumAuditTxsByLabel(
+		windowCoreumIncomingAuditTxs, addressBook.Labels(), func(tx AuditTx) string { return tx.FromAddress },
+	)
+	summary.CoreumOutcomeByLabel = SumAuditTxsByLabel(
+		windowCoreumOutgoingAuditTxs, addressBook.Labels(), func(tx AuditTx) string { return tx.ToAddress },
+	)
+
+	return summary, nil
+}
+
+// getAddressBook returns the address book with the foundation account and the configured entries.
+func getAddressBook(config Config) (AddressBook, error) {
+	memoCodec, err := getMemoCodec(config)
+	if err != nil {
+		return AddressBook{}, err
+	}
+
+	return NewAddressBook(config.CoreumFoundationAccount, memoCodec, config.AddressBookEntries...)
+}
+
+// getMemoCodec returns the configured memo codec of the bridge chain index.
+func getMemoCodec(config Config) (MemoCodec, error) {
+	return NewMemoCodec(config.MemoCodec, config.BridgeChainIndex)
+}
+
+// getCoreumOpeningBalances returns the opening balances of the multichain and foundation coreum accounts from the
+// genesis file, or the configured opening balance of the multichain account.
+func getCoreumOpeningBalances(config Config) (map[string]*big.Int, error) {
+	if config.CoreumGenesisFile != "" {
+		return ReadCoreumGenesisBalances(
+			config.CoreumGenesisFile, config.Denom, config.CoreumAccount, config.CoreumFoundationAccount,
+		)
+	}
+
+	return map[string]*big.Int{
+		config.CoreumAccount:           bigIntOrZero(config.CoreumOpeningBalance),
+		config.CoreumFoundationAccount: big.NewInt(0),
+	}, nil
+}
+
+// fetchSupplyHistory fetches the full history of the xrpl issuer payments and the coreum multichain account txs and
+// builds the daily supply history with the coreum opening balance of the summary.
+func fetchSupplyHistory(ctx context.Context, config Config, httpClient *HTTPClient) ([]SupplyHistoryDay, error) {
 	log := logger.Get(ctx)
-	log.Info(fmt.Sprintf("Fetching incoming transactions for %s xrpl account", config.XrplAccount))
-	xrplAuditTxs, err := GetXRPLAuditTransactions(
+	log.Info(fmt.Sprintf("Fetching payments of %s xrpl issuer", config.XrplIssuer))
+	xrplIssuedTxs, xrplBurntTxs, err := GetXRPLIssuerAuditTransactions(
 		ctx,
+		httpClient,
 		config.XrplFetchPoolSize,
-		config.XrplRPCAPIURL,
+		config.XrplCrossCheckSample,
+		config.XrplRPCAPIURLs,
 		config.XrplHistoricalAPIURL,
-		config.XrplAccount,
 		config.XrplCurrency,
 		config.XrplIssuer,
-		config.BridgeChainIndex,
-		defaultBeforeDateTime, // for the discrepancies we export full history and filter later
+		config.XrplLedgerIndex,
+		config.Now, // the supply is accumulated from the first payment
 		defaultAfterDateTime,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	clientCtx := createClientContext(config)
-	log.Info("Fetching outgoing transactions from multichain coreum wallet")
-	coreumAuditTxs, err := GetCoreumAuditTransactions(
+	clientCtx := createClientContext(config, httpClient)
+	log.Info("Fetching incoming and outgoing transactions of multichain coreum wallet")
+	coreumIncomingTxs, err := GetCoreumAuditTransactions(
+		ctx,
+		clientCtx,
+		fmt.Sprintf("coin_received.receiver='%s'", config.CoreumAccount),
+		config.Denom,
+		config.Now,
+		defaultAfterDateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	coreumOutgoingTxs, err := GetCoreumAuditTransactions(
 		ctx,
 		clientCtx,
 		fmt.Sprintf("coin_spent.spender='%s'", config.CoreumAccount),
 		config.Denom,
-		defaultBeforeDateTime, // for the discrepancies we export full history and filter later
+		config.Now,
 		defaultAfterDateTime,
 	)
 	if err != nil {
 		return nil, err
 	}
+	coreumOpeningBalances, err := getCoreumOpeningBalances(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildSupplyHistory(
+		xrplIssuedTxs,
+		xrplBurntTxs,
+		CoreumBalanceSources{
+			OpeningBalance: coreumOpeningBalances[config.CoreumAccount],
+			IncomingTxs:    coreumIncomingTxs,
+			OutgoingTxs:    coreumOutgoingTxs,
+		},
+		config.AfterDateTime,
+		config.BeforeDateTime,
+	), nil
+}
+
+// getXrplCurrencySupply returns the xrpl supply of the currency from the configured source, the cross-check fails if
+// the xrpscan and rippled supplies differ.
+func getXrplCurrencySupply(ctx context.Context, config Config, httpClient *HTTPClient) (*big.Int, error) {
+	switch config.XrplSupplySource {
+	case XrplSupplySourceXrpscan:
+		return GetXrplCurrencySupply(ctx, httpClient, config.XrplScanAPIURL, config.XrplIssuer, config.XrplCurrency)
+	case XrplSupplySourceRippled:
+		return GetXrplCurrencySupplyAtLedger(
+			ctx, httpClient, config.XrplRPCAPIURLs[0], config.XrplIssuer, config.XrplCurrency, config.XrplLedgerIndex,
+		)
+	case XrplSupplySourceCrossCheck:
+		xrpscanSupply, err := GetXrplCurrencySupply(ctx, httpClient, config.XrplScanAPIURL, config.XrplIssuer, config.XrplCurrency)
+		if err != nil {
+			return nil, err
+		}
+		rippledSupply, err := GetXrplCurrencySupplyAtLedger(
+			ctx, httpClient, config.XrplRPCAPIURLs[0], config.XrplIssuer, config.XrplCurrency, config.XrplLedgerIndex,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if xrpscanSupply.Cmp(rippledSupply) != 0 {
+			return nil, errors.Errorf(
+				"xrpl supply mismatch, xrpscan: %s, rippled: %s",
+				convertFloatToSixDecimalsFloatText(xrpscanSupply), convertFloatToSixDecimalsFloatText(rippledSupply),
+			)
+		}
+		logger.Get(ctx).Info(fmt.Sprintf("Xrpl supply is cross-checked: %s", convertFloatToSixDecimalsFloatText(rippledSupply)))
+
+		return rippledSupply, nil
+	default:
+		return nil, errors.Errorf("unknown xrpl supply source %q", config.XrplSupplySource)
+	}
+}
+
+func findTxDiscrepancies(ctx context.Context, config Config, httpClient *HTTPClient) ([]TxDiscrepancy, error) {
+	xrplAuditTxs, coreumAuditTxs, err := fetchBridgeAuditTxs(ctx, config, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTxDiscrepancies(ctx, config, xrplAuditTxs, coreumAuditTxs, config.IncludeAll)
+}
+
+// buildTxDiscrepancies finds the discrepancies of the fetched full history of the bridge txs.
+func buildTxDiscrepancies(
+	ctx context.Context,
+	config Config,
+	xrplAuditTxs, coreumAuditTxs []AuditTx,
+	includeAll bool,
+) ([]TxDiscrepancy, error) {
+	memoCodec, err := getMemoCodec(config)
+	if err != nil {
+		return nil, err
+	}
 
 	discrepancies := FindAuditTxDiscrepancies(
 		xrplAuditTxs,
 		coreumAuditTxs,
 		config.FeeConfigs,
-		config.IncludeAll,
+		config.AmountTolerance,
+		memoCodec,
+		config.CoreumMemoSourceChainID,
+		includeAll,
 		config.BeforeDateTime,
 		config.AfterDateTime,
 	)
-	log.Info(fmt.Sprintf("Found %d discrepancies", len(discrepancies)))
+	logger.Get(ctx).Info(fmt.Sprintf("Found %d discrepancies", len(discrepancies)))
 
 	return discrepancies, nil
 }
+
+// fetchBridgeAuditTxs fetches the full history of the incoming xrpl and outgoing coreum bridge txs.
+func fetchBridgeAuditTxs(ctx context.Context, config Config, httpClient *HTTPClient) ([]AuditTx, []AuditTx, error) {
+	log := logger.Get(ctx)
+	memoCodec, err := getMemoCodec(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Info(fmt.Sprintf("Fetching incoming transactions for %s xrpl account", config.XrplAccount))
+	xrplAuditTxs, err := GetXRPLAuditTransactions(
+		ctx,
+		httpClient,
+		config.XrplFetchPoolSize,
+		config.XrplCrossCheckSample,
+		config.XrplRPCAPIURLs,
+		config.XrplHistoricalAPIURL,
+		config.XrplAccount,
+		config.XrplCurrency,
+		config.XrplIssuer,
+		memoCodec,
+		config.XrplLedgerIndex,
+		config.Now, // for the discrepancies we export full history and filter later
+		defaultAfterDateTime,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientCtx := createClientContext(config, httpClient)
+	log.Info("Fetching outgoing transactions from multichain coreum wallet")
+	coreumAuditTxs, err := GetCoreumAuditTransactions(
+		ctx,
+		clientCtx,
+		fmt.Sprintf("coin_spent.spender='%s'", config.CoreumAccount),
+		config.Denom,
+		config.Now, // for the discrepancies we export full history and filter later
+		defaultAfterDateTime,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return xrplAuditTxs, coreumAuditTxs, nil
+}
+
+// fetchCoreumIncomingAuditTxs fetches the full history of the incoming coreum txs, which explains the balance.
+func fetchCoreumIncomingAuditTxs(ctx context.Context, config Config, httpClient *HTTPClient) ([]AuditTx, error) {
+	logger.Get(ctx).Info("Fetching incoming transactions to multichain coreum wallet")
+
+	return GetCoreumAuditTransactions(
+		ctx,
+		createClientContext(config, httpClient),
+		fmt.Sprintf("coin_received.receiver='%s'", config.CoreumAccount),
+		config.Denom,
+		config.Now,
+		defaultAfterDateTime,
+	)
+}
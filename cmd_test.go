@@ -0,0 +1,807 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "update the golden files in testdata")
+
+// fakeAuditEnv is the set of the fake servers with the consistent bridge history.
+type fakeAuditEnv struct {
+	xrpl       *fakeXrplServer
+	tendermint *fakeTendermintServer
+	multichain *fakeMultichainServer
+}
+
+// newFakeAuditEnv returns the fake servers serving the bridge history with each kind of the discrepancies.
+func newFakeAuditEnv(t *testing.T) fakeAuditEnv {
+	day := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	xrplHash := func(symbol string) string {
+		return fmt.Sprintf("%064s", symbol)
+	}
+
+	xrpl := newFakeXrplServer(t, 2, []xrplTransaction{
+		// matched
+		newFakeXrplBridgeTx(xrplHash("A1"), fakeCoreumAddress(1), defaultBridgeChainIndex, "10", day),
+		// different amount
+		newFakeXrplBridgeTx(xrplHash("A2"), fakeCoreumAddress(2), defaultBridgeChainIndex, "20", day.Add(24*time.Hour)),
+		// orphan
+		newFakeXrplBridgeTx(xrplHash("A3"), fakeCoreumAddress(3), defaultBridgeChainIndex, "30", day.Add(48*time.Hour)),
+		// different target address
+		newFakeXrplBridgeTx(xrplHash("A4"), fakeCoreumAddress(4), defaultBridgeChainIndex, "40", day.Add(72*time.Hour)),
+		// amount out of range
+		newFakeXrplBridgeTx(xrplHash("A5"), fakeCoreumAddress(5), defaultBridgeChainIndex, "1", day.Add(96*time.Hour)),
+		// not a bridge tx
+		newFakeXrplBridgeTx(xrplHash("A6"), fakeCoreumAddress(6), "1", "60", day.Add(120*time.Hour)),
+		// issued
+		newFakeXrplIssuerPayment(xrplHash("I1"), "rHolderAccount", "200", day.Add(-22*time.Hour)),
+		newFakeXrplIssuerPayment(xrplHash("I2"), "rHolderAccount", "7.5", day.Add(24*time.Hour+time.Minute)),
+	}, []xrplCurrencySupply{
+		{Currency: defaultXrplCurrency, Value: mustParseFloat("1000")},
+	})
+	// the same supply at the latest validated ledger the report bundles are pinned to
+	xrpl.SetLedgerObligations(fakeXrplLedgerIndex, map[string]string{defaultXrplCurrency: "1000"})
+
+	tendermint := newFakeTendermintServer(t)
+	tendermint.AddTxs(
+		fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumAccount),
+		fakeCoreumTx{
+			Height: 101,
+			Time:   day.Add(time.Minute),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(1),
+			Amount: 7_600000,
+			Memo:   fmt.Sprintf("%s:0x%s:0", defaultCoreumMemoSourceChainID, xrplHash("A1")),
+		},
+		fakeCoreumTx{
+			Height: 102,
+			Time:   day.Add(24*time.Hour + time.Minute),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(2),
+			Amount: 17_500000,
+			Memo:   fmt.Sprintf("%s:0x%s:0", defaultCoreumMemoSourceChainID, xrplHash("A2")),
+		},
+		fakeCoreumTx{
+			Height: 104,
+			Time:   day.Add(72*time.Hour + time.Minute),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(40),
+			Amount: 37_600000,
+			Memo:   fmt.Sprintf("%s:0x%s:0", defaultCoreumMemoSourceChainID, xrplHash("A4")),
+		},
+		fakeCoreumTx{
+			Height: 106,
+			Time:   day.Add(120*time.Hour + time.Minute),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(7),
+			Amount: 1_000000,
+			Memo:   "invalid memo",
+		},
+	)
+	tendermint.AddTxs(
+		fmt.Sprintf("coin_received.receiver='%s'", defaultCoreumAccount),
+		fakeCoreumTx{
+			Height: 100,
+			Time:   day.Add(-time.Hour),
+			From:   defaultCoreumFoundationAccount,
+			To:     defaultCoreumAccount,
+			Amount: 1000_000000,
+			Memo:   "top up",
+		},
+		fakeCoreumTx{
+			Height: 105,
+			Time:   day.Add(100 * time.Hour),
+			From:   fakeCoreumAddress(8),
+			To:     defaultCoreumAccount,
+			Amount: 5_000000,
+		},
+	)
+	tendermint.SetBalance(defaultCoreumAccount, sdk.NewInt64Coin("ucore", 941_300000))
+
+	return fakeAuditEnv{
+		xrpl:       xrpl,
+		tendermint: tendermint,
+		multichain: newFakeMultichainServer(t, xrplHash("A3")),
+	}
+}
+
+// run executes the command against the fake servers.
+func (e fakeAuditEnv) run(t *testing.T, args ...string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// the time window might be overridden by the args
+	for _, flagValue := range [][2]string{
+		{afterDateTimeFlag, "2023-05-01 00:00:00"},
+		{beforeDateTimeFlag, "2023-07-01 00:00:00"},
+	} {
+		if !lo.Contains(args, "--"+flagValue[0]) {
+			args = append(args, "--"+flagValue[0], flagValue[1])
+		}
+	}
+
+	cmd := rootCmd()
+	cmd.SetArgs(append(args,
+		"--"+coreumNodeFlag, e.tendermint.URL,
+		"--"+xrplRPCAPIURLFlag, e.xrpl.URL,
+		"--"+xrplHistoricalAPIURLFlag, e.xrpl.URL,
+		"--"+xrplScanAPIURLFlag, e.xrpl.URL,
+		"--"+httpMaxRetriesFlag, "1",
+		"--"+httpMinBackoffFlag, "1ms",
+		"--"+httpMaxBackoffFlag, "1ms",
+	))
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	return cmd.ExecuteContext(ctx)
+}
+
+func TestCommands(t *testing.T) {
+	env := newFakeAuditEnv(t)
+
+	tests := []struct {
+		name          string
+		args          []string
+		maskedColumns []int
+	}{
+		{
+			name: "outgoing-on-coreum",
+			args: []string{"coreum", "export-outgoing"},
+		},
+		{
+			name: "incoming-on-coreum",
+			args: []string{"coreum", "export-incoming"},
+		},
+		{
+			name: "incoming-on-xrpl",
+			args: []string{"xrpl", "export-incoming"},
+		},
+		{
+			name: "discrepancies",
+			args: []string{"discrepancy", "export"},
+		},
+		{
+			name: "discrepancies-include-all",
+			args: []string{"discrepancy", "export", "--" + includeAllFlag},
+		},
+		{
+			name: "rescan-results-dry-run",
+			args: []string{
+				"discrepancy", "rescan", "--" + dryRunFlag, "--" + multichainRescanAPIURLFlag, env.multichain.URL,
+				"--" + rescanDiscrepanciesFlag, DiscrepancyOrphanXrplTx + "," + DiscrepancyDifferentAmountOnXrplAndCoreum,
+			},
+			maskedColumns: []int{4},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name+".csv")
+			require.NoError(t, env.run(t, append(tt.args, "--"+outputDocumentFlag, path)...))
+			requireGoldenCSV(t, path, filepath.Join("testdata", tt.name+".csv"), tt.maskedColumns...)
+		})
+	}
+	require.Empty(t, env.multichain.TxHashes())
+}
+
+func TestDiscrepancyRescanCommand(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	path := filepath.Join(t.TempDir(), "rescan-results.csv")
+
+	err := env.run(t, "discrepancy", "rescan",
+		"--"+multichainRescanAPIURLFlag, env.multichain.URL,
+		"--"+outputDocumentFlag, path,
+		"--"+rescanDiscrepanciesFlag, DiscrepancyOrphanXrplTx+","+DiscrepancyDifferentAmountOnXrplAndCoreum,
+	)
+	// the orphan tx is rejected by the fake multichain
+	require.ErrorContains(t, err, "1 of 2 txs failed to rescan")
+	requireGoldenCSV(t, path, filepath.Join("testdata", "rescan-results.csv"), 4)
+	require.ElementsMatch(t, []string{
+		fmt.Sprintf("%064s", "A2"), fmt.Sprintf("%064s", "A3"), fmt.Sprintf("%064s", "A3"),
+	}, env.multichain.TxHashes())
+
+	// the dry run of the failed txs to the same file keeps them failed
+	require.NoError(t, env.run(t, "discrepancy", "rescan", "--"+dryRunFlag,
+		"--"+multichainRescanAPIURLFlag, env.multichain.URL,
+		"--"+outputDocumentFlag, path,
+		"--"+rescanFailedFromFlag, path,
+	))
+	requireGoldenCSV(t, path, filepath.Join("testdata", "rescan-results.csv"), 4)
+}
+
+func TestSummaryPrintCommand(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	require.NoError(t, env.run(t, "summary", "print"))
+}
+
+func TestSummarySupplyHistoryCommand(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	path := filepath.Join(t.TempDir(), "supply-history.csv")
+	require.NoError(t, env.run(t, "summary", "supply-history", "--"+outputDocumentFlag, path,
+		"--"+afterDateTimeFlag, "2023-05-31 00:00:00",
+		"--"+beforeDateTimeFlag, "2023-06-07 00:00:00",
+	))
+	requireGoldenCSV(t, path, filepath.Join("testdata", "supply-history.csv"))
+
+	// the locked balance starts from the opening balance as in the summary
+	svgPath := filepath.Join(t.TempDir(), "supply-history.svg")
+	require.NoError(t, env.run(t, "summary", "supply-history", "--"+outputDocumentFlag, svgPath,
+		"--"+afterDateTimeFlag, "2023-05-31 00:00:00",
+		"--"+beforeDateTimeFlag, "2023-06-07 00:00:00",
+		"--"+coreumOpeningBalanceFlag, "100000000",
+	))
+	requireGoldenFile(t, svgPath, filepath.Join("testdata", "supply-history.svg"))
+}
+
+func TestCoreumBalanceHistoryCommand(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	env.tendermint.AddTxs(
+		fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumFoundationAccount),
+		fakeCoreumTx{
+			Height: 100,
+			Time:   time.Date(2023, time.Month(6), 1, 9, 0, 0, 0, time.UTC),
+			From:   defaultCoreumFoundationAccount,
+			To:     defaultCoreumAccount,
+			Amount: 1000_000000,
+			Memo:   "top up",
+		},
+	)
+	// the sampled heights, the last balance misses 1 CORE spent out of the tracked txs
+	env.tendermint.SetBalanceAt(101, defaultCoreumAccount, sdk.NewInt64Coin("ucore", 992_400000))
+	env.tendermint.SetBalanceAt(104, defaultCoreumAccount, sdk.NewInt64Coin("ucore", 937_300000))
+	env.tendermint.SetBalanceAt(106, defaultCoreumAccount, sdk.NewInt64Coin("ucore", 940_300000))
+	env.tendermint.SetBalanceAt(100, defaultCoreumFoundationAccount, sdk.NewInt64Coin("ucore", 5000_000000))
+
+	path := filepath.Join(t.TempDir(), "balance-history.csv")
+	require.NoError(t, env.run(t, "coreum", "balance-history", "--"+outputDocumentFlag, path, "--"+balanceSamplesFlag, "3"))
+	requireGoldenCSV(t, path, filepath.Join("testdata", "balance-history.csv"))
+
+	jsonPath := filepath.Join(t.TempDir(), "balance-history.json")
+	require.NoError(t, env.run(t, "coreum", "balance-history", "--"+outputDocumentFlag, jsonPath, "--"+balanceSamplesFlag, "3"))
+	data, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	var history []BalanceHistoryEntry
+	require.NoError(t, json.Unmarshal(data, &history))
+	require.Len(t, history, 7)
+	require.Equal(t, big.NewInt(-1_000000), history[5].Gap)
+}
+
+func TestCoreumBalanceHistoryCommandNonBankSendTxs(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	// the delegation isn't decoded by the client, and the multi send isn't the single bank send
+	env.tendermint.AddTxs(
+		fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumAccount),
+		fakeCoreumTx{
+			Height: 107,
+			Time:   time.Date(2023, time.Month(6), 6, 11, 0, 0, 0, time.UTC),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(9),
+			Amount: 100_000000,
+			Fee:    1_000000,
+			Msgs: []sdk.Msg{&stakingtypes.MsgDelegate{
+				DelegatorAddress: defaultCoreumAccount,
+				ValidatorAddress: "corevaloper1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnwkh6rv",
+				Amount:           sdk.NewInt64Coin("ucore", 100_000000),
+			}},
+		},
+	)
+	env.tendermint.AddTxs(
+		fmt.Sprintf("coin_received.receiver='%s'", defaultCoreumAccount),
+		fakeCoreumTx{
+			Height: 108,
+			Time:   time.Date(2023, time.Month(6), 6, 12, 0, 0, 0, time.UTC),
+			From:   defaultCoreumFoundationAccount,
+			To:     defaultCoreumAccount,
+			Amount: 50_000000,
+			Msgs: []sdk.Msg{&banktypes.MsgMultiSend{
+				Inputs: []banktypes.Input{
+					{Address: defaultCoreumFoundationAccount, Coins: sdk.NewCoins(sdk.NewInt64Coin("ucore", 50_000000))},
+				},
+				Outputs: []banktypes.Output{
+					{Address: defaultCoreumAccount, Coins: sdk.NewCoins(sdk.NewInt64Coin("ucore", 50_000000))},
+				},
+			}},
+		},
+	)
+	env.tendermint.SetBalanceAt(108, defaultCoreumAccount, sdk.NewInt64Coin("ucore", 890_300000))
+
+	path := filepath.Join(t.TempDir(), "balance-history.json")
+	require.NoError(t, env.run(t, "coreum", "balance-history", "--"+outputDocumentFlag, path, "--"+balanceSamplesFlag, "10"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var history []BalanceHistoryEntry
+	require.NoError(t, json.Unmarshal(data, &history))
+	require.Len(t, history, 8)
+	// the delegation is spent with its fee, and the balance is reconstructed without the gap
+	require.Equal(t, int64(107), history[6].Height)
+	require.Equal(t, big.NewInt(-101_000000), history[6].Amount)
+	require.Equal(t, int64(108), history[7].Height)
+	require.Equal(t, big.NewInt(50_000000), history[7].Amount)
+	require.Equal(t, big.NewInt(890_300000), history[7].Balance)
+	require.Equal(t, big.NewInt(0), history[7].Gap)
+}
+
+func TestSummaryCoreumOpeningBalance(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	env.tendermint.SetBalance(defaultCoreumAccount, sdk.NewInt64Coin("ucore", 1641_300000))
+	genesisPath := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, os.WriteFile(genesisPath, []byte(`{"app_state":{"bank":{"balances":[`+
+		`{"address":"`+defaultCoreumAccount+`","coins":[{"denom":"ucore","amount":"700000000"}]}]}}}`), 0o600))
+
+	for _, args := range [][]string{
+		{"--" + coreumGenesisFileFlag, genesisPath},
+		{"--" + coreumOpeningBalanceFlag, "700000000"},
+	} {
+		bundleDir := filepath.Join(t.TempDir(), "bundle")
+		require.NoError(t, env.run(t, append([]string{"report", "bundle", "--" + bundleDirFlag, bundleDir}, args...)...))
+		summary, err := os.ReadFile(filepath.Join(bundleDir, "summary.txt"))
+		require.NoError(t, err)
+		require.Contains(t, string(summary), "Balance:1641.300000, OpeningBalance:700.000000, GasFees:0.000000, Unexplained:0.000000")
+	}
+
+	err := env.run(t, "summary", "print", "--"+coreumGenesisFileFlag, genesisPath, "--"+coreumOpeningBalanceFlag, "1")
+	require.ErrorContains(t, err, "can't be used together")
+}
+
+func TestSummaryCoreumCounterparties(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	addressBookPath := filepath.Join(t.TempDir(), "address-book.yaml")
+	require.NoError(t, os.WriteFile(addressBookPath, []byte(`addresses:
+  - address: `+fakeCoreumAddress(7)+`
+    class: operator
+  - address: `+fakeCoreumAddress(8)+`
+    class: operator
+`), 0o600))
+
+	bundleDir := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, env.run(t, "report", "bundle", "--"+bundleDirFlag, bundleDir, "--"+addressBookFlag, addressBookPath))
+	summary, err := os.ReadFile(filepath.Join(bundleDir, "summary.txt"))
+	require.NoError(t, err)
+	require.Contains(t, string(summary),
+		"Coreum incoming by counterparty [foundation:1000.000000, operator:5.000000, bridge-user:0.000000, third-party:0.000000]")
+	require.Contains(t, string(summary),
+		"Coreum outgoing by counterparty [foundation:0.000000, operator:1.000000, bridge-user:62.700000, third-party:0.000000]")
+}
+
+func TestAddressLabels(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	addressBookPath := filepath.Join(t.TempDir(), "address-book.yaml")
+	require.NoError(t, os.WriteFile(addressBookPath, []byte(`addresses:
+  - address: `+defaultCoreumAccount+`
+    label: Multichain MPC
+  - address: `+defaultCoreumFoundationAccount+`
+    label: Coreum Foundation
+  - address: `+fakeCoreumAddress(1)+`
+    class: third-party
+    label: Distribution address
+`), 0o600))
+
+	outgoingPath := filepath.Join(t.TempDir(), "outgoing-on-coreum.csv")
+	require.NoError(t, env.run(t, "coreum", "export-outgoing",
+		"--"+outputDocumentFlag, outgoingPath, "--"+addressBookFlag, addressBookPath,
+	))
+	outgoing, err := os.ReadFile(outgoingPath)
+	require.NoError(t, err)
+	require.Contains(t, string(outgoing), ",FromLabel,ToLabel\n")
+	require.Contains(t, string(outgoing), ",Multichain MPC,Distribution address\n")
+
+	balanceHistoryPath := filepath.Join(t.TempDir(), "balance-history.json")
+	require.NoError(t, env.run(t, "coreum", "balance-history", "--"+balanceSamplesFlag, "0",
+		"--"+outputDocumentFlag, balanceHistoryPath, "--"+addressBookFlag, addressBookPath,
+	))
+	balanceHistory, err := os.ReadFile(balanceHistoryPath)
+	require.NoError(t, err)
+	require.Contains(t, string(balanceHistory), `"accountLabel": "Multichain MPC"`)
+
+	bundleDir := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, env.run(t, "report", "bundle", "--"+bundleDirFlag, bundleDir, "--"+addressBookFlag, addressBookPath))
+	summary, err := os.ReadFile(filepath.Join(bundleDir, "summary.txt"))
+	require.NoError(t, err)
+	require.Contains(t, string(summary), "Coreum incoming by label [Coreum Foundation:1000.000000]")
+	require.Contains(t, string(summary), "Coreum outgoing by label [Distribution address:7.600000]")
+	discrepancies, err := os.ReadFile(filepath.Join(bundleDir, "discrepancies.csv"))
+	require.NoError(t, err)
+	require.Contains(t, string(discrepancies), ",XrplTargetLabel,CoreumTargetLabel\n")
+
+	manifest, err := ReadReportBundleManifest(bundleDir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Config.AddressBook, 3)
+	// the address book of the bundle is used to re-fetch
+	require.NoError(t, env.run(t, "report", "verify", "--"+bundleDirFlag, bundleDir, "--"+refetchFlag))
+}
+
+func TestSummaryPrintCommandXrplSupplySources(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	env.xrpl.SetLedgerObligations(0, map[string]string{defaultXrplCurrency: "1000"})
+	require.NoError(t, env.run(t, "summary", "print", "--"+xrplSupplySourceFlag, XrplSupplySourceRippled))
+	require.NoError(t, env.run(t, "summary", "print", "--"+xrplSupplySourceFlag, XrplSupplySourceCrossCheck))
+
+	env.xrpl.SetLedgerObligations(0, map[string]string{defaultXrplCurrency: "999.5"})
+	err := env.run(t, "summary", "print", "--"+xrplSupplySourceFlag, XrplSupplySourceCrossCheck)
+	require.ErrorContains(t, err, "xrpl supply mismatch, xrpscan: 1000.000000, rippled: 999.500000")
+
+	err = env.run(t, "summary", "print", "--"+xrplSupplySourceFlag, "explorer")
+	require.ErrorContains(t, err, "invalid xrpl-supply-source")
+	// xrpscan can't read the supply at the pinned ledger
+	err = env.run(t, "summary", "print", "--"+xrplSupplySourceFlag, XrplSupplySourceXrpscan, "--"+xrplLedgerIndexFlag, "100")
+	require.ErrorContains(t, err, "can't be used with xrpl-ledger-index")
+}
+
+func TestPinnedCommands(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	// the state after the A3 xrpl tx and the A2 coreum tx
+	xrplLedgerIndex := fakeXrplLedgerIndexAt(time.Date(2023, time.Month(6), 3, 10, 0, 0, 0, time.UTC))
+	env.xrpl.SetLedgerObligations(xrplLedgerIndex, map[string]string{defaultXrplCurrency: "60"})
+	env.tendermint.SetBalanceAt(102, defaultCoreumAccount, sdk.NewInt64Coin("ucore", 974_900000))
+	pinArgs := []string{
+		"--" + coreumHeightFlag, "102",
+		"--" + xrplLedgerIndexFlag, fmt.Sprint(xrplLedgerIndex),
+	}
+
+	path := filepath.Join(t.TempDir(), "discrepancies-pinned.csv")
+	require.NoError(t, env.run(t, append([]string{"discrepancy", "export", "--" + outputDocumentFlag, path}, pinArgs...)...))
+	requireGoldenCSV(t, path, filepath.Join("testdata", "discrepancies-pinned.csv"))
+
+	require.NoError(t, env.run(t, append([]string{"summary", "print"}, pinArgs...)...))
+	// the supply isn't recorded for the other ledgers
+	require.ErrorContains(t, env.run(t, "summary", "print", "--"+xrplLedgerIndexFlag, "1"), "lgrNotFound")
+}
+
+func TestRescanTrackCommand(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	resultsPath := filepath.Join(t.TempDir(), "rescan-results.csv")
+	require.NoError(t, WriteRescanResultsToCSV([]RescanResult{
+		{Hash: fmt.Sprintf("%064s", "A1"), Status: RescanStatusSubmitted, Timestamp: time.Date(2023, time.Month(6), 1, 9, 0, 0, 0, time.UTC)},
+		{Hash: fmt.Sprintf("%064s", "A3"), Status: RescanStatusSubmitted, Timestamp: time.Date(2023, time.Month(6), 3, 9, 0, 0, 0, time.UTC)},
+	}, resultsPath))
+
+	trackPath := filepath.Join(t.TempDir(), "rescan-track.json")
+	require.NoError(t, env.run(t, "discrepancy", "rescan", "track",
+		"--"+rescanResultsFlag, resultsPath,
+		"--"+outputDocumentFlag, trackPath,
+	))
+
+	tracks, err := ReadRescanTracksFromJSON(trackPath)
+	require.NoError(t, err)
+	require.Len(t, tracks, 2)
+	require.Equal(t, TrackStatusMatched, tracks[0].Status)
+	require.Equal(t, time.Hour+time.Minute, tracks[0].TimeToMatch())
+	require.Equal(t, TrackStatusOrphaned, tracks[1].Status)
+}
+
+// requireGoldenCSV compares the CSV file with the golden one, the values of the masked columns aren't compared
+// since they are not deterministic.
+func requireGoldenCSV(t *testing.T, path, goldenPath string, maskedColumns ...int) {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+	for _, record := range records[1:] {
+		for _, column := range maskedColumns {
+			record[column] = "*"
+		}
+	}
+	buf := bytes.Buffer{}
+	writer := csv.NewWriter(&buf)
+	require.NoError(t, writer.WriteAll(records))
+
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(goldenPath, buf.Bytes(), 0o600))
+	}
+	golden, err := os.ReadFile(goldenPath)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), buf.String())
+}
+
+// requireGoldenFile compares the file with the golden file, the golden file is updated with the -update-golden.
+func requireGoldenFile(t *testing.T, path, goldenPath string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(goldenPath, data, 0o600))
+	}
+	golden, err := os.ReadFile(goldenPath)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(data))
+}
+
+func TestAuditSelfCheckCommand(t *testing.T) {
+	env := newFakeAuditEnv(t)
+
+	// fetched txs
+	require.NoError(t, env.run(t, "audit", "self-check"))
+
+	// exported txs
+	xrplTxsPath := filepath.Join(t.TempDir(), "incoming-on-xrpl.csv")
+	require.NoError(t, env.run(t, "xrpl", "export-incoming", "--"+outputDocumentFlag, xrplTxsPath))
+	coreumTxsPath := filepath.Join(t.TempDir(), "outgoing-on-coreum.csv")
+	require.NoError(t, env.run(t, "coreum", "export-outgoing", "--"+outputDocumentFlag, coreumTxsPath))
+	require.NoError(t, env.run(t, "audit", "self-check",
+		"--"+xrplTxsFileFlag, xrplTxsPath,
+		"--"+coreumTxsFileFlag, coreumTxsPath,
+	))
+
+	err := env.run(t, "audit", "self-check", "--"+xrplTxsFileFlag, xrplTxsPath)
+	require.ErrorContains(t, err, "must be set")
+}
+
+func TestReadExportedAuditTxs(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{
+		XrplTxsFile:   filepath.Join(dir, "incoming-on-xrpl.csv"),
+		CoreumTxsFile: filepath.Join(dir, "outgoing-on-coreum.csv"),
+		MemoCodec:     MemoCodecConfig{Name: MemoCodecJSON},
+	}
+	jsonMemo := `{"address":"` + fakeCoreumAddress(1) + `","chainIndex":"` + defaultBridgeChainIndex + `"}`
+	require.NoError(t, WriteAuditTxsToCSV([]AuditTx{
+		{Hash: "xrplHash1", Amount: big.NewInt(1), Memo: jsonMemo, TargetAddress: fakeCoreumAddress(1)},
+	}, nil, config.XrplTxsFile))
+	require.NoError(t, WriteAuditTxsToCSV([]AuditTx{
+		{Hash: "coreumHash1", Amount: big.NewInt(1), ToAddress: fakeCoreumAddress(1), TargetAddress: fakeCoreumAddress(1)},
+	}, nil, config.CoreumTxsFile))
+
+	// the target address is read from the export, not from the memo
+	xrplAuditTxs, coreumAuditTxs, err := readExportedAuditTxs(config)
+	require.NoError(t, err)
+	require.Equal(t, fakeCoreumAddress(1), xrplAuditTxs[0].TargetAddress)
+	require.Equal(t, fakeCoreumAddress(1), coreumAuditTxs[0].TargetAddress)
+
+	// the export without the target addresses is restored from the colon memos only
+	require.NoError(t, os.WriteFile(config.XrplTxsFile, []byte(`Hash,FromAddress,ToAddress,Amount,Memo,Timestamp
+xrplHash1,,,1.000000,`+fakeCoreumAddress(2)+`:`+defaultBridgeChainIndex+`,
+`), 0o600))
+	_, _, err = readExportedAuditTxs(config)
+	require.ErrorContains(t, err, "empty target address of the xrpl tx xrplHash1")
+	config.MemoCodec = MemoCodecConfig{Name: MemoCodecColon}
+	xrplAuditTxs, _, err = readExportedAuditTxs(config)
+	require.NoError(t, err)
+	require.Equal(t, fakeCoreumAddress(2), xrplAuditTxs[0].TargetAddress)
+}
+
+func TestDiscrepancyDiffCommand(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	oldPath := filepath.Join(t.TempDir(), "discrepancies-old.csv")
+	require.NoError(t, env.run(t, "discrepancy", "export", "--"+outputDocumentFlag, oldPath))
+
+	// the orphan tx is bridged after the rescan
+	env.tendermint.AddTxs(
+		fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumAccount),
+		fakeCoreumTx{
+			Height: 107,
+			Time:   time.Date(2023, time.Month(6), 6, 11, 0, 0, 0, time.UTC),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(3),
+			Amount: 27_600000,
+			Memo:   fmt.Sprintf("%s:0x%064s:0", defaultCoreumMemoSourceChainID, "A3"),
+		},
+	)
+	newPath := filepath.Join(t.TempDir(), "discrepancies-new.csv")
+	require.NoError(t, env.run(t, "discrepancy", "export", "--"+outputDocumentFlag, newPath))
+
+	diffPath := filepath.Join(t.TempDir(), "discrepancies-diff.csv")
+	require.NoError(t, env.run(t, "discrepancy", "diff", oldPath, newPath, "--"+outputDocumentFlag, diffPath))
+	requireGoldenCSV(t, diffPath, filepath.Join("testdata", "discrepancies-diff.csv"))
+
+	err := env.run(t, "discrepancy", "diff", oldPath)
+	require.ErrorContains(t, err, "accepts 2 arg(s)")
+}
+
+func TestReportBundleCommands(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	bundleDir := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, env.run(t, "report", "bundle", "--"+bundleDirFlag, bundleDir))
+
+	manifest, err := ReadReportBundleManifest(bundleDir)
+	require.NoError(t, err)
+	require.Equal(t, int64(106), manifest.CoreumHeight)
+	require.Equal(t, int64(fakeXrplLedgerIndex), manifest.XrplLedgerIndex)
+	require.Equal(t, time.Date(2023, time.Month(7), 1, 0, 0, 0, 0, time.UTC), manifest.Config.BeforeDateTime)
+	require.Len(t, manifest.Files, 5)
+	// the bundle exports are the same as the exports of the separate commands
+	requireGoldenCSV(t, filepath.Join(bundleDir, "discrepancies.csv"), filepath.Join("testdata", "discrepancies.csv"))
+	requireGoldenCSV(t, filepath.Join(bundleDir, "incoming-on-xrpl.csv"), filepath.Join("testdata", "incoming-on-xrpl.csv"))
+	// the xrpl history is fetched once for all the bundle files, as by the single export
+	bundlePaymentsRequests := env.xrpl.PaymentsRequests()
+	require.NoError(t, env.run(t, "discrepancy", "export", "--"+outputDocumentFlag, filepath.Join(t.TempDir(), "discrepancies.csv")))
+	require.Equal(t, bundlePaymentsRequests, env.xrpl.PaymentsRequests()-bundlePaymentsRequests)
+
+	require.NoError(t, env.run(t, "report", "verify", "--"+bundleDirFlag, bundleDir))
+	// the bundle config is used to re-fetch, not the flags
+	require.NoError(t, env.run(t, "report", "verify", "--"+bundleDirFlag, bundleDir, "--"+refetchFlag,
+		"--"+beforeDateTimeFlag, "2023-06-03 00:00:00",
+	))
+
+	// the data is added after the bundle
+	env.tendermint.AddTxs(
+		fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumAccount),
+		fakeCoreumTx{
+			Height: 107,
+			Time:   time.Date(2023, time.Month(6), 6, 11, 0, 0, 0, time.UTC),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(3),
+			Amount: 27_600000,
+			Memo:   fmt.Sprintf("%s:0x%064s:0", defaultCoreumMemoSourceChainID, "A3"),
+		},
+	)
+	// the bundle is pinned to the latest heights, so the new data doesn't change the re-fetched bundle
+	require.NoError(t, env.run(t, "report", "verify", "--"+bundleDirFlag, bundleDir, "--"+refetchFlag))
+	manifest, err = ReadReportBundleManifest(bundleDir)
+	require.NoError(t, err)
+	require.Equal(t, int64(106), manifest.Config.CoreumHeight)
+	require.Equal(t, int64(fakeXrplLedgerIndex), manifest.Config.XrplLedgerIndex)
+
+	// the file is modified
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "summary.txt"), []byte("modified"), 0o600))
+	err = env.run(t, "report", "verify", "--"+bundleDirFlag, bundleDir)
+	require.ErrorContains(t, err, "report bundle verification failed, 1 mismatches")
+}
+
+func TestPinnedReportBundleCommands(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	env.xrpl.SetLedgerObligations(fakeXrplLedgerIndex, map[string]string{defaultXrplCurrency: "1000"})
+	env.tendermint.SetBalanceAt(106, defaultCoreumAccount, sdk.NewInt64Coin("ucore", 941_300000))
+	bundleDir := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, env.run(t, "report", "bundle", "--"+bundleDirFlag, bundleDir,
+		"--"+coreumHeightFlag, "106",
+		"--"+xrplLedgerIndexFlag, fmt.Sprint(fakeXrplLedgerIndex),
+	))
+
+	manifest, err := ReadReportBundleManifest(bundleDir)
+	require.NoError(t, err)
+	require.Equal(t, int64(106), manifest.CoreumHeight)
+	require.Equal(t, int64(106), manifest.Config.CoreumHeight)
+	require.Equal(t, int64(fakeXrplLedgerIndex), manifest.Config.XrplLedgerIndex)
+
+	// the data after the pinned height doesn't change the re-fetched bundle
+	env.tendermint.AddTxs(
+		fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumAccount),
+		fakeCoreumTx{
+			Height: 107,
+			Time:   time.Date(2023, time.Month(6), 6, 11, 0, 0, 0, time.UTC),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(3),
+			Amount: 27_600000,
+			Memo:   fmt.Sprintf("%s:0x%064s:0", defaultCoreumMemoSourceChainID, "A3"),
+		},
+	)
+	require.NoError(t, env.run(t, "report", "verify", "--"+bundleDirFlag, bundleDir, "--"+refetchFlag))
+}
+
+func TestFeeScheduleFileCommands(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	feeSchedulePath := filepath.Join(t.TempDir(), "fee-schedule.yaml")
+	require.NoError(t, os.WriteFile(feeSchedulePath, []byte(`feeSchedule:
+  - startTime: 2023-01-01T00:00:00Z
+    feeModel: fixed
+    fee: 2400000
+`), 0o600))
+
+	// the fee schedule of the file has no amount range, so the tx below the min amount of the default schedule is
+	// expected to be bridged
+	path := filepath.Join(t.TempDir(), "discrepancies.csv")
+	require.NoError(t, env.run(t, "discrepancy", "export",
+		"--"+feeScheduleFileFlag, feeSchedulePath, "--"+outputDocumentFlag, path,
+	))
+	discrepancies, err := ReadTxsDiscrepancyFromCSV(path)
+	require.NoError(t, err)
+	require.True(t, lo.ContainsBy(discrepancies, func(discrepancy TxDiscrepancy) bool {
+		return discrepancy.XrplTx.Hash == fmt.Sprintf("%064s", "A5")
+	}))
+	goldenDiscrepancies, err := ReadTxsDiscrepancyFromCSV(filepath.Join("testdata", "discrepancies.csv"))
+	require.NoError(t, err)
+	require.False(t, lo.ContainsBy(goldenDiscrepancies, func(discrepancy TxDiscrepancy) bool {
+		return discrepancy.XrplTx.Hash == fmt.Sprintf("%064s", "A5")
+	}))
+
+	// the fee schedule is recorded in the bundle and restored without the file
+	bundleDir := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, env.run(t, "report", "bundle", "--"+feeScheduleFileFlag, feeSchedulePath, "--"+bundleDirFlag, bundleDir))
+	manifest, err := ReadReportBundleManifest(bundleDir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Config.RouteFeeSchedule, 1)
+	require.NoError(t, env.run(t, "report", "verify", "--"+bundleDirFlag, bundleDir, "--"+refetchFlag))
+
+	require.NoError(t, os.WriteFile(feeSchedulePath, []byte("feeSchedule:\n  - feeModel: percent\n"), 0o600))
+	err = env.run(t, "discrepancy", "export", "--"+feeScheduleFileFlag, feeSchedulePath, "--"+outputDocumentFlag, path)
+	require.ErrorContains(t, err, `invalid fee model "percent"`)
+}
+
+func TestRoutesCommands(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	routesPath := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(routesPath, []byte(`routes:
+  - name: xrpl-coreum-core
+`+testRouteIdentity("ucore", defaultXrplCurrency, defaultBridgeChainIndex)+`  - name: other-chain-index
+`+testRouteIdentity("ucore", defaultXrplCurrency, "1")+`    feeSchedule:
+      - startTime: 2023-01-01T00:00:00Z
+        feeModel: fixed
+        fee: 1000000
+`), 0o600))
+
+	// the single route is written to the output document
+	path := filepath.Join(t.TempDir(), "discrepancies.csv")
+	require.NoError(t, env.run(t, "discrepancy", "export",
+		"--"+routesFileFlag, routesPath, "--"+routeFlag, "xrpl-coreum-core", "--"+outputDocumentFlag, path,
+	))
+	requireGoldenCSV(t, path, filepath.Join("testdata", "discrepancies.csv"))
+
+	// all the routes are written to the per-route files
+	dir := t.TempDir()
+	require.NoError(t, env.run(t, "discrepancy", "export",
+		"--"+routesFileFlag, routesPath, "--"+allRoutesFlag, "--"+outputDocumentFlag, filepath.Join(dir, "discrepancies.csv"),
+	))
+	requireGoldenCSV(t, filepath.Join(dir, "discrepancies-xrpl-coreum-core.csv"), filepath.Join("testdata", "discrepancies.csv"))
+	discrepancies, err := ReadTxsDiscrepancyFromCSV(filepath.Join(dir, "discrepancies-other-chain-index.csv"))
+	require.NoError(t, err)
+	// only the xrpl tx of the other chain index is bridged by the route
+	xrplTxHashes := make([]string, 0)
+	for _, discrepancy := range discrepancies {
+		if discrepancy.XrplTx.Hash != "" {
+			xrplTxHashes = append(xrplTxHashes, discrepancy.XrplTx.Hash)
+		}
+	}
+	require.Equal(t, []string{fmt.Sprintf("%064s", "A6")}, xrplTxHashes)
+	require.NoFileExists(t, filepath.Join(dir, "discrepancies.csv"))
+
+	bundleDir := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, env.run(t, "report", "bundle",
+		"--"+routesFileFlag, routesPath, "--"+allRoutesFlag, "--"+bundleDirFlag, bundleDir,
+	))
+	manifest, err := ReadReportBundleManifest(filepath.Join(bundleDir, "other-chain-index"))
+	require.NoError(t, err)
+	require.Equal(t, "other-chain-index", manifest.Config.Route)
+	require.Equal(t, "1", manifest.Config.BridgeChainIndex)
+	// the route and its fee schedule are restored from the bundle without the routes file
+	for _, routeName := range []string{"xrpl-coreum-core", "other-chain-index"} {
+		require.NoError(t, env.run(t, "report", "verify",
+			"--"+bundleDirFlag, filepath.Join(bundleDir, routeName), "--"+refetchFlag,
+		))
+	}
+	// the bundle isn't verified with the route config
+	err = env.run(t, "report", "verify",
+		"--"+routesFileFlag, routesPath, "--"+allRoutesFlag, "--"+bundleDirFlag, bundleDir, "--"+refetchFlag,
+	)
+	require.ErrorContains(t, err, "--route and --all-routes aren't supported by the command")
+	err = env.run(t, "discrepancy", "diff", "--"+routesFileFlag, routesPath, "--"+routeFlag, "xrpl-coreum-core",
+		filepath.Join(dir, "discrepancies-xrpl-coreum-core.csv"), filepath.Join(dir, "discrepancies-other-chain-index.csv"),
+	)
+	require.ErrorContains(t, err, "--route and --all-routes aren't supported by the command")
+
+	require.NoError(t, env.run(t, "summary", "print", "--"+routesFileFlag, routesPath, "--"+allRoutesFlag))
+
+	// the routes share the HTTP client, so the endpoints of the pool are probed once per process
+	healthProbes := env.tendermint.HealthProbes()
+	require.NoError(t, env.run(t, "discrepancy", "export",
+		"--"+routesFileFlag, routesPath, "--"+allRoutesFlag, "--"+outputDocumentFlag, filepath.Join(dir, "discrepancies.csv"),
+		"--"+coreumNodeFlag, env.tendermint.URL+"/",
+	))
+	require.Equal(t, 2, env.tendermint.HealthProbes()-healthProbes)
+
+	err = env.run(t, "summary", "print", "--"+routesFileFlag, routesPath, "--"+routeFlag, "xrpl-coreum-core", "--"+allRoutesFlag)
+	require.ErrorContains(t, err, "mutually exclusive")
+	err = env.run(t, "discrepancy", "export", "--"+routeFlag, "xrpl-coreum-core")
+	require.ErrorContains(t, err, "--routes-file must be set")
+	err = env.run(t, "discrepancy", "export", "--"+routesFileFlag, routesPath, "--"+routeFlag, "missing")
+	require.ErrorContains(t, err, "route missing not found")
+}
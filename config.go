@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"math/big"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
@@ -14,33 +18,64 @@ import (
 	"github.com/CoreumFoundation/faucet/pkg/logger"
 )
 
+// setSDKConfigOnce guards the global sdk config which can be set only once per process.
+var setSDKConfigOnce sync.Once
+
 type Config struct {
+	Now                     time.Time // the current time, or the recording time on replay
+	Route                   string    // the name of the applied route, empty if no route is applied
 	BeforeDateTime          time.Time
 	AfterDateTime           time.Time
 	Denom                   string
 	CoreumAccount           string
 	CoreumFoundationAccount string
-	CoreumRPCURL            string
-	XrplRPCAPIURL           string
+	CoreumRPCURLs           []string
+	CoreumHeight            int64 // the height to read the state as of, zero means the latest
+	CoreumGenesisFile       string
+	CoreumOpeningBalance    *big.Int
+	AddressBookEntries      []AddressBookEntry
+	XrplRPCAPIURLs          []string
+	XrplCrossCheckSample    int
+	XrplLedgerIndex         int64 // the ledger index to read the state as of, zero means the latest validated
 	XrplScanAPIURL          string
+	XrplSupplySource        string
 	XrplHistoricalAPIURL    string
 	XrplAccount             string
 	XrplCurrency            string
 	XrplIssuer              string
 	XrplFetchPoolSize       int
 	BridgeChainIndex        string
+	CoreumMemoSourceChainID string
+	MemoCodec               MemoCodecConfig
 	OutputDocument          string
 	FeeConfigs              []FeeConfig
+	RouteFeeSchedule        []RouteFeeConfig // the fee schedule of the applied route or fee schedule file, empty for the default one
+	AmountTolerance         AmountTolerance
 	IncludeAll              bool
 	MultichainRescanAPIURL  string
+	RescanPoolSize          int
+	RescanFailedFrom        string
+	RescanDiscrepancies     []string
+	RescanHashesFile        string
+	RescanSrcChainID        string
+	RescanDestChainID       string
+	RescanResultsFile       string
+	TrackInterval           time.Duration
+	TrackIterations         int
+	TrackOrphanAfter        time.Duration
+	DryRun                  bool
+	XrplTxsFile             string
+	CoreumTxsFile           string
+	BundleDir               string
+	Refetch                 bool
+	BalanceSamples          int
+	HTTP                    HTTPConfig
 }
 
 // FeeConfig the settings used for the calculation of the final amount which includes fee.
 type FeeConfig struct {
 	StartTime time.Time
-	FeeRatio  *big.Int // to get percent you need to div it by 1000, we need it to make the calculation without floats
-	MinFee    *big.Int
-	MaxFee    *big.Int
+	FeeModel  FeeModel
 	MinAmount *big.Int
 	MaxAmount *big.Int
 }
@@ -48,7 +83,11 @@ type FeeConfig struct {
 func Setup(cmd *cobra.Command) (Config, context.Context, *zap.Logger, error) {
 	loggerConfig, _ := logger.ConfigureWithCLI(logger.ToolDefaultConfig)
 	log := logger.New(loggerConfig)
-	ctx := logger.WithLogger(context.Background(), log)
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = logger.WithLogger(ctx, log)
 
 	сfg, err := getConfig(cmd)
 	if err != nil {
@@ -58,7 +97,53 @@ func Setup(cmd *cobra.Command) (Config, context.Context, *zap.Logger, error) {
 	return сfg, ctx, log, nil
 }
 
+// defaultFeeSchedule is the fee schedule of the CORE bridge used if the fee schedule file isn't set.
+var defaultFeeSchedule = []RouteFeeConfig{
+	{
+		StartTime: time.Date(2023, time.Month(3), 24, 17, 0, 0, 0, time.UTC),
+		FeeModel:  RouteFeeModelPerMille,
+		Ratio:     1,                                 // 0.1%
+		MinFee:    lo.ToPtr[int64](2_400000),         // 2.4 CORE
+		MaxFee:    lo.ToPtr[int64](477_000000),       // 477 CORE
+		MinAmount: lo.ToPtr[int64](4_800000),         // 4.8 CORE
+		MaxAmount: lo.ToPtr[int64](2_400_000_000000), // 2.400.000 CORE
+	},
+	{
+		StartTime: time.Date(2023, time.Month(3), 17, 13, 0, 0, 0, time.UTC),
+		FeeModel:  RouteFeeModelPerMille,
+		Ratio:     1,                           // 0.1%
+		MinFee:    lo.ToPtr[int64](7000),       // 0.007 CORE
+		MaxFee:    lo.ToPtr[int64](50000),      // 0.05 CORE
+		MinAmount: lo.ToPtr[int64](8000),       // 0.008 CORE
+		MaxAmount: lo.ToPtr[int64](100_000000), // 100 CORE
+	},
+	{
+		StartTime: time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC),
+		FeeModel:  RouteFeeModelPerMille,
+		Ratio:     1,                                 // 0.1%
+		MinFee:    lo.ToPtr[int64](2_400000),         // 2.4 CORE
+		MaxFee:    lo.ToPtr[int64](477_000000),       // 477 CORE
+		MinAmount: lo.ToPtr[int64](4_800000),         // 4.8 CORE
+		MaxAmount: lo.ToPtr[int64](2_400_000_000000), // 2.400.000 CORE
+	},
+}
+
 func getConfig(cmd *cobra.Command) (Config, error) {
+	httpConfig, err := getHTTPConfig(cmd)
+	if err != nil {
+		return Config{}, err
+	}
+
+	now := defaultBeforeDateTime
+	if httpConfig.ReplayDir != "" {
+		// the replayed requests must be the same as the recorded ones, so the recording time is used
+		manifest, err := ReadFixtureManifest(httpConfig.ReplayDir)
+		if err != nil {
+			return Config{}, err
+		}
+		now = manifest.RecordedAt
+	}
+
 	beforeDateTimeString, err := cmd.Flags().GetString(beforeDateTimeFlag)
 	if err != nil {
 		return Config{}, err
@@ -67,6 +152,9 @@ func getConfig(cmd *cobra.Command) (Config, error) {
 	if err != nil {
 		return Config{}, errors.Errorf("error parsing %s the expected format is %s", beforeDateTimeFlag, time.DateTime)
 	}
+	if !cmd.Flags().Changed(beforeDateTimeFlag) {
+		beforeDateTime = now
+	}
 
 	afterDateTimeString, err := cmd.Flags().GetString(afterDateTimeFlag)
 	if err != nil {
@@ -82,9 +170,9 @@ func getConfig(cmd *cobra.Command) (Config, error) {
 		return Config{}, err
 	}
 
-	network.SetSDKConfig()
+	setSDKConfigOnce.Do(network.SetSDKConfig)
 
-	coreumRPCAddress, err := cmd.Flags().GetString(coreumNodeFlag)
+	coreumRPCAddresses, err := cmd.Flags().GetStringSlice(coreumNodeFlag)
 	if err != nil {
 		return Config{}, err
 	}
@@ -99,12 +187,75 @@ func getConfig(cmd *cobra.Command) (Config, error) {
 		return Config{}, err
 	}
 
+	coreumHeight, err := cmd.Flags().GetInt64(coreumHeightFlag)
+	if err != nil {
+		return Config{}, err
+	}
+	if coreumHeight < 0 {
+		return Config{}, errors.Errorf("invalid %s %d, must be non-negative", coreumHeightFlag, coreumHeight)
+	}
+
+	coreumGenesisFile, err := cmd.Flags().GetString(coreumGenesisFileFlag)
+	if err != nil {
+		return Config{}, err
+	}
+
+	coreumOpeningBalance, err := cmd.Flags().GetInt64(coreumOpeningBalanceFlag)
+	if err != nil {
+		return Config{}, err
+	}
+	if coreumOpeningBalance < 0 {
+		return Config{}, errors.Errorf("invalid %s %d, must be non-negative", coreumOpeningBalanceFlag, coreumOpeningBalance)
+	}
+	if coreumGenesisFile != "" && coreumOpeningBalance != 0 {
+		return Config{}, errors.Errorf("%s and %s can't be used together", coreumGenesisFileFlag, coreumOpeningBalanceFlag)
+	}
+
+	addressBookFile, err := cmd.Flags().GetString(addressBookFlag)
+	if err != nil {
+		return Config{}, err
+	}
+	var addressBookEntries []AddressBookEntry
+	if addressBookFile != "" {
+		if addressBookEntries, err = ReadAddressBookEntries(addressBookFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	xrplLedgerIndex, err := cmd.Flags().GetInt64(xrplLedgerIndexFlag)
+	if err != nil {
+		return Config{}, err
+	}
+	if xrplLedgerIndex < 0 {
+		return Config{}, errors.Errorf("invalid %s %d, must be non-negative", xrplLedgerIndexFlag, xrplLedgerIndex)
+	}
+
+	xrplSupplySource, err := cmd.Flags().GetString(xrplSupplySourceFlag)
+	if err != nil {
+		return Config{}, err
+	}
+	if !lo.Contains(xrplSupplySources, xrplSupplySource) {
+		return Config{}, errors.Errorf("invalid %s %q, must be one of: %s", xrplSupplySourceFlag, xrplSupplySource, strings.Join(xrplSupplySources, ", "))
+	}
+	// xrpscan doesn't support the ledger index, so the pinned supply is read from rippled
+	if xrplLedgerIndex > 0 && xrplSupplySource != XrplSupplySourceRippled {
+		if cmd.Flags().Changed(xrplSupplySourceFlag) {
+			return Config{}, errors.Errorf("%s %q can't be used with %s", xrplSupplySourceFlag, xrplSupplySource, xrplLedgerIndexFlag)
+		}
+		xrplSupplySource = XrplSupplySourceRippled
+	}
+
 	xrplFetchPullSize, err := cmd.Flags().GetInt(xrplFetchPoolSizeFlag)
 	if err != nil {
 		return Config{}, err
 	}
 
-	xrplRPCAPIURL, err := cmd.Flags().GetString(xrplRPCAPIURLFlag)
+	xrplRPCAPIURLs, err := cmd.Flags().GetStringSlice(xrplRPCAPIURLFlag)
+	if err != nil {
+		return Config{}, err
+	}
+
+	xrplCrossCheckSample, err := cmd.Flags().GetInt(xrplCrossCheckSampleFlag)
 	if err != nil {
 		return Config{}, err
 	}
@@ -139,6 +290,162 @@ func getConfig(cmd *cobra.Command) (Config, error) {
 		return Config{}, err
 	}
 
+	coreumMemoSourceChainID, err := cmd.Flags().GetString(coreumMemoSourceChainIDFlag)
+	if err != nil {
+		return Config{}, err
+	}
+
+	memoCodecConfig, err := getMemoCodecConfig(cmd)
+	if err != nil {
+		return Config{}, err
+	}
+	if _, err := NewMemoCodec(memoCodecConfig, bridgeChainIndex); err != nil {
+		return Config{}, err
+	}
+
+	amountAbsoluteTolerance, err := cmd.Flags().GetInt64(amountToleranceFlag)
+	if err != nil {
+		return Config{}, err
+	}
+
+	amountRelativeTolerance, err := cmd.Flags().GetInt64(amountRelativeToleranceFlag)
+	if err != nil {
+		return Config{}, err
+	}
+
+	amountTolerance := AmountTolerance{
+		Absolute:    big.NewInt(amountAbsoluteTolerance),
+		RelativePPM: big.NewInt(amountRelativeTolerance),
+	}
+
+	rescanPoolSize := 0
+	if cmd.Flags().Lookup(rescanPoolSizeFlag) != nil {
+		rescanPoolSize, err = cmd.Flags().GetInt(rescanPoolSizeFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	rescanFailedFrom := ""
+	if cmd.Flags().Lookup(rescanFailedFromFlag) != nil {
+		rescanFailedFrom, err = cmd.Flags().GetString(rescanFailedFromFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	var rescanDiscrepancies []string
+	if cmd.Flags().Lookup(rescanDiscrepanciesFlag) != nil {
+		rescanDiscrepancies, err = cmd.Flags().GetStringSlice(rescanDiscrepanciesFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	rescanHashesFile := ""
+	if cmd.Flags().Lookup(rescanHashesFileFlag) != nil {
+		rescanHashesFile, err = cmd.Flags().GetString(rescanHashesFileFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	rescanSrcChainID := ""
+	if cmd.Flags().Lookup(rescanSrcChainIDFlag) != nil {
+		rescanSrcChainID, err = cmd.Flags().GetString(rescanSrcChainIDFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	rescanDestChainID := ""
+	if cmd.Flags().Lookup(rescanDestChainIDFlag) != nil {
+		rescanDestChainID, err = cmd.Flags().GetString(rescanDestChainIDFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	rescanResultsFile := ""
+	if cmd.Flags().Lookup(rescanResultsFlag) != nil {
+		rescanResultsFile, err = cmd.Flags().GetString(rescanResultsFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	trackInterval := time.Duration(0)
+	if cmd.Flags().Lookup(trackIntervalFlag) != nil {
+		trackInterval, err = cmd.Flags().GetDuration(trackIntervalFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	trackIterations := 0
+	if cmd.Flags().Lookup(trackIterationsFlag) != nil {
+		trackIterations, err = cmd.Flags().GetInt(trackIterationsFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	trackOrphanAfter := time.Duration(0)
+	if cmd.Flags().Lookup(trackOrphanAfterFlag) != nil {
+		trackOrphanAfter, err = cmd.Flags().GetDuration(trackOrphanAfterFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	dryRun := false
+	if cmd.Flags().Lookup(dryRunFlag) != nil {
+		dryRun, err = cmd.Flags().GetBool(dryRunFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	xrplTxsFile := ""
+	if cmd.Flags().Lookup(xrplTxsFileFlag) != nil {
+		xrplTxsFile, err = cmd.Flags().GetString(xrplTxsFileFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	coreumTxsFile := ""
+	if cmd.Flags().Lookup(coreumTxsFileFlag) != nil {
+		coreumTxsFile, err = cmd.Flags().GetString(coreumTxsFileFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	bundleDir := ""
+	if cmd.Flags().Lookup(bundleDirFlag) != nil {
+		bundleDir, err = cmd.Flags().GetString(bundleDirFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	refetch := false
+	if cmd.Flags().Lookup(refetchFlag) != nil {
+		refetch, err = cmd.Flags().GetBool(refetchFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	balanceSamples := 0
+	if cmd.Flags().Lookup(balanceSamplesFlag) != nil {
+		balanceSamples, err = cmd.Flags().GetInt(balanceSamplesFlag)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
 	outputDocument := ""
 	if cmd.Flags().Lookup(outputDocumentFlag) != nil {
 		outputDocument, err = cmd.Flags().GetString(outputDocumentFlag)
@@ -163,54 +470,354 @@ func getConfig(cmd *cobra.Command) (Config, error) {
 		}
 	}
 
-	// the feeConfigs are fixed, and can be modified in the code only
 	// we use the list of the configs since the fees have been modified during the bridge life, and
 	// each time period uses different fee configs.
-	feeConfigs := []FeeConfig{
-		{
-			StartTime: time.Date(2023, time.Month(3), 24, 17, 0, 0, 0, time.UTC),
-			FeeRatio:  big.NewInt(1),                // 0.1%
-			MinFee:    big.NewInt(2_400000),         // 2.4 CORE
-			MaxFee:    big.NewInt(477_000000),       // 477 CORE
-			MinAmount: big.NewInt(4_800000),         // 4.8 CORE
-			MaxAmount: big.NewInt(2_400_000_000000), // 2.400.000 CORE
-		},
-		{
-			StartTime: time.Date(2023, time.Month(3), 17, 13, 0, 0, 0, time.UTC),
-			FeeRatio:  big.NewInt(1),          // 0.1%
-			MinFee:    big.NewInt(7000),       // 0.007 CORE
-			MaxFee:    big.NewInt(50000),      // 0.05 CORE
-			MinAmount: big.NewInt(8000),       // 0.008 CORE
-			MaxAmount: big.NewInt(100_000000), // 100 CORE
-		},
-		{
-			StartTime: time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC),
-			FeeRatio:  big.NewInt(1),                // 0.1%
-			MinFee:    big.NewInt(2_400000),         // 2.4 CORE
-			MaxFee:    big.NewInt(477_000000),       // 477 CORE
-			MinAmount: big.NewInt(4_800000),         // 4.8 CORE
-			MaxAmount: big.NewInt(2_400_000_000000), // 2.400.000 CORE
-		},
-	}
-
-	return Config{
+	feeScheduleFile, err := cmd.Flags().GetString(feeScheduleFileFlag)
+	if err != nil {
+		return Config{}, err
+	}
+	feeSchedule := defaultFeeSchedule
+	var routeFeeSchedule []RouteFeeConfig
+	if feeScheduleFile != "" {
+		if feeSchedule, err = ReadFeeSchedule(feeScheduleFile); err != nil {
+			return Config{}, err
+		}
+		routeFeeSchedule = feeSchedule
+	}
+	feeConfigs, err := convertRouteFeeSchedule(feeSchedule)
+	if err != nil {
+		return Config{}, errors.Errorf("invalid fee schedule, err: %s", err)
+	}
+
+	cfg := Config{
+		Now:                     now.UTC(),
 		BeforeDateTime:          beforeDateTime.UTC(),
 		AfterDateTime:           afterDateTime.UTC(),
 		Denom:                   network.Denom(),
 		CoreumAccount:           coreumAccount,
 		CoreumFoundationAccount: coreumFoundationAccount,
-		CoreumRPCURL:            coreumRPCAddress,
+		CoreumRPCURLs:           coreumRPCAddresses,
+		CoreumHeight:            coreumHeight,
+		CoreumGenesisFile:       coreumGenesisFile,
+		CoreumOpeningBalance:    big.NewInt(coreumOpeningBalance),
+		AddressBookEntries:      addressBookEntries,
 		XrplFetchPoolSize:       xrplFetchPullSize,
-		XrplRPCAPIURL:           xrplRPCAPIURL,
+		XrplRPCAPIURLs:          xrplRPCAPIURLs,
+		XrplCrossCheckSample:    xrplCrossCheckSample,
+		XrplLedgerIndex:         xrplLedgerIndex,
 		XrplScanAPIURL:          xrplScanAPIURL,
+		XrplSupplySource:        xrplSupplySource,
 		XrplHistoricalAPIURL:    xrplHistoricalAPIURL,
 		XrplAccount:             xrplAccount,
 		XrplCurrency:            xrplCurrency,
 		XrplIssuer:              xrplIssuer,
 		BridgeChainIndex:        bridgeChainIndex,
+		CoreumMemoSourceChainID: coreumMemoSourceChainID,
+		MemoCodec:               memoCodecConfig,
 		OutputDocument:          outputDocument,
 		FeeConfigs:              feeConfigs,
+		RouteFeeSchedule:        routeFeeSchedule,
+		AmountTolerance:         amountTolerance,
 		IncludeAll:              includeAll,
 		MultichainRescanAPIURL:  multichainRescanAPIURL,
+		RescanPoolSize:          rescanPoolSize,
+		RescanFailedFrom:        rescanFailedFrom,
+		RescanDiscrepancies:     rescanDiscrepancies,
+		RescanHashesFile:        rescanHashesFile,
+		RescanSrcChainID:        rescanSrcChainID,
+		RescanDestChainID:       rescanDestChainID,
+		RescanResultsFile:       rescanResultsFile,
+		TrackInterval:           trackInterval,
+		TrackIterations:         trackIterations,
+		TrackOrphanAfter:        trackOrphanAfter,
+		DryRun:                  dryRun,
+		XrplTxsFile:             xrplTxsFile,
+		CoreumTxsFile:           coreumTxsFile,
+		BundleDir:               bundleDir,
+		Refetch:                 refetch,
+		BalanceSamples:          balanceSamples,
+		HTTP:                    httpConfig,
+	}
+
+	return applyRoute(cmd, cfg)
+}
+
+// applyRoute applies the route selected by the flags to the config. The route is selected per run with the
+// --all-routes, so the output files and dirs and the files written by the previous per-route runs get the route name.
+func applyRoute(cmd *cobra.Command, cfg Config) (Config, error) {
+	routeName, err := cmd.Flags().GetString(routeFlag)
+	if err != nil {
+		return Config{}, err
+	}
+	if routeName == "" {
+		return cfg, nil
+	}
+	routes, err := getRoutes(cmd)
+	if err != nil {
+		return Config{}, err
+	}
+	route, err := FindRoute(routes, routeName)
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg, err = route.Apply(cfg); err != nil {
+		return Config{}, err
+	}
+
+	allRoutes, err := cmd.Flags().GetBool(allRoutesFlag)
+	if err != nil {
+		return Config{}, err
+	}
+	if allRoutes {
+		cfg.OutputDocument = routeFilePath(cfg.OutputDocument, routeName)
+		cfg.RescanFailedFrom = routeFilePath(cfg.RescanFailedFrom, routeName)
+		cfg.RescanResultsFile = routeFilePath(cfg.RescanResultsFile, routeName)
+		cfg.XrplTxsFile = routeFilePath(cfg.XrplTxsFile, routeName)
+		cfg.CoreumTxsFile = routeFilePath(cfg.CoreumTxsFile, routeName)
+		if cfg.BundleDir != "" {
+			cfg.BundleDir = filepath.Join(cfg.BundleDir, routeName)
+		}
+	}
+
+	return cfg, nil
+}
+
+// getRoutes reads the routes file set by the flag.
+func getRoutes(cmd *cobra.Command) ([]Route, error) {
+	routesFile, err := cmd.Flags().GetString(routesFileFlag)
+	if err != nil {
+		return nil, err
+	}
+	if routesFile == "" {
+		return nil, errors.Errorf("--%s must be set to select the route", routesFileFlag)
+	}
+
+	return ReadRoutes(routesFile)
+}
+
+// getAllRouteNames returns the names of all the routes with the --all-routes, or nil without it.
+func getAllRouteNames(cmd *cobra.Command) ([]string, error) {
+	allRoutes, err := cmd.Flags().GetBool(allRoutesFlag)
+	if err != nil {
+		return nil, err
+	}
+	if !allRoutes {
+		return nil, nil
+	}
+	if cmd.Flags().Changed(routeFlag) {
+		return nil, errors.Errorf("--%s and --%s are mutually exclusive", routeFlag, allRoutesFlag)
+	}
+	routes, err := getRoutes(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(routes, func(route Route, _ int) string { return route.Name }), nil
+}
+
+func getHTTPConfig(cmd *cobra.Command) (HTTPConfig, error) {
+	requestTimeout, err := cmd.Flags().GetDuration(httpRequestTimeoutFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	maxRetries, err := cmd.Flags().GetInt(httpMaxRetriesFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	minBackoff, err := cmd.Flags().GetDuration(httpMinBackoffFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	maxBackoff, err := cmd.Flags().GetDuration(httpMaxBackoffFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	rateLimit, err := cmd.Flags().GetFloat64(httpRateLimitFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	rateBurst, err := cmd.Flags().GetInt(httpRateBurstFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	endpointMaxLag, err := cmd.Flags().GetInt64(endpointMaxLagFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	endpointCooldown, err := cmd.Flags().GetDuration(endpointCooldownFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	endpointHealthCheckInterval, err := cmd.Flags().GetDuration(endpointHealthCheckFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	recordDir, err := cmd.Flags().GetString(recordFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	replayDir, err := cmd.Flags().GetString(replayFlag)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+	if recordDir != "" && replayDir != "" {
+		return HTTPConfig{}, errors.Errorf("%s and %s can't be used together", recordFlag, replayFlag)
+	}
+
+	return HTTPConfig{
+		RequestTimeout: requestTimeout,
+		MaxRetries:     maxRetries,
+		MinBackoff:     minBackoff,
+		MaxBackoff:     maxBackoff,
+		RateLimit:      rateLimit,
+		RateBurst:      rateBurst,
+
+		EndpointMaxLag:              endpointMaxLag,
+		EndpointCooldown:            endpointCooldown,
+		EndpointHealthCheckInterval: endpointHealthCheckInterval,
+
+		RecordDir: recordDir,
+		ReplayDir: replayDir,
+	}, nil
+}
+
+// httpClientKey is the key of the HTTP client of the httpClientCache, the routes share the endpoints and HTTP config
+// of the flags, so they share the client.
+type httpClientKey struct {
+	xrplRPCAPIURLs string
+	coreumRPCURLs  string
+	http           HTTPConfig
+}
+
+// httpClientCache keeps the HTTP clients of the process, so the command which runs once per route or sets up the
+// client several times reuses the client, and its health checks are started once.
+type httpClientCache struct {
+	mu      sync.Mutex
+	clients map[httpClientKey]*HTTPClient
+}
+
+type httpClientCacheKey struct{}
+
+// withHTTPClientCache returns the context with the cache of the HTTP clients set up by the SetupHTTPClient.
+func withHTTPClientCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, httpClientCacheKey{}, &httpClientCache{clients: make(map[httpClientKey]*HTTPClient)})
+}
+
+// SetupHTTPClient returns the HTTP client of the context cache for the config, or creates the HTTP client with the
+// endpoint pools for the xrpl and coreum RPC endpoints, checks the endpoints health and starts the periodic health
+// checks.
+func SetupHTTPClient(ctx context.Context, cfg Config) (*HTTPClient, error) {
+	cache, ok := ctx.Value(httpClientCacheKey{}).(*httpClientCache)
+	if !ok {
+		return setupHTTPClient(ctx, cfg)
+	}
+
+	key := httpClientKey{
+		xrplRPCAPIURLs: strings.Join(cfg.XrplRPCAPIURLs, ","),
+		coreumRPCURLs:  strings.Join(cfg.CoreumRPCURLs, ","),
+		http:           cfg.HTTP,
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if httpClient, ok := cache.clients[key]; ok {
+		return httpClient, nil
+	}
+	httpClient, err := setupHTTPClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cache.clients[key] = httpClient
+
+	return httpClient, nil
+}
+
+func setupHTTPClient(ctx context.Context, cfg Config) (*HTTPClient, error) {
+	xrplPool, err := NewEndpointPool(
+		"xrpl", cfg.XrplRPCAPIURLs, XrplEndpointHealthProbe, cfg.HTTP.EndpointMaxLag, cfg.HTTP.EndpointCooldown,
+	)
+	if err != nil {
+		return nil, err
+	}
+	coreumPool, err := NewEndpointPool(
+		"coreum", cfg.CoreumRPCURLs, CoreumEndpointHealthProbe, cfg.HTTP.EndpointMaxLag, cfg.HTTP.EndpointCooldown,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.HTTP.RecordDir != "" {
+		if err := WriteFixtureManifest(cfg.HTTP.RecordDir, FixtureManifest{RecordedAt: cfg.Now}); err != nil {
+			return nil, err
+		}
+	}
+
+	httpClient := NewHTTPClient(cfg.HTTP, xrplPool, coreumPool)
+
+	log := logger.Get(ctx)
+	for _, pool := range httpClient.Pools() {
+		// the health is checked only if there is an alternative
+		if len(pool.URLs()) < 2 {
+			continue
+		}
+		for _, status := range pool.CheckHealth(ctx, httpClient) {
+			switch {
+			case status.Err != nil:
+				log.Warn("Endpoint is unhealthy", zap.String("Pool", pool.Name()), zap.String("URL", status.URL), zap.Error(status.Err))
+			case status.Behind:
+				log.Warn("Endpoint is behind", zap.String("Pool", pool.Name()), zap.String("URL", status.URL), zap.Int64("Height", status.Height))
+			default:
+				log.Info("Endpoint is healthy", zap.String("Pool", pool.Name()), zap.String("URL", status.URL), zap.Int64("Height", status.Height))
+			}
+		}
+	}
+	StartHealthChecks(ctx, httpClient, cfg.HTTP.EndpointHealthCheckInterval)
+
+	return httpClient, nil
+}
+
+func getMemoCodecConfig(cmd *cobra.Command) (MemoCodecConfig, error) {
+	name, err := cmd.Flags().GetString(memoCodecFlag)
+	if err != nil {
+		return MemoCodecConfig{}, err
+	}
+	memoType, err := cmd.Flags().GetString(xrplMemoTypeFlag)
+	if err != nil {
+		return MemoCodecConfig{}, err
+	}
+	memoFormat, err := cmd.Flags().GetString(xrplMemoFormatFlag)
+	if err != nil {
+		return MemoCodecConfig{}, err
+	}
+	coreumMemoFormat, err := cmd.Flags().GetString(coreumMemoFormatFlag)
+	if err != nil {
+		return MemoCodecConfig{}, err
+	}
+	destinationTagsFile, err := cmd.Flags().GetString(xrplDestinationTagsFlag)
+	if err != nil {
+		return MemoCodecConfig{}, err
+	}
+	var destinationTags map[uint32]string
+	if destinationTagsFile != "" {
+		if destinationTags, err = ReadXrplDestinationTags(destinationTagsFile); err != nil {
+			return MemoCodecConfig{}, err
+		}
+	}
+
+	return MemoCodecConfig{
+		Name:             name,
+		MemoType:         memoType,
+		MemoFormat:       memoFormat,
+		CoreumMemoFormat: coreumMemoFormat,
+		DestinationTags:  destinationTags,
 	}, nil
 }
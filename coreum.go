@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +18,10 @@ import (
 	"github.com/gammazero/workerpool"
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
+	abci "github.com/tendermint/tendermint/abci/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	"go.uber.org/zap"
 
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
@@ -34,6 +39,9 @@ type bankSendWithMemo struct {
 	*banktypes.MsgSend
 	Memo      string
 	Timestamp time.Time
+	Height    int64
+	// the coins spent by the sender in the tx including the gas fee, from the coin_spent events
+	SpentCoins sdk.Coins
 }
 
 // GetCoreumAuditTransactions returns the list of the valid coreum bridge transaction converted to the audit model.
@@ -48,7 +56,74 @@ func GetCoreumAuditTransactions(
 		return nil, err
 	}
 
-	return convertBankTxsToAuditTxs(bankTxs, denom), nil
+	auditTxs := convertBankTxsToAuditTxs(bankTxs, denom)
+	sortAuditTxs(auditTxs)
+
+	return auditTxs, nil
+}
+
+// GetCoreumBalanceTransactions returns the txs received and spent by the account converted to the audit model. Unlike
+// GetCoreumAuditTransactions the txs of any messages are returned, since the amount is the sum of the coins received
+// or spent by the account in the coin_received and coin_spent events, the spent amount includes the gas fee.
+func GetCoreumBalanceTransactions(
+	ctx context.Context,
+	clientCtx client.Context,
+	account, denom string,
+	beforeDateTime, afterDateTime time.Time,
+) ([]AuditTx, []AuditTx, error) {
+	incomingTxs, err := getCoreumBalanceTransactions(
+		ctx, clientCtx, banktypes.EventTypeCoinReceived, banktypes.AttributeKeyReceiver, account, denom, beforeDateTime, afterDateTime,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	outgoingTxs, err := getCoreumBalanceTransactions(
+		ctx, clientCtx, banktypes.EventTypeCoinSpent, banktypes.AttributeKeySpender, account, denom, beforeDateTime, afterDateTime,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return incomingTxs, outgoingTxs, nil
+}
+
+func getCoreumBalanceTransactions(
+	ctx context.Context,
+	clientCtx client.Context,
+	eventType, addressKey, account, denom string,
+	beforeDateTime, afterDateTime time.Time,
+) ([]AuditTx, error) {
+	// the txs aren't decoded, since the account txs might have the messages of any modules
+	txs, err := queryCoreumTxs(
+		ctx, clientCtx, fmt.Sprintf("%s.%s='%s'", eventType, addressKey, account), beforeDateTime, afterDateTime, searchCoreumTxs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	auditTxs := make([]AuditTx, 0, len(txs))
+	for _, txAny := range txs {
+		timestamp, err := time.Parse(time.RFC3339, txAny.Timestamp)
+		if err != nil {
+			return nil, errors.Errorf("can't parse time: %s with format %s", txAny.Timestamp, time.RFC3339)
+		}
+		if timestamp.After(beforeDateTime) || timestamp.Before(afterDateTime) {
+			continue
+		}
+		coins, err := getEventCoins(txAny.Events, eventType, addressKey, account)
+		if err != nil {
+			return nil, errors.Errorf("can't get %s coins of tx %s, err: %s", eventType, txAny.TxHash, err)
+		}
+		auditTxs = append(auditTxs, AuditTx{
+			Hash:      txAny.TxHash,
+			Amount:    coins.AmountOf(denom).BigInt(),
+			Timestamp: timestamp,
+			Height:    txAny.Height,
+		})
+	}
+	sortAuditTxs(auditTxs)
+
+	return auditTxs, nil
 }
 
 // GetCoreumAccountBalance returns the coreum account balance.
@@ -65,7 +140,17 @@ func GetCoreumAccountBalance(ctx context.Context, clientCtx client.Context, acco
 	return res.Balance.Amount.BigInt(), nil
 }
 
-func createClientContext(cfg Config) client.Context {
+// GetCoreumLatestHeight returns the latest block height.
+func GetCoreumLatestHeight(ctx context.Context, clientCtx client.Context) (int64, error) {
+	status, err := clientCtx.Client.Status(ctx)
+	if err != nil {
+		return 0, errors.Errorf("can't get node status, err: %s", err)
+	}
+
+	return status.SyncInfo.LatestBlockHeight, nil
+}
+
+func createClientContext(cfg Config, httpClient *HTTPClient) client.Context {
 	// List required modules.
 	// If you need types from any other module import them and add here.
 	modules := module.NewBasicManager(
@@ -73,7 +158,7 @@ func createClientContext(cfg Config) client.Context {
 		wbank.AppModuleBasic{},
 	)
 
-	rpcClient, err := client.NewClientFromNode(cfg.CoreumRPCURL)
+	rpcClient, err := rpchttp.NewWithClient(cfg.CoreumRPCURLs[0], "/websocket", httpClient.Client())
 	if err != nil {
 		panic(err)
 	}
@@ -85,11 +170,42 @@ func createClientContext(cfg Config) client.Context {
 		WithCodec(encodingConfig.Codec).
 		WithInterfaceRegistry(encodingConfig.InterfaceRegistry).
 		WithTxConfig(encodingConfig.TxConfig).
-		WithLegacyAmino(encodingConfig.Amino)
+		WithLegacyAmino(encodingConfig.Amino).
+		WithHeight(cfg.CoreumHeight)
 
 	return clientCtx
 }
 
+// contextRPCClient is the rpc client which sends the tx search and block requests with its context, since the sdk
+// queries send them with the background context.
+type contextRPCClient struct {
+	rpcclient.Client
+	ctx context.Context
+}
+
+func (c contextRPCClient) TxSearch(
+	_ context.Context,
+	query string,
+	prove bool,
+	page, perPage *int,
+	orderBy string,
+) (*ctypes.ResultTxSearch, error) {
+	return c.Client.TxSearch(c.ctx, query, prove, page, perPage, orderBy)
+}
+
+func (c contextRPCClient) Block(_ context.Context, height *int64) (*ctypes.ResultBlock, error) {
+	return c.Client.Block(c.ctx, height)
+}
+
+// withRPCContext returns the client context which sends the sdk queries with the ctx, so they are cancelled with it.
+func withRPCContext(ctx context.Context, clientCtx client.Context) client.Context {
+	if clientCtx.Client == nil {
+		return clientCtx
+	}
+
+	return clientCtx.WithClient(contextRPCClient{Client: clientCtx.Client, ctx: ctx})
+}
+
 // getTxsWithSingleBankSend returns transactions filtered by the provided event and time.
 // It assumes that all the transactions contain only a single bank send, and errors out
 // if this is not true. We can start with this assumption and write more complicated type assertion
@@ -100,13 +216,82 @@ func getTxsWithSingleBankSend(
 	event string,
 	beforeDateTime, afterDateTime time.Time,
 ) ([]bankSendWithMemo, error) {
+	log := logger.Get(ctx)
+	txs, err := queryCoreumTxs(ctx, clientCtx, event, beforeDateTime, afterDateTime, authtx.QueryTxsByEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	var bankSendMessages []bankSendWithMemo
+	for _, txAny := range txs {
+		tx, ok := txAny.Tx.GetCachedValue().(*sdktx.Tx)
+		if !ok {
+			return nil, errors.New("tx does not implement sdk.Tx interface")
+		}
+
+		messages := tx.GetMsgs()
+		if len(messages) != 1 {
+			return nil, errors.New("there should be only 1 message in the transaction")
+		}
+
+		msg := messages[0]
+		bankSend, ok := msg.(*banktypes.MsgSend)
+		if !ok {
+			return nil, errors.New("message is not bank MsgSend type")
+		}
+		timestamp, err := time.Parse(time.RFC3339, txAny.Timestamp)
+		if timestamp.After(beforeDateTime) {
+			continue
+		}
+		if timestamp.Before(afterDateTime) {
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.Errorf("can't parse time: %s with format %s", txAny.Timestamp, time.RFC3339)
+		}
+		spentCoins, err := getEventCoins(txAny.Events, banktypes.EventTypeCoinSpent, banktypes.AttributeKeySpender, bankSend.FromAddress)
+		if err != nil {
+			return nil, errors.Errorf("can't get spent coins of tx %s, err: %s", txAny.TxHash, err)
+		}
+		bankSendMessages = append(bankSendMessages, bankSendWithMemo{
+			Hash:       txAny.TxHash,
+			MsgSend:    bankSend,
+			Memo:       tx.Body.Memo,
+			Timestamp:  timestamp,
+			Height:     txAny.Height,
+			SpentCoins: spentCoins,
+		})
+	}
+
+	log.Info(fmt.Sprintf("Found coreum txs total: %d", len(bankSendMessages)))
+
+	return bankSendMessages, nil
+}
+
+// queryCoreumTxsPageFunc queries the page of the txs filtered by the events.
+type queryCoreumTxsPageFunc func(
+	clientCtx client.Context, events []string, page, limit int, orderBy string,
+) (*sdk.SearchTxsResult, error)
+
+// queryCoreumTxs returns all the transactions filtered by the provided event, the pages are queried by the queryPage.
+func queryCoreumTxs(
+	ctx context.Context,
+	clientCtx client.Context,
+	event string,
+	beforeDateTime, afterDateTime time.Time,
+	queryPage queryCoreumTxsPageFunc,
+) ([]*sdk.TxResponse, error) {
 	log := logger.Get(ctx)
 	log.Info(fmt.Sprintf("Fetching coreum txs before: %s, after: %s ...", beforeDateTime.Format(time.DateTime), afterDateTime.Format(time.DateTime)))
 
 	tmEvents := []string{event}
+	// the txs are pinned to the height of the client context
+	if clientCtx.Height > 0 {
+		tmEvents = append(tmEvents, fmt.Sprintf("tx.height<=%d", clientCtx.Height))
+	}
 
 	limit := 100 // 100 is the max limit
-	var bankSendMessages []bankSendWithMemo
 
 	// allocate limited pool to fetch tx in parallel
 	workerPool := workerpool.New(coreumTxFetcherPoolSize)
@@ -114,90 +299,82 @@ func getTxsWithSingleBankSend(
 
 	// We make first query only to get the total number of txs & pages.
 	// Later all pages are fetched in parallel to have consistent logic.
-	res0, err := authtx.QueryTxsByEvents(clientCtx, tmEvents, 1, limit, "")
+	res0, err := queryPage(withRPCContext(ctx, clientCtx), tmEvents, 1, limit, "")
 	if err != nil {
 		return nil, err
 	}
 
-	txs := make([]*sdk.TxResponse, 0)
+	// the pages are fetched in parallel, so each page is kept separately to preserve the order
+	pages := make([][]*sdk.TxResponse, res0.PageTotal)
+
+	// the fetch context is cancelled on the first failed fetch to skip the queued pages
+	fetchCtx, fetchCtxCancel := context.WithCancel(ctx)
+	defer fetchCtxCancel()
+	fetchClientCtx := withRPCContext(fetchCtx, clientCtx)
 
 	mu := sync.Mutex{}
 	wg := sync.WaitGroup{}
 
-	var fetchError error
+	var (
+		fetchError  error
+		failedPages []int
+	)
 
 	for page := 1; page <= int(res0.PageTotal); page++ {
 		pageToFetch := page
 		wg.Add(1)
 		workerPool.Submit(func() {
 			defer wg.Done()
+			// the fetch is cancelled, skip the queued page
+			if fetchCtx.Err() != nil {
+				return
+			}
 
 			log.Info("Fetching", zap.String("Page", fmt.Sprintf("%d/%d", pageToFetch, res0.PageTotal)))
-			res, err := authtx.QueryTxsByEvents(clientCtx, tmEvents, pageToFetch, limit, "")
+			res, err := queryPage(fetchClientCtx, tmEvents, pageToFetch, limit, "")
+
+			mu.Lock()
+			defer mu.Unlock()
+
 			if err != nil {
-				fetchError = multierror.Append(fetchError, err)
 				log.Error("Can't fetch page", zap.String("Page", fmt.Sprintf("%d", pageToFetch)), zap.Error(err))
+				failedPages = append(failedPages, pageToFetch)
+				fetchError = multierror.Append(fetchError, err)
+				fetchCtxCancel()
 				return
 			}
 
-			mu.Lock()
-			defer mu.Unlock()
-
-			txs = append(txs, res.Txs...)
+			pages[pageToFetch-1] = res.Txs
 		})
 	}
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "fetching of the coreum txs is interrupted")
+	}
 	if fetchError != nil {
-		return nil, fetchError
+		return nil, errors.Errorf("can't fetch coreum txs, failed pages: %v, err: %s", failedPages, fetchError)
 	}
 
-	if len(txs) != int(res0.TotalCount) {
-		return nil, errors.New("fetched tx count doesn't match total tx count returned by pagination")
+	txs := make([]*sdk.TxResponse, 0, res0.TotalCount)
+	for _, pageTxs := range pages {
+		txs = append(txs, pageTxs...)
 	}
 
-	for _, txAny := range txs {
-		tx, ok := txAny.Tx.GetCachedValue().(*sdktx.Tx)
-		if !ok {
-			return nil, errors.New("tx does not implement sdk.Tx interface")
-		}
-
-		messages := tx.GetMsgs()
-		if len(messages) != 1 {
-			return nil, errors.New("there should be only 1 message in the transaction")
-		}
-
-		msg := messages[0]
-		bankSend, ok := msg.(*banktypes.MsgSend)
-		if !ok {
-			return nil, errors.New("message is not bank MsgSend type")
-		}
-		timestamp, err := time.Parse(time.RFC3339, txAny.Timestamp)
-		if timestamp.After(beforeDateTime) {
-			continue
-		}
-		if timestamp.Before(afterDateTime) {
-			continue
-		}
-
-		if err != nil {
-			return nil, errors.Errorf("can't parse time: %s with format %s", txAny.Timestamp, time.RFC3339)
-		}
-		bankSendMessages = append(bankSendMessages, bankSendWithMemo{
-			Hash:      txAny.TxHash,
-			MsgSend:   bankSend,
-			Memo:      tx.Body.Memo,
-			Timestamp: timestamp,
-		})
+	if len(txs) != int(res0.TotalCount) {
+		return nil, errors.New("fetched tx count doesn't match total tx count returned by pagination")
 	}
 
-	log.Info(fmt.Sprintf("Found coreum txs total: %d", len(bankSendMessages)))
-
-	return bankSendMessages, nil
+	return txs, nil
 }
 
 func convertBankTxsToAuditTxs(coreumTxs []bankSendWithMemo, denom string) []AuditTx {
 	txs := make([]AuditTx, 0, len(coreumTxs))
 	for _, coreumTx := range coreumTxs {
+		// the spent coins include the sent amount, the rest is the gas fee
+		var fee *big.Int
+		if spentFee := coreumTx.SpentCoins.AmountOf(denom).Sub(coreumTx.Amount.AmountOf(denom)); spentFee.IsPositive() {
+			fee = spentFee.BigInt()
+		}
 		txs = append(txs, AuditTx{
 			Hash:          coreumTx.Hash,
 			FromAddress:   coreumTx.FromAddress,
@@ -206,8 +383,73 @@ func convertBankTxsToAuditTxs(coreumTxs []bankSendWithMemo, denom string) []Audi
 			Amount:        coreumTx.Amount.AmountOf(denom).BigInt(),
 			Memo:          coreumTx.Memo,
 			Timestamp:     coreumTx.Timestamp,
+			Height:        coreumTx.Height,
+			Fee:           fee,
 		})
 	}
 
 	return txs
 }
+
+// searchCoreumTxs queries the txs as the authtx.QueryTxsByEvents, but doesn't decode them, so the txs with the messages
+// of the modules unknown to the client are returned too, without the decoded tx but with the events.
+func searchCoreumTxs(clientCtx client.Context, events []string, page, limit int, orderBy string) (*sdk.SearchTxsResult, error) {
+	node, err := clientCtx.GetNode()
+	if err != nil {
+		return nil, err
+	}
+	resTxs, err := node.TxSearch(context.Background(), strings.Join(events, " AND "), false, &page, &limit, orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	blockTimes := make(map[int64]string)
+	txs := make([]*sdk.TxResponse, 0, len(resTxs.Txs))
+	for _, resTx := range resTxs.Txs {
+		blockTime, ok := blockTimes[resTx.Height]
+		if !ok {
+			resBlock, err := node.Block(context.Background(), &resTx.Height)
+			if err != nil {
+				return nil, err
+			}
+			blockTime = resBlock.Block.Time.Format(time.RFC3339)
+			blockTimes[resTx.Height] = blockTime
+		}
+		txs = append(txs, sdk.NewResponseResultTx(resTx, nil, blockTime))
+	}
+
+	return sdk.NewSearchTxsResult(uint64(resTxs.TotalCount), uint64(len(txs)), uint64(page), uint64(limit), txs), nil
+}
+
+// getEventCoins returns the sum of the coins of the events of the type with the address, e.g. the coins spent by the
+// spender in the coin_spent events.
+func getEventCoins(events []abci.Event, eventType, addressKey, address string) (sdk.Coins, error) {
+	eventCoins := sdk.NewCoins()
+	for _, event := range events {
+		if event.Type != eventType {
+			continue
+		}
+		var (
+			eventAddress string
+			eventAmount  string
+		)
+		for _, attribute := range event.Attributes {
+			switch string(attribute.Key) {
+			case addressKey:
+				eventAddress = string(attribute.Value)
+			case sdk.AttributeKeyAmount:
+				eventAmount = string(attribute.Value)
+			}
+		}
+		if eventAddress != address {
+			continue
+		}
+		coins, err := sdk.ParseCoinsNormalized(eventAmount)
+		if err != nil {
+			return nil, errors.Errorf("can't parse %s amount %q, err: %s", eventType, eventAmount, err)
+		}
+		eventCoins = eventCoins.Add(coins...)
+	}
+
+	return eventCoins, nil
+}
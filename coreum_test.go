@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+)
+
+func TestGetCoreumAuditTransactions(t *testing.T) {
+	server := newFakeTendermintServer(t)
+	query := fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumAccount)
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	// more than a single page
+	for i := 0; i < 250; i++ {
+		server.AddTxs(query, fakeCoreumTx{
+			Height: int64(100 + i),
+			Time:   txTime.Add(time.Duration(i) * time.Minute),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(byte(i)),
+			Amount: int64(1_000000 + i),
+			Memo:   fmt.Sprintf("%s:0xHASH%d:0", defaultCoreumMemoSourceChainID, i),
+		})
+	}
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	clientCtx := createClientContext(Config{CoreumRPCURLs: []string{server.URL}}, NewHTTPClient(HTTPConfig{}))
+	auditTxs, err := GetCoreumAuditTransactions(
+		ctx, clientCtx, query, "ucore", txTime.Add(200*time.Minute), txTime.Add(10*time.Minute),
+	)
+	require.NoError(t, err)
+	// the txs out of the time range are skipped
+	require.Len(t, auditTxs, 191)
+
+	sort.Slice(auditTxs, func(i, j int) bool {
+		return auditTxs[i].Timestamp.Before(auditTxs[j].Timestamp)
+	})
+	require.NotEmpty(t, auditTxs[0].Hash)
+	require.Equal(t, AuditTx{
+		Hash:          auditTxs[0].Hash,
+		FromAddress:   defaultCoreumAccount,
+		ToAddress:     fakeCoreumAddress(10),
+		TargetAddress: fakeCoreumAddress(10),
+		Amount:        big.NewInt(1_000010),
+		Memo:          fmt.Sprintf("%s:0xHASH10:0", defaultCoreumMemoSourceChainID),
+		Timestamp:     txTime.Add(10 * time.Minute),
+		Height:        110,
+	}, auditTxs[0])
+}
+
+func TestGetCoreumAuditTransactionsAtHeight(t *testing.T) {
+	server := newFakeTendermintServer(t)
+	query := fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumAccount)
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		server.AddTxs(query, fakeCoreumTx{
+			Height: int64(100 + i),
+			Time:   txTime.Add(time.Duration(i) * time.Minute),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(byte(i)),
+			Amount: 1_000000,
+		})
+	}
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	clientCtx := createClientContext(
+		Config{CoreumRPCURLs: []string{server.URL}, CoreumHeight: 101}, NewHTTPClient(HTTPConfig{}),
+	)
+	auditTxs, err := GetCoreumAuditTransactions(ctx, clientCtx, query, "ucore", txTime.Add(time.Hour), txTime)
+	require.NoError(t, err)
+	// the tx of the later block is skipped
+	require.Len(t, auditTxs, 2)
+	require.Equal(t, fakeCoreumAddress(1), auditTxs[0].ToAddress)
+	require.Equal(t, fakeCoreumAddress(0), auditTxs[1].ToAddress)
+
+	// the sdk queries are sent with the context, so the cancelled fetch doesn't query the node
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = authtx.QueryTxsByEvents(withRPCContext(cancelledCtx, clientCtx), []string{query}, 1, 100, "")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGetCoreumAuditTransactionsWithFee(t *testing.T) {
+	server := newFakeTendermintServer(t)
+	query := fmt.Sprintf("coin_spent.spender='%s'", defaultCoreumAccount)
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	server.AddTxs(query,
+		fakeCoreumTx{
+			Height: 100,
+			Time:   txTime,
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(1),
+			Amount: 1_000000,
+			Fee:    12500,
+		},
+		// the tx without the fee
+		fakeCoreumTx{
+			Height: 101,
+			Time:   txTime.Add(time.Minute),
+			From:   defaultCoreumAccount,
+			To:     fakeCoreumAddress(2),
+			Amount: 2_000000,
+		},
+	)
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	clientCtx := createClientContext(Config{CoreumRPCURLs: []string{server.URL}}, NewHTTPClient(HTTPConfig{}))
+	auditTxs, err := GetCoreumAuditTransactions(ctx, clientCtx, query, "ucore", txTime.Add(time.Hour), txTime)
+	require.NoError(t, err)
+	require.Len(t, auditTxs, 2)
+	require.Nil(t, auditTxs[0].Fee)
+	require.Equal(t, big.NewInt(1_000000), auditTxs[1].Amount)
+	require.Equal(t, big.NewInt(12500), auditTxs[1].Fee)
+}
+
+func TestGetCoreumAccountBalanceAtHeight(t *testing.T) {
+	server := newFakeTendermintServer(t)
+	server.SetBalance(defaultCoreumAccount, sdk.NewInt64Coin("ucore", 123_456789))
+	server.SetBalanceAt(100, defaultCoreumAccount, sdk.NewInt64Coin("ucore", 100_000000))
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	clientCtx := createClientContext(
+		Config{CoreumRPCURLs: []string{server.URL}, CoreumHeight: 100}, NewHTTPClient(HTTPConfig{}),
+	)
+	balance, err := GetCoreumAccountBalance(ctx, clientCtx, defaultCoreumAccount, "ucore")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100_000000), balance)
+}
+
+func TestGetCoreumAccountBalance(t *testing.T) {
+	server := newFakeTendermintServer(t)
+	server.SetBalance(defaultCoreumAccount, sdk.NewInt64Coin("ucore", 123_456789))
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	clientCtx := createClientContext(Config{CoreumRPCURLs: []string{server.URL}}, NewHTTPClient(HTTPConfig{}))
+	balance, err := GetCoreumAccountBalance(ctx, clientCtx, defaultCoreumAccount, "ucore")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(123_456789), balance)
+}
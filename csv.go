@@ -6,12 +6,90 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-// WriteAuditTxsToCSV create and writes AuditTx CSV file.
-func WriteAuditTxsToCSV(txs []AuditTx, path string) error {
+var auditTxsCSVHeader = []string{
+	"Hash",
+	"FromAddress",
+	"ToAddress",
+	"Amount",
+	"Memo",
+	"Timestamp",
+	"Height",
+	"Result",
+	"Validated",
+	"TargetAddress",
+}
+
+// auditTxsCSVRequiredColumns is the number of the audit tx columns required on read, the Height, Result, Validated and
+// TargetAddress columns are optional, since the older exports don't have them.
+const auditTxsCSVRequiredColumns = 6
+
+var txDiscrepancyCSVHeader = []string{
+	"XrplHash",
+	"XrplAmount",
+	"XrplTargetAddress",
+	"XrplMemo",
+	"XrplTimestamp",
+	"CoreumHash",
+	"CoreumAmount",
+	"ExpectedAmount",
+	"AmountDelta",
+	"ImpliedFee",
+	"CoreumTargetAddress",
+	"CoreumMemo",
+	"CoreumTimestamp",
+	"BridgingTime",
+	"Discrepancy",
+}
+
+var txDiscrepancyDiffCSVHeader = []string{
+	"Status",
+	"XrplHash",
+	"CoreumHash",
+	"OldDiscrepancy",
+	"NewDiscrepancy",
+	"Changes",
+}
+
+var supplyHistoryCSVHeader = []string{
+	"Date",
+	"XrplIssuedAmount",
+	"XrplBurntAmount",
+	"XrplSupply",
+	"CoreumIncomeAmount",
+	"CoreumOutcomeAmount",
+	"CoreumGasFeesAmount",
+	"CoreumLockedAmount",
+	"Difference",
+}
+
+var balanceHistoryCSVHeader = []string{
+	"Account",
+	"Height",
+	"Timestamp",
+	"Hash",
+	"Amount",
+	"Balance",
+	"QueriedBalance",
+	"Gap",
+}
+
+var rescanResultsCSVHeader = []string{
+	"Hash",
+	"Status",
+	"LastMessage",
+	"Attempts",
+	"Timestamp",
+}
+
+// WriteAuditTxsToCSV create and writes AuditTx CSV file, the address label columns are added if there are labels.
+func WriteAuditTxsToCSV(txs []AuditTx, labels AddressLabels, path string) error {
 	file, err := createFile(path)
 	if err != nil {
 		return err
@@ -24,26 +102,23 @@ func WriteAuditTxsToCSV(txs []AuditTx, path string) error {
 	}()
 
 	// write header
-	if err := writer.Write([]string{
-		"Hash",
-		"FromAddress",
-		"ToAddress",
-		"Amount",
-		"Memo",
-		"Timestamp",
-	}); err != nil {
+	if err := writer.Write(appendLabelColumns(auditTxsCSVHeader, labels, "FromLabel", "ToLabel")); err != nil {
 		return err
 	}
 
 	for _, tx := range txs {
-		err := writer.Write([]string{
+		err := writer.Write(appendLabelColumns([]string{
 			tx.Hash,
 			tx.FromAddress,
 			tx.ToAddress,
 			convertFloatToSixDecimalsFloatText(tx.Amount),
 			tx.Memo,
 			tx.Timestamp.String(),
-		})
+			strconv.FormatInt(tx.Height, 10),
+			tx.Result,
+			strconv.FormatBool(!tx.Unvalidated),
+			tx.TargetAddress,
+		}, labels, labels[tx.FromAddress], labels[tx.ToAddress]))
 		if err != nil {
 			return err
 		}
@@ -51,8 +126,65 @@ func WriteAuditTxsToCSV(txs []AuditTx, path string) error {
 	return nil
 }
 
-// WriteTxsDiscrepancyToCSV create and writes TxDiscrepancy CSV file.
-func WriteTxsDiscrepancyToCSV(discrepancies []TxDiscrepancy, path string) error {
+// ReadAuditTxsFromCSV reads AuditTx CSV file. The file without the Height, Result and Validated columns is read with
+// the zero height, empty result and validated txs, and the file without the TargetAddress with the empty one.
+func ReadAuditTxsFromCSV(path string) ([]AuditTx, error) {
+	header, records, err := readCSVWithHeader(path, auditTxsCSVHeader[:auditTxsCSVRequiredColumns])
+	if err != nil {
+		return nil, err
+	}
+	// the optional columns are found by name, since the exports of the different versions have different columns
+	optionalColumns := make(map[string]int)
+	for i := auditTxsCSVRequiredColumns; i < len(header); i++ {
+		optionalColumns[header[i]] = i
+	}
+	heightColumn, hasHeight := optionalColumns["Height"]
+	resultColumn, hasResult := optionalColumns["Result"]
+	validatedColumn, hasValidated := optionalColumns["Validated"]
+	hasResultColumns := hasHeight && hasResult && hasValidated
+	targetAddressColumn, hasTargetAddress := optionalColumns["TargetAddress"]
+
+	txs := make([]AuditTx, 0, len(records))
+	for _, record := range records {
+		amount, err := parseSixDecimalsFloatText(record[3])
+		if err != nil {
+			return nil, errors.Errorf("can't parse amount %q, path: %s, err: %s", record[3], path, err)
+		}
+		timestamp, err := parseCSVTime(record[5])
+		if err != nil {
+			return nil, errors.Errorf("can't parse timestamp %q, path: %s, err: %s", record[5], path, err)
+		}
+		tx := AuditTx{
+			Hash:        record[0],
+			FromAddress: record[1],
+			ToAddress:   record[2],
+			Amount:      amount,
+			Memo:        record[4],
+			Timestamp:   timestamp,
+		}
+		if hasResultColumns {
+			if tx.Height, err = strconv.ParseInt(record[heightColumn], 10, 64); err != nil {
+				return nil, errors.Errorf("can't parse height %q, path: %s, err: %s", record[heightColumn], path, err)
+			}
+			validated, err := strconv.ParseBool(record[validatedColumn])
+			if err != nil {
+				return nil, errors.Errorf("can't parse validated %q, path: %s, err: %s", record[validatedColumn], path, err)
+			}
+			tx.Result = record[resultColumn]
+			tx.Unvalidated = !validated
+		}
+		if hasTargetAddress {
+			tx.TargetAddress = record[targetAddressColumn]
+		}
+		txs = append(txs, tx)
+	}
+
+	return txs, nil
+}
+
+// WriteTxsDiscrepancyToCSV create and writes TxDiscrepancy CSV file, the target address label columns are added if
+// there are labels.
+func WriteTxsDiscrepancyToCSV(discrepancies []TxDiscrepancy, labels AddressLabels, path string) error {
 	file, err := createFile(path)
 	if err != nil {
 		return err
@@ -65,26 +197,12 @@ func WriteTxsDiscrepancyToCSV(discrepancies []TxDiscrepancy, path string) error
 	}()
 
 	// write header
-	if err := writer.Write([]string{
-		"XrplHash",
-		"XrplAmount",
-		"XrplTargetAddress",
-		"XrplMemo",
-		"XrplTimestamp",
-		"CoreumHash",
-		"CoreumAmount",
-		"ExpectedAmount",
-		"CoreumTargetAddress",
-		"CoreumMemo",
-		"CoreumTimestamp",
-		"BridgingTime",
-		"Discrepancy",
-	}); err != nil {
+	if err := writer.Write(appendLabelColumns(txDiscrepancyCSVHeader, labels, "XrplTargetLabel", "CoreumTargetLabel")); err != nil {
 		return err
 	}
 
 	for _, discrepancy := range discrepancies {
-		err := writer.Write([]string{
+		err := writer.Write(appendLabelColumns([]string{
 			discrepancy.XrplTx.Hash,
 			convertFloatToSixDecimalsFloatText(discrepancy.XrplTx.Amount),
 			discrepancy.XrplTx.TargetAddress,
@@ -93,11 +211,103 @@ func WriteTxsDiscrepancyToCSV(discrepancies []TxDiscrepancy, path string) error
 			discrepancy.CoreumTx.Hash,
 			convertFloatToSixDecimalsFloatText(discrepancy.CoreumTx.Amount),
 			convertFloatToSixDecimalsFloatText(discrepancy.ExpectedAmount),
+			convertFloatToSixDecimalsFloatText(discrepancy.AmountDelta),
+			convertFloatToSixDecimalsFloatText(discrepancy.ImpliedFee),
 			discrepancy.CoreumTx.TargetAddress,
 			discrepancy.CoreumTx.Memo,
 			discrepancy.CoreumTx.Timestamp.String(),
 			discrepancy.BridgingTime.String(),
 			discrepancy.Discrepancy,
+		}, labels, labels[discrepancy.XrplTx.TargetAddress], labels[discrepancy.CoreumTx.TargetAddress]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadTxsDiscrepancyFromCSV reads TxDiscrepancy CSV file.
+func ReadTxsDiscrepancyFromCSV(path string) ([]TxDiscrepancy, error) {
+	records, err := readCSV(path, txDiscrepancyCSVHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancies := make([]TxDiscrepancy, 0, len(records))
+	for _, record := range records {
+		amounts := make([]*big.Int, 0, 5)
+		for _, column := range []int{1, 6, 7, 8, 9} {
+			amount, err := parseSixDecimalsFloatText(record[column])
+			if err != nil {
+				return nil, errors.Errorf("can't parse %s %q, path: %s, err: %s", txDiscrepancyCSVHeader[column], record[column], path, err)
+			}
+			amounts = append(amounts, amount)
+		}
+		timestamps := make([]time.Time, 0, 2)
+		for _, column := range []int{4, 12} {
+			timestamp, err := parseCSVTime(record[column])
+			if err != nil {
+				return nil, errors.Errorf("can't parse %s %q, path: %s, err: %s", txDiscrepancyCSVHeader[column], record[column], path, err)
+			}
+			timestamps = append(timestamps, timestamp)
+		}
+		bridgingTime, err := time.ParseDuration(record[13])
+		if err != nil {
+			return nil, errors.Errorf("can't parse BridgingTime %q, path: %s, err: %s", record[13], path, err)
+		}
+
+		discrepancies = append(discrepancies, TxDiscrepancy{
+			XrplTx: AuditTx{
+				Hash:          record[0],
+				Amount:        amounts[0],
+				TargetAddress: record[2],
+				Memo:          record[3],
+				Timestamp:     timestamps[0],
+			},
+			CoreumTx: AuditTx{
+				Hash:          record[5],
+				Amount:        amounts[1],
+				TargetAddress: record[10],
+				Memo:          record[11],
+				Timestamp:     timestamps[1],
+			},
+			ExpectedAmount: amounts[2],
+			AmountDelta:    amounts[3],
+			ImpliedFee:     amounts[4],
+			BridgingTime:   bridgingTime,
+			Discrepancy:    record[14],
+		})
+	}
+
+	return discrepancies, nil
+}
+
+// WriteTxDiscrepancyDiffsToCSV create and writes TxDiscrepancyDiff CSV file.
+func WriteTxDiscrepancyDiffsToCSV(diffs []TxDiscrepancyDiff, path string) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	defer func() {
+		writer.Flush()
+		file.Close()
+	}()
+
+	// write header
+	if err := writer.Write(txDiscrepancyDiffCSVHeader); err != nil {
+		return err
+	}
+
+	for _, diff := range diffs {
+		err := writer.Write([]string{
+			diff.Status,
+			diff.XrplHash,
+			diff.CoreumHash,
+			diff.OldDiscrepancy,
+			diff.NewDiscrepancy,
+			strings.Join(diff.Changes, "; "),
 		})
 		if err != nil {
 			return err
@@ -106,6 +316,188 @@ func WriteTxsDiscrepancyToCSV(discrepancies []TxDiscrepancy, path string) error
 	return nil
 }
 
+// WriteSupplyHistoryToCSV create and writes SupplyHistoryDay CSV file.
+func WriteSupplyHistoryToCSV(history []SupplyHistoryDay, path string) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	defer func() {
+		writer.Flush()
+		file.Close()
+	}()
+
+	// write header
+	if err := writer.Write(supplyHistoryCSVHeader); err != nil {
+		return err
+	}
+
+	for _, day := range history {
+		err := writer.Write([]string{
+			day.Date.Format(time.DateOnly),
+			convertFloatToSixDecimalsFloatText(day.XrplIssuedAmount),
+			convertFloatToSixDecimalsFloatText(day.XrplBurntAmount),
+			convertFloatToSixDecimalsFloatText(day.XrplSupply),
+			convertFloatToSixDecimalsFloatText(day.CoreumIncomeAmount),
+			convertFloatToSixDecimalsFloatText(day.CoreumOutcomeAmount),
+			convertFloatToSixDecimalsFloatText(day.CoreumGasFeesAmount),
+			convertFloatToSixDecimalsFloatText(day.CoreumLockedAmount),
+			convertFloatToSixDecimalsFloatText(day.Difference),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBalanceHistoryToCSV create and writes BalanceHistoryEntry CSV file, the balance of the entries which aren't
+// sampled is empty. The account label column is added if there are labels.
+func WriteBalanceHistoryToCSV(history []BalanceHistoryEntry, labels AddressLabels, path string) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	defer func() {
+		writer.Flush()
+		file.Close()
+	}()
+
+	// write header
+	if err := writer.Write(appendLabelColumns(balanceHistoryCSVHeader, labels, "AccountLabel")); err != nil {
+		return err
+	}
+
+	for _, entry := range history {
+		err := writer.Write(appendLabelColumns([]string{
+			entry.Account,
+			strconv.FormatInt(entry.Height, 10),
+			entry.Timestamp.String(),
+			entry.Hash,
+			convertFloatToSixDecimalsFloatText(entry.Amount),
+			convertFloatToSixDecimalsFloatText(entry.Balance),
+			convertFloatToSixDecimalsFloatText(entry.QueriedBalance),
+			convertFloatToSixDecimalsFloatText(entry.Gap),
+		}, labels, labels[entry.Account]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRescanResultsToCSV create and writes RescanResult CSV file.
+func WriteRescanResultsToCSV(results []RescanResult, path string) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	defer func() {
+		writer.Flush()
+		file.Close()
+	}()
+
+	// write header
+	if err := writer.Write(rescanResultsCSVHeader); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		err := writer.Write([]string{
+			result.Hash,
+			result.Status,
+			result.LastMessage,
+			strconv.Itoa(result.Attempts),
+			result.Timestamp.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRescanResultsFromCSV reads RescanResult CSV file.
+func ReadRescanResultsFromCSV(path string) ([]RescanResult, error) {
+	records, err := readCSV(path, rescanResultsCSVHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]RescanResult, 0, len(records))
+	for _, record := range records {
+		attempts, err := strconv.Atoi(record[3])
+		if err != nil {
+			return nil, errors.Errorf("can't parse attempts %q, path: %s, err: %s", record[3], path, err)
+		}
+		timestamp, err := parseCSVTime(record[4])
+		if err != nil {
+			return nil, errors.Errorf("can't parse timestamp %q, path: %s, err: %s", record[4], path, err)
+		}
+		results = append(results, RescanResult{
+			Hash:        record[0],
+			Status:      record[1],
+			LastMessage: record[2],
+			Attempts:    attempts,
+			Timestamp:   timestamp,
+		})
+	}
+
+	return results, nil
+}
+
+// readCSV reads all the records of the CSV file and validates its header, the trailing label columns are allowed.
+func readCSV(path string, header []string) ([][]string, error) {
+	_, records, err := readCSVWithHeader(path, header)
+	return records, err
+}
+
+// readCSVWithHeader reads the CSV file as the readCSV and returns its header as well, so the trailing optional columns
+// can be detected.
+func readCSVWithHeader(path string, header []string) ([]string, [][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, errors.Errorf("can't open file, path: %s, err: %s", path, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, nil, errors.Errorf("can't read csv file, path: %s, err: %s", path, err)
+	}
+	if len(records) == 0 || len(records[0]) < len(header) ||
+		strings.Join(records[0][:len(header)], ",") != strings.Join(header, ",") {
+		return nil, nil, errors.Errorf("unexpected csv header, path: %s, expected: %s", path, strings.Join(header, ","))
+	}
+
+	return records[0], records[1:], nil
+}
+
+// appendLabelColumns returns the record with the label columns appended, the record is returned as is if there are
+// no labels.
+func appendLabelColumns(record []string, labels AddressLabels, labelColumns ...string) []string {
+	if len(labels) == 0 {
+		return record
+	}
+
+	return append(append(make([]string, 0, len(record)+len(labelColumns)), record...), labelColumns...)
+}
+
+// parseCSVTime parses the time written by the time.Time String method.
+func parseCSVTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", value)
+}
+
 func createFile(path string) (*os.File, error) {
 	if err := os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
 		return nil, errors.Errorf("can't create dir, path:%s, err: %s", path, err)
@@ -124,3 +516,21 @@ func convertFloatToSixDecimalsFloatText(amount *big.Int) string {
 	}
 	return big.NewFloat(0).Quo(big.NewFloat(0).SetInt(amount), oneMillionFloat).Text('f', 6)
 }
+
+// parseSixDecimalsFloatText parses the amount written by the convertFloatToSixDecimalsFloatText exactly,
+// the empty value is parsed as nil.
+func parseSixDecimalsFloatText(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, ok := big.NewRat(0, 1).SetString(value)
+	if !ok {
+		return nil, errors.Errorf("invalid decimal %q", value)
+	}
+	amount.Mul(amount, big.NewRat(1_000_000, 1))
+	if !amount.IsInt() {
+		return nil, errors.Errorf("decimal %q has more than 6 decimals", value)
+	}
+
+	return big.NewInt(0).Set(amount.Num()), nil
+}
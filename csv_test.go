@@ -0,0 +1,142 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditTxsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "txs.csv")
+	txs := []AuditTx{
+		{
+			Hash:          "hash1",
+			FromAddress:   "from1",
+			ToAddress:     "to1",
+			Amount:        big.NewInt(123_456789),
+			Memo:          "memo, with comma",
+			Timestamp:     time.Date(2023, time.Month(7), 1, 12, 30, 0, 0, time.UTC),
+			Height:        80_000_000,
+			Result:        xrplTxResultSuccess,
+			Unvalidated:   true,
+			TargetAddress: "target1",
+		},
+		{
+			Hash:        "hash2",
+			FromAddress: "from2",
+			ToAddress:   "to2",
+			Amount:      big.NewInt(-1),
+			Timestamp:   time.Date(2023, time.Month(7), 1, 12, 31, 0, 0, time.UTC),
+		},
+	}
+
+	require.NoError(t, WriteAuditTxsToCSV(txs, nil, path))
+	got, err := ReadAuditTxsFromCSV(path)
+	require.NoError(t, err)
+	require.Equal(t, txs, got)
+
+	// the label columns are appended and ignored on read
+	require.NoError(t, WriteAuditTxsToCSV(txs, AddressLabels{"to1": "Distribution address"}, path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "Hash,FromAddress,ToAddress,Amount,Memo,Timestamp,Height,Result,Validated,TargetAddress,FromLabel,ToLabel\n")
+	require.Contains(t, string(data), ",,Distribution address\n")
+	got, err = ReadAuditTxsFromCSV(path)
+	require.NoError(t, err)
+	require.Equal(t, txs, got)
+
+	// the older export without the height, result and validated columns
+	require.NoError(t, os.WriteFile(path, []byte(`Hash,FromAddress,ToAddress,Amount,Memo,Timestamp,FromLabel,ToLabel
+hash1,from1,to1,123.456789,memo,2023-07-01 12:30:00 +0000 UTC,,Distribution address
+`), 0o600))
+	got, err = ReadAuditTxsFromCSV(path)
+	require.NoError(t, err)
+	require.Equal(t, []AuditTx{
+		{
+			Hash:        "hash1",
+			FromAddress: "from1",
+			ToAddress:   "to1",
+			Amount:      big.NewInt(123_456789),
+			Memo:        "memo",
+			Timestamp:   time.Date(2023, time.Month(7), 1, 12, 30, 0, 0, time.UTC),
+		},
+	}, got)
+
+	// the export without the target address
+	require.NoError(t, os.WriteFile(path, []byte(`Hash,FromAddress,ToAddress,Amount,Memo,Timestamp,Height,Result,Validated
+hash1,from1,to1,123.456789,memo,2023-07-01 12:30:00 +0000 UTC,80000000,tesSUCCESS,false
+`), 0o600))
+	got, err = ReadAuditTxsFromCSV(path)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, int64(80_000_000), got[0].Height)
+	require.True(t, got[0].Unvalidated)
+	require.Empty(t, got[0].TargetAddress)
+
+	require.NoError(t, os.WriteFile(path, []byte("Hash,FromAddress,ToAddress,Amount\n"), 0o600))
+	_, err = ReadAuditTxsFromCSV(path)
+	require.ErrorContains(t, err, "unexpected csv header")
+}
+
+func Test_parseSixDecimalsFloatText(t *testing.T) {
+	amount, err := parseSixDecimalsFloatText("1.000001")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1_000001), amount)
+
+	amount, err = parseSixDecimalsFloatText("")
+	require.NoError(t, err)
+	require.Nil(t, amount)
+
+	_, err = parseSixDecimalsFloatText("1.0000001")
+	require.ErrorContains(t, err, "more than 6 decimals")
+
+	_, err = parseSixDecimalsFloatText("one")
+	require.ErrorContains(t, err, "invalid decimal")
+}
+
+func TestTxsDiscrepancyCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discrepancies.csv")
+	discrepancies := []TxDiscrepancy{
+		{
+			XrplTx: AuditTx{
+				Hash:          "xrplHash1",
+				TargetAddress: "core1",
+				Amount:        big.NewInt(10_000000),
+				Memo:          "core1:1111",
+				Timestamp:     time.Date(2023, time.Month(7), 1, 12, 30, 0, 0, time.UTC),
+			},
+			CoreumTx: AuditTx{
+				Hash:          "coreHash1",
+				TargetAddress: "core1",
+				Amount:        big.NewInt(9_000000),
+				Memo:          "1111:xrplHash1:0",
+				Timestamp:     time.Date(2023, time.Month(7), 1, 12, 31, 0, 0, time.UTC),
+			},
+			ExpectedAmount: big.NewInt(9_990000),
+			AmountDelta:    big.NewInt(-990000),
+			ImpliedFee:     big.NewInt(1_000000),
+			BridgingTime:   time.Minute,
+			Discrepancy:    DiscrepancyDifferentAmountOnXrplAndCoreum,
+		},
+		{
+			CoreumTx: AuditTx{
+				Hash:      "coreHash2",
+				Amount:    big.NewInt(1_000000),
+				Memo:      "invalid memo",
+				Timestamp: time.Date(2023, time.Month(7), 1, 12, 32, 0, 0, time.UTC),
+			},
+			Discrepancy: DiscrepancyInvalidMemoOnCoreum,
+		},
+	}
+
+	require.NoError(t, WriteTxsDiscrepancyToCSV(discrepancies, AddressLabels{"core1": "Coreum Foundation"}, path))
+	got, err := ReadTxsDiscrepancyFromCSV(path)
+	require.NoError(t, err)
+	// the zero time is read in UTC
+	discrepancies[1].XrplTx.Timestamp = time.Time{}.UTC()
+	require.Equal(t, discrepancies, got)
+}
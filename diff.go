@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/samber/lo"
+)
+
+const (
+	DiffStatusResolved = "resolved"
+	DiffStatusChanged  = "changed"
+	DiffStatusNew      = "new"
+)
+
+var diffStatusesOrder = map[string]int{
+	DiffStatusResolved: 0,
+	DiffStatusChanged:  1,
+	DiffStatusNew:      2,
+}
+
+// TxDiscrepancyDiff is the difference of the discrepancy row between two audit runs.
+type TxDiscrepancyDiff struct {
+	Status         string
+	XrplHash       string
+	CoreumHash     string
+	OldDiscrepancy string
+	NewDiscrepancy string
+	Changes        []string // the changed fields in the "Field: old -> new" format
+}
+
+// DiffTxDiscrepancies compares the discrepancies of two audit runs. The rows are matched by the xrpl tx hash, or by
+// the coreum tx hash if there is no xrpl tx. The rows which aren't discrepancies (matched or info rows) are reported
+// only if they became discrepancies, or the discrepancies became them.
+func DiffTxDiscrepancies(oldDiscrepancies, newDiscrepancies []TxDiscrepancy) []TxDiscrepancyDiff {
+	newByXrplHash := make(map[string]int)
+	newByCoreumHash := make(map[string]int)
+	for i, discrepancy := range newDiscrepancies {
+		if discrepancy.XrplTx.Hash != "" {
+			newByXrplHash[discrepancy.XrplTx.Hash] = i
+		}
+		if discrepancy.CoreumTx.Hash != "" {
+			newByCoreumHash[discrepancy.CoreumTx.Hash] = i
+		}
+	}
+
+	diffs := make([]TxDiscrepancyDiff, 0)
+	matchedNew := make(map[int]struct{})
+	for _, oldDiscrepancy := range oldDiscrepancies {
+		newIndex, ok := -1, false
+		if oldDiscrepancy.XrplTx.Hash != "" {
+			newIndex, ok = newByXrplHash[oldDiscrepancy.XrplTx.Hash]
+		}
+		// the orphan coreum tx might be matched with the xrpl tx in the new run
+		if !ok && oldDiscrepancy.CoreumTx.Hash != "" {
+			newIndex, ok = newByCoreumHash[oldDiscrepancy.CoreumTx.Hash]
+		}
+		if ok {
+			if _, used := matchedNew[newIndex]; used {
+				ok = false
+			}
+		}
+
+		if !ok {
+			if isDiscrepancy(oldDiscrepancy.Discrepancy) {
+				diffs = append(diffs, newTxDiscrepancyDiff(DiffStatusResolved, oldDiscrepancy, TxDiscrepancy{}, nil))
+			}
+			continue
+		}
+
+		matchedNew[newIndex] = struct{}{}
+		newDiscrepancy := newDiscrepancies[newIndex]
+		changes := compareTxDiscrepancies(oldDiscrepancy, newDiscrepancy)
+		switch {
+		case isDiscrepancy(oldDiscrepancy.Discrepancy) && !isDiscrepancy(newDiscrepancy.Discrepancy):
+			diffs = append(diffs, newTxDiscrepancyDiff(DiffStatusResolved, oldDiscrepancy, newDiscrepancy, changes))
+		case !isDiscrepancy(oldDiscrepancy.Discrepancy) && isDiscrepancy(newDiscrepancy.Discrepancy):
+			diffs = append(diffs, newTxDiscrepancyDiff(DiffStatusNew, oldDiscrepancy, newDiscrepancy, changes))
+		case isDiscrepancy(newDiscrepancy.Discrepancy) && len(changes) > 0:
+			diffs = append(diffs, newTxDiscrepancyDiff(DiffStatusChanged, oldDiscrepancy, newDiscrepancy, changes))
+		}
+	}
+
+	for i, newDiscrepancy := range newDiscrepancies {
+		if _, ok := matchedNew[i]; ok {
+			continue
+		}
+		if isDiscrepancy(newDiscrepancy.Discrepancy) {
+			diffs = append(diffs, newTxDiscrepancyDiff(DiffStatusNew, TxDiscrepancy{}, newDiscrepancy, nil))
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffStatusesOrder[diffs[i].Status] < diffStatusesOrder[diffs[j].Status]
+	})
+
+	return diffs
+}
+
+// CountTxDiscrepancyDiffs returns the number of the diffs per status.
+func CountTxDiscrepancyDiffs(diffs []TxDiscrepancyDiff) map[string]int {
+	counts := make(map[string]int)
+	for _, diff := range diffs {
+		counts[diff.Status]++
+	}
+
+	return counts
+}
+
+func newTxDiscrepancyDiff(status string, oldDiscrepancy, newDiscrepancy TxDiscrepancy, changes []string) TxDiscrepancyDiff {
+	diff := TxDiscrepancyDiff{
+		Status:         status,
+		XrplHash:       newDiscrepancy.XrplTx.Hash,
+		CoreumHash:     newDiscrepancy.CoreumTx.Hash,
+		OldDiscrepancy: oldDiscrepancy.Discrepancy,
+		NewDiscrepancy: newDiscrepancy.Discrepancy,
+		Changes:        changes,
+	}
+	if diff.XrplHash == "" {
+		diff.XrplHash = oldDiscrepancy.XrplTx.Hash
+	}
+	if diff.CoreumHash == "" {
+		diff.CoreumHash = oldDiscrepancy.CoreumTx.Hash
+	}
+
+	return diff
+}
+
+// compareTxDiscrepancies returns the changed category, counterpart and amounts of the row.
+func compareTxDiscrepancies(oldDiscrepancy, newDiscrepancy TxDiscrepancy) []string {
+	changes := make([]string, 0)
+	addChange := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", field, oldValue, newValue))
+		}
+	}
+	addAmountChange := func(field string, oldValue, newValue *big.Int) {
+		addChange(field, convertFloatToSixDecimalsFloatText(oldValue), convertFloatToSixDecimalsFloatText(newValue))
+	}
+
+	addChange("Discrepancy", oldDiscrepancy.Discrepancy, newDiscrepancy.Discrepancy)
+	addChange("XrplHash", oldDiscrepancy.XrplTx.Hash, newDiscrepancy.XrplTx.Hash)
+	addChange("CoreumHash", oldDiscrepancy.CoreumTx.Hash, newDiscrepancy.CoreumTx.Hash)
+	addAmountChange("XrplAmount", oldDiscrepancy.XrplTx.Amount, newDiscrepancy.XrplTx.Amount)
+	addAmountChange("CoreumAmount", oldDiscrepancy.CoreumTx.Amount, newDiscrepancy.CoreumTx.Amount)
+	addAmountChange("ExpectedAmount", oldDiscrepancy.ExpectedAmount, newDiscrepancy.ExpectedAmount)
+	addAmountChange("AmountDelta", oldDiscrepancy.AmountDelta, newDiscrepancy.AmountDelta)
+
+	return changes
+}
+
+// isDiscrepancy returns true if the category is a discrepancy, and false for the matched and info rows.
+func isDiscrepancy(category string) bool {
+	return lo.Contains(allDiscrepancies, category)
+}
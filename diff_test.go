@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTxDiscrepancies(t *testing.T) {
+	xrplTx := func(hash string, amount int64) AuditTx {
+		return AuditTx{Hash: hash, Amount: big.NewInt(amount)}
+	}
+	coreumTx := func(hash string, amount int64) AuditTx {
+		return AuditTx{Hash: hash, Amount: big.NewInt(amount)}
+	}
+
+	oldDiscrepancies := []TxDiscrepancy{
+		// resolved by the rescan
+		{XrplTx: xrplTx("xrplHash1", 10), Discrepancy: DiscrepancyOrphanXrplTx},
+		// resolved and matched with include all
+		{XrplTx: xrplTx("xrplHash2", 20), Discrepancy: DiscrepancyOrphanXrplTx},
+		// the amount is changed
+		{
+			XrplTx:         xrplTx("xrplHash3", 30),
+			CoreumTx:       coreumTx("coreHash3", 25),
+			ExpectedAmount: big.NewInt(29),
+			AmountDelta:    big.NewInt(-4),
+			Discrepancy:    DiscrepancyDifferentAmountOnXrplAndCoreum,
+		},
+		// the category is changed
+		{XrplTx: xrplTx("xrplHash4", 40), Discrepancy: DiscrepancyOrphanXrplTx},
+		// the orphan coreum tx is matched
+		{CoreumTx: coreumTx("coreHash5", 50), Discrepancy: DiscrepancyOrphanCoreumTx},
+		// not changed
+		{CoreumTx: coreumTx("coreHash6", 60), Discrepancy: DiscrepancyInvalidMemoOnCoreum},
+		// the matched row becomes the discrepancy
+		{XrplTx: xrplTx("xrplHash7", 70), CoreumTx: coreumTx("coreHash7", 70)},
+	}
+	newDiscrepancies := []TxDiscrepancy{
+		{XrplTx: xrplTx("xrplHash2", 20), CoreumTx: coreumTx("coreHash2", 20)},
+		{
+			XrplTx:         xrplTx("xrplHash3", 30),
+			CoreumTx:       coreumTx("coreHash3", 26),
+			ExpectedAmount: big.NewInt(29),
+			AmountDelta:    big.NewInt(-3),
+			Discrepancy:    DiscrepancyDifferentAmountOnXrplAndCoreum,
+		},
+		{XrplTx: xrplTx("xrplHash4", 40), CoreumTx: coreumTx("coreHash4", 40), Discrepancy: DiscrepancyDifferentTargetAddressesOnXrplAndCoreum},
+		{XrplTx: xrplTx("xrplHash5", 50), CoreumTx: coreumTx("coreHash5", 50)},
+		{CoreumTx: coreumTx("coreHash6", 60), Discrepancy: DiscrepancyInvalidMemoOnCoreum},
+		{XrplTx: xrplTx("xrplHash7", 70), CoreumTx: coreumTx("coreHash7", 70), Discrepancy: DiscrepancyDifferentTargetAddressesOnXrplAndCoreum},
+		// new
+		{XrplTx: xrplTx("xrplHash8", 80), Discrepancy: DiscrepancyOrphanXrplTx},
+	}
+
+	diffs := DiffTxDiscrepancies(oldDiscrepancies, newDiscrepancies)
+	require.Equal(t, []TxDiscrepancyDiff{
+		{
+			Status:         DiffStatusResolved,
+			XrplHash:       "xrplHash1",
+			OldDiscrepancy: DiscrepancyOrphanXrplTx,
+		},
+		{
+			Status:         DiffStatusResolved,
+			XrplHash:       "xrplHash2",
+			CoreumHash:     "coreHash2",
+			OldDiscrepancy: DiscrepancyOrphanXrplTx,
+			Changes: []string{
+				`Discrepancy: "orphan xrpl tx" -> ""`,
+				`CoreumHash: "" -> "coreHash2"`,
+				`CoreumAmount: "" -> "0.000020"`,
+			},
+		},
+		{
+			Status:         DiffStatusResolved,
+			XrplHash:       "xrplHash5",
+			CoreumHash:     "coreHash5",
+			OldDiscrepancy: DiscrepancyOrphanCoreumTx,
+			Changes: []string{
+				`Discrepancy: "orphan coreum tx" -> ""`,
+				`XrplHash: "" -> "xrplHash5"`,
+				`XrplAmount: "" -> "0.000050"`,
+			},
+		},
+		{
+			Status:         DiffStatusChanged,
+			XrplHash:       "xrplHash3",
+			CoreumHash:     "coreHash3",
+			OldDiscrepancy: DiscrepancyDifferentAmountOnXrplAndCoreum,
+			NewDiscrepancy: DiscrepancyDifferentAmountOnXrplAndCoreum,
+			Changes: []string{
+				`CoreumAmount: "0.000025" -> "0.000026"`,
+				`AmountDelta: "-0.000004" -> "-0.000003"`,
+			},
+		},
+		{
+			Status:         DiffStatusChanged,
+			XrplHash:       "xrplHash4",
+			CoreumHash:     "coreHash4",
+			OldDiscrepancy: DiscrepancyOrphanXrplTx,
+			NewDiscrepancy: DiscrepancyDifferentTargetAddressesOnXrplAndCoreum,
+			Changes: []string{
+				`Discrepancy: "orphan xrpl tx" -> "different target addresses on xrpl and coreum"`,
+				`CoreumHash: "" -> "coreHash4"`,
+				`CoreumAmount: "" -> "0.000040"`,
+			},
+		},
+		{
+			Status:         DiffStatusNew,
+			XrplHash:       "xrplHash7",
+			CoreumHash:     "coreHash7",
+			NewDiscrepancy: DiscrepancyDifferentTargetAddressesOnXrplAndCoreum,
+			Changes: []string{
+				`Discrepancy: "" -> "different target addresses on xrpl and coreum"`,
+			},
+		},
+		{
+			Status:         DiffStatusNew,
+			XrplHash:       "xrplHash8",
+			NewDiscrepancy: DiscrepancyOrphanXrplTx,
+		},
+	}, diffs)
+	require.Equal(t, map[string]int{
+		DiffStatusResolved: 3,
+		DiffStatusChanged:  2,
+		DiffStatusNew:      2,
+	}, CountTxDiscrepancyDiffs(diffs))
+}
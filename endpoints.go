@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// EndpointHealthProbe returns the latest block height or ledger index of the endpoint.
+type EndpointHealthProbe func(ctx context.Context, httpClient *HTTPClient, endpointURL string) (int64, error)
+
+// EndpointStatus is the result of the endpoint health check.
+type EndpointStatus struct {
+	URL    string
+	Height int64
+	Behind bool
+	Err    error
+}
+
+// EndpointPool distributes the requests addressed to the primary (first) endpoint across all the redundant
+// endpoints of the pool in the round-robin manner. The endpoints which fail or are behind are skipped.
+type EndpointPool struct {
+	name     string
+	primary  string
+	urls     []string
+	probe    EndpointHealthProbe
+	maxLag   int64
+	cooldown time.Duration
+
+	next uint64
+
+	mu             sync.Mutex
+	unhealthyUntil []time.Time
+	behind         []bool
+}
+
+// NewEndpointPool returns new instance of the EndpointPool.
+func NewEndpointPool(name string, urls []string, probe EndpointHealthProbe, maxLag int64, cooldown time.Duration) (*EndpointPool, error) {
+	if len(urls) == 0 {
+		return nil, errors.Errorf("no endpoints are provided for %s", name)
+	}
+
+	trimmedURLs := make([]string, 0, len(urls))
+	for _, endpointURL := range urls {
+		if _, err := url.Parse(endpointURL); err != nil {
+			return nil, errors.Errorf("invalid %s endpoint url %s, err: %s", name, endpointURL, err)
+		}
+		trimmedURLs = append(trimmedURLs, strings.TrimSuffix(endpointURL, "/"))
+	}
+
+	return &EndpointPool{
+		name:           name,
+		primary:        trimmedURLs[0],
+		urls:           trimmedURLs,
+		probe:          probe,
+		maxLag:         maxLag,
+		cooldown:       cooldown,
+		unhealthyUntil: make([]time.Time, len(urls)),
+		behind:         make([]bool, len(urls)),
+	}, nil
+}
+
+// Name returns the pool name.
+func (p *EndpointPool) Name() string {
+	return p.name
+}
+
+// URLs returns the urls of all the endpoints.
+func (p *EndpointPool) URLs() []string {
+	return p.urls
+}
+
+// CheckHealth probes all the endpoints and excludes the failed and behind ones from the rotation.
+func (p *EndpointPool) CheckHealth(ctx context.Context, httpClient *HTTPClient) []EndpointStatus {
+	statuses := make([]EndpointStatus, len(p.urls))
+	maxHeight := int64(0)
+	for i, endpointURL := range p.urls {
+		height, err := p.probe(WithoutRetries(WithDirectEndpoint(ctx)), httpClient, endpointURL)
+		statuses[i] = EndpointStatus{
+			URL:    endpointURL,
+			Height: height,
+			Err:    err,
+		}
+		if err == nil && height > maxHeight {
+			maxHeight = height
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range statuses {
+		if statuses[i].Err != nil {
+			// the height of the failed endpoint is unknown, so it's excluded by the cooldown only, and isn't kept
+			// behind after the cooldown until the next successful probe
+			p.unhealthyUntil[i] = time.Now().Add(p.cooldown)
+			p.behind[i] = false
+			continue
+		}
+		statuses[i].Behind = maxHeight-statuses[i].Height > p.maxLag
+		p.behind[i] = statuses[i].Behind
+	}
+
+	return statuses
+}
+
+// rewrite returns the index of the next available endpoint and the request url addressed to it.
+// The second value is false if the url doesn't belong to the pool.
+func (p *EndpointPool) rewrite(requestURL *url.URL) (int, *url.URL, bool) {
+	requestURLString := requestURL.String()
+	if !strings.HasPrefix(requestURLString, p.primary) {
+		return 0, nil, false
+	}
+
+	i := p.pick()
+	rewrittenURL, err := url.Parse(p.urls[i] + strings.TrimPrefix(requestURLString, p.primary))
+	if err != nil {
+		return 0, nil, false
+	}
+
+	return i, rewrittenURL, true
+}
+
+// pick returns the index of the next available endpoint, or the next one in the rotation if none is available.
+func (p *EndpointPool) pick() int {
+	start := int((atomic.AddUint64(&p.next, 1) - 1) % uint64(len(p.urls)))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for offset := 0; offset < len(p.urls); offset++ {
+		i := (start + offset) % len(p.urls)
+		if !p.behind[i] && !now.Before(p.unhealthyUntil[i]) {
+			return i
+		}
+	}
+
+	return start
+}
+
+func (p *EndpointPool) markFailed(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unhealthyUntil[i] = time.Now().Add(p.cooldown)
+}
+
+// StartHealthChecks runs the health checks of all the pools of the client periodically until the context is done.
+// It must be called once per client, use SetupHTTPClient to share the client and its health checks in the process.
+func StartHealthChecks(ctx context.Context, httpClient *HTTPClient, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, pool := range httpClient.Pools() {
+					pool.CheckHealth(ctx, httpClient)
+				}
+			}
+		}
+	}()
+}
+
+type directEndpointKey struct{}
+
+// WithDirectEndpoint returns the context for the requests which must be sent to the requested endpoint
+// bypassing the pool rotation.
+func WithDirectEndpoint(ctx context.Context) context.Context {
+	return context.WithValue(ctx, directEndpointKey{}, true)
+}
+
+func isDirectEndpoint(ctx context.Context) bool {
+	direct, _ := ctx.Value(directEndpointKey{}).(bool)
+	return direct
+}
+
+// failoverTransport is the http.RoundTripper which sends the requests addressed to the pool to the next
+// available endpoint of the pool.
+type failoverTransport struct {
+	base  http.RoundTripper
+	pools []*EndpointPool
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isDirectEndpoint(req.Context()) {
+		return t.base.RoundTrip(req)
+	}
+
+	for _, pool := range t.pools {
+		i, rewrittenURL, ok := pool.rewrite(req.URL)
+		if !ok {
+			continue
+		}
+		rewrittenReq := req.Clone(req.Context())
+		rewrittenReq.URL = rewrittenURL
+		rewrittenReq.Host = rewrittenURL.Host
+		resp, err := t.base.RoundTrip(rewrittenReq)
+		// the failed endpoint is skipped for the cooldown, so the retry goes to the next one
+		if req.Context().Err() == nil && isRetryableResponse(resp, err) {
+			pool.markFailed(i)
+		}
+
+		return resp, err
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+type xrplServerInfoRequest struct {
+	Method string                   `json:"method"`
+	Params []map[string]interface{} `json:"params"`
+}
+
+type xrplServerInfoResp struct {
+	Result struct {
+		Status string `json:"status"`
+		Info   struct {
+			ValidatedLedger struct {
+				Seq int64 `json:"seq"`
+			} `json:"validated_ledger"`
+		} `json:"info"`
+	} `json:"result"`
+}
+
+// XrplEndpointHealthProbe returns the latest validated ledger index of the rippled endpoint.
+func XrplEndpointHealthProbe(ctx context.Context, httpClient *HTTPClient, endpointURL string) (int64, error) {
+	return GetXrplValidatedLedgerIndex(ctx, httpClient, endpointURL)
+}
+
+type tendermintStatusResp struct {
+	Result struct {
+		SyncInfo struct {
+			LatestBlockHeight string `json:"latest_block_height"`
+			CatchingUp        bool   `json:"catching_up"`
+		} `json:"sync_info"`
+	} `json:"result"`
+}
+
+// CoreumEndpointHealthProbe returns the latest block height of the tendermint RPC endpoint.
+func CoreumEndpointHealthProbe(ctx context.Context, httpClient *HTTPClient, endpointURL string) (int64, error) {
+	var resBody tendermintStatusResp
+	if err := httpClient.DoJSON(ctx, http.MethodGet, fmt.Sprintf("%s/status", endpointURL), nil, &resBody); err != nil {
+		return 0, err
+	}
+	if resBody.Result.SyncInfo.CatchingUp {
+		return 0, errors.New("node is catching up")
+	}
+
+	height, err := strconv.ParseInt(resBody.Result.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("can't parse latest block height %q, err: %s", resBody.Result.SyncInfo.LatestBlockHeight, err)
+	}
+
+	return height, nil
+}
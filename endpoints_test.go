@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointPoolFailover(t *testing.T) {
+	var failingCalls, healthyCalls int32
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failingCalls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failingServer.Close()
+	healthyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&healthyCalls, 1)
+		require.Equal(t, "/path", r.URL.Path)
+		_, _ = w.Write([]byte(`{"result":"success"}`))
+	}))
+	defer healthyServer.Close()
+
+	pool, err := NewEndpointPool("test", []string{failingServer.URL, healthyServer.URL}, nil, 0, time.Minute)
+	require.NoError(t, err)
+	httpClient := NewHTTPClient(HTTPConfig{
+		MaxRetries: 1,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	}, pool)
+
+	for i := 0; i < 4; i++ {
+		var resBody struct {
+			Result string `json:"result"`
+		}
+		require.NoError(t, httpClient.DoJSON(context.Background(), http.MethodGet, failingServer.URL+"/path", nil, &resBody))
+		require.Equal(t, "success", resBody.Result)
+	}
+	// the failing endpoint is called once and then is excluded for the cooldown
+	require.Equal(t, int32(1), atomic.LoadInt32(&failingCalls))
+	require.Equal(t, int32(4), atomic.LoadInt32(&healthyCalls))
+}
+
+func TestEndpointPoolCheckHealth(t *testing.T) {
+	heights := map[string]int64{
+		"http://node1": 100,
+		"http://node2": 95,
+		"http://node3": 80,
+	}
+	probe := func(ctx context.Context, httpClient *HTTPClient, endpointURL string) (int64, error) {
+		require.True(t, isDirectEndpoint(ctx))
+		return heights[endpointURL], nil
+	}
+
+	pool, err := NewEndpointPool("test", []string{"http://node1", "http://node2/", "http://node3"}, probe, 10, time.Minute)
+	require.NoError(t, err)
+
+	statuses := pool.CheckHealth(context.Background(), NewHTTPClient(HTTPConfig{}, pool))
+	require.Equal(t, []EndpointStatus{
+		{URL: "http://node1", Height: 100},
+		{URL: "http://node2", Height: 95},
+		{URL: "http://node3", Height: 80, Behind: true},
+	}, statuses)
+
+	// the behind node is skipped in the rotation
+	for i := 0; i < 6; i++ {
+		require.NotEqual(t, 2, pool.pick())
+	}
+
+	// the failed probe of the behind node resets it, so it's excluded by the cooldown only
+	probeErr := errors.New("probe failed")
+	failingProbe := func(ctx context.Context, httpClient *HTTPClient, endpointURL string) (int64, error) {
+		if endpointURL == "http://node3" {
+			return 0, probeErr
+		}
+		return heights[endpointURL], nil
+	}
+	pool.probe = failingProbe
+	statuses = pool.CheckHealth(context.Background(), NewHTTPClient(HTTPConfig{}, pool))
+	require.Equal(t, EndpointStatus{URL: "http://node3", Err: probeErr}, statuses[2])
+	require.False(t, pool.behind[2])
+	require.True(t, time.Now().Before(pool.unhealthyUntil[2]))
+	// the cooldown is over
+	pool.unhealthyUntil[2] = time.Time{}
+	require.Contains(t, []int{pool.pick(), pool.pick(), pool.pick()}, 2)
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const fakeXrplLedgerIndex = 80_000_000
+
+// fakeXrplServer serves the rippled JSON-RPC, the historical data API and the xrpscan API.
+type fakeXrplServer struct {
+	*httptest.Server
+
+	t                   *testing.T
+	pageSize            int
+	txs                 []xrplTransaction
+	obligations         []xrplCurrencySupply
+	obligationsByLedger map[int64]map[string]string
+	paymentsRequests    int32
+}
+
+func newFakeXrplServer(t *testing.T, pageSize int, txs []xrplTransaction, obligations []xrplCurrencySupply) *fakeXrplServer {
+	s := &fakeXrplServer{
+		t:                   t,
+		pageSize:            pageSize,
+		txs:                 txs,
+		obligations:         obligations,
+		obligationsByLedger: make(map[int64]map[string]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleRPC)
+	mux.HandleFunc("/v2/accounts/", s.handlePayments)
+	mux.HandleFunc("/api/v1/account/", s.handleObligations)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// SetLedgerObligations sets the obligations returned by the gateway balances at the ledger, the zero ledger index is
+// the latest validated ledger.
+func (s *fakeXrplServer) SetLedgerObligations(ledgerIndex int64, obligations map[string]string) {
+	s.obligationsByLedger[ledgerIndex] = obligations
+}
+
+func (s *fakeXrplServer) handleRPC(w http.ResponseWriter, r *http.Request) {
+	require.Equal(s.t, http.MethodPost, r.Method)
+	var req struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	require.NoError(s.t, json.NewDecoder(r.Body).Decode(&req))
+
+	switch req.Method {
+	case "tx":
+		var params xrplTransactionRequestParams
+		require.NoError(s.t, json.Unmarshal(req.Params[0], &params))
+		for _, tx := range s.txs {
+			if tx.Hash == params.Transaction {
+				tx.Status = xrplResStatusSuccess
+				writeFakeJSON(s.t, w, xrplTransactionResp{Result: tx})
+				return
+			}
+		}
+		writeFakeJSON(s.t, w, xrplTransactionResp{Result: xrplTransaction{
+			Status: xrplResStatusError,
+			Error:  "txnNotFound",
+		}})
+	case "server_info":
+		var resBody xrplServerInfoResp
+		resBody.Result.Status = xrplResStatusSuccess
+		resBody.Result.Info.ValidatedLedger.Seq = fakeXrplLedgerIndex
+		writeFakeJSON(s.t, w, resBody)
+	case "gateway_balances":
+		var params xrplGatewayBalancesRequestParams
+		require.NoError(s.t, json.Unmarshal(req.Params[0], &params))
+		require.Equal(s.t, defaultXrplIssuer, params.Account)
+		var resBody xrplGatewayBalancesResp
+		obligations, ok := s.obligationsByLedger[int64(params.LedgerIndex)]
+		if !ok {
+			resBody.Result.Status = xrplResStatusError
+			resBody.Result.Error = "lgrNotFound"
+			writeFakeJSON(s.t, w, resBody)
+			return
+		}
+		resBody.Result.Status = xrplResStatusSuccess
+		resBody.Result.Obligations = obligations
+		writeFakeJSON(s.t, w, resBody)
+	default:
+		s.t.Errorf("unexpected xrpl rpc method %q", req.Method)
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// PaymentsRequests returns the number of the served historical payments pages.
+func (s *fakeXrplServer) PaymentsRequests() int {
+	return int(atomic.LoadInt32(&s.paymentsRequests))
+}
+
+// handlePayments serves the hashes of the txs sent or received by the account page by page, the marker is the index of
+// the next tx.
+func (s *fakeXrplServer) handlePayments(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&s.paymentsRequests, 1)
+	require.Equal(s.t, http.MethodGet, r.Method)
+	require.True(s.t, strings.HasSuffix(r.URL.Path, "/payments/"))
+	account := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/accounts/"), "/payments/")
+	txType := r.URL.Query().Get("type")
+	require.Contains(s.t, []string{xrplReceivedTxType, xrplSentTxType}, txType)
+	txs := lo.Filter(s.txs, func(tx xrplTransaction, _ int) bool {
+		if txType == xrplSentTxType {
+			return tx.Account == account
+		}
+		return tx.Destination == account
+	})
+
+	start := 0
+	if marker := r.URL.Query().Get("marker"); marker != "" {
+		var err error
+		start, err = strconv.Atoi(marker)
+		require.NoError(s.t, err)
+	}
+	end := start + s.pageSize
+	resBody := xrplAccountTransactionsResp{
+		Result:   xrplResStatusSuccess,
+		Payments: make([]xrplAccountTransactionPayment, 0),
+	}
+	if end < len(txs) {
+		resBody.Marker = strconv.Itoa(end)
+	} else {
+		end = len(txs)
+	}
+	for _, tx := range txs[start:end] {
+		resBody.Payments = append(resBody.Payments, xrplAccountTransactionPayment{TxHash: tx.Hash})
+	}
+
+	writeFakeJSON(s.t, w, resBody)
+}
+
+func (s *fakeXrplServer) handleObligations(w http.ResponseWriter, r *http.Request) {
+	require.Equal(s.t, http.MethodGet, r.Method)
+	require.True(s.t, strings.HasSuffix(r.URL.Path, "/obligations"))
+	writeFakeJSON(s.t, w, s.obligations)
+}
+
+// newFakeXrplBridgeTx returns the xrpl payment with the bridge memo.
+func newFakeXrplBridgeTx(hash, targetAddress, chainIndex, amount string, timestamp time.Time) xrplTransaction {
+	memo := fmt.Sprintf("%s:%s", targetAddress, chainIndex)
+	xrplEpoch := time.Date(2000, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
+
+	return xrplTransaction{
+		Account:     "rSenderAccount",
+		Destination: defaultXrplAccount,
+		Amount: xrplMetaDeliveredAmount{
+			Currency: defaultXrplCurrency,
+			Issuer:   defaultXrplIssuer,
+			Value:    mustParseFloat(amount),
+		},
+		Meta: xrplMeta{
+			DeliveredAmount: xrplMetaDeliveredAmount{
+				Currency: defaultXrplCurrency,
+				Issuer:   defaultXrplIssuer,
+				Value:    mustParseFloat(amount),
+			},
+			TransactionResult: xrplTxResultSuccess,
+		},
+		Memos: []xrplMemo{
+			{Memo: xrplMemoItem{MemoData: strings.ToUpper(hex.EncodeToString([]byte(memo)))}},
+		},
+		Hash:            hash,
+		TransactionType: "Payment",
+		Date:            int(timestamp.Sub(xrplEpoch).Seconds()),
+		LedgerIndex:     fakeXrplLedgerIndexAt(timestamp),
+		Validated:       true,
+	}
+}
+
+// newFakeXrplIssuerPayment returns the xrpl payment of the currency sent by the issuer.
+func newFakeXrplIssuerPayment(hash, destination, amount string, timestamp time.Time) xrplTransaction {
+	xrplEpoch := time.Date(2000, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
+
+	return xrplTransaction{
+		Account:     defaultXrplIssuer,
+		Destination: destination,
+		Amount: xrplMetaDeliveredAmount{
+			Currency: defaultXrplCurrency,
+			Issuer:   defaultXrplIssuer,
+			Value:    mustParseFloat(amount),
+		},
+		Meta: xrplMeta{
+			DeliveredAmount: xrplMetaDeliveredAmount{
+				Currency: defaultXrplCurrency,
+				Issuer:   defaultXrplIssuer,
+				Value:    mustParseFloat(amount),
+			},
+			TransactionResult: xrplTxResultSuccess,
+		},
+		Hash:            hash,
+		TransactionType: "Payment",
+		Date:            int(timestamp.Sub(xrplEpoch).Seconds()),
+		LedgerIndex:     fakeXrplLedgerIndexAt(timestamp),
+		Validated:       true,
+	}
+}
+
+// fakeXrplLedgerIndexAt returns the number of hours since the xrpl epoch as the ledger index, so the ledgers are
+// ordered as the txs.
+func fakeXrplLedgerIndexAt(timestamp time.Time) int64 {
+	xrplEpoch := time.Date(2000, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
+
+	return int64(timestamp.Sub(xrplEpoch) / time.Hour)
+}
+
+// fakeCoreumTx is the bank send tx served by the fake tendermint server, the Msgs replace the bank send message but
+// keep its bank events.
+type fakeCoreumTx struct {
+	Height int64
+	Time   time.Time
+	From   string
+	To     string
+	Amount int64
+	Fee    int64
+	Memo   string
+	Msgs   []sdk.Msg
+}
+
+// fakeTendermintServer serves the tendermint JSON-RPC methods required to query the txs and balances.
+type fakeTendermintServer struct {
+	*httptest.Server
+
+	t *testing.T
+
+	mu               sync.Mutex
+	txsByQuery       map[string][]*ctypes.ResultTx
+	blockTimes       map[int64]time.Time
+	balances         map[string]sdk.Coin
+	balancesByHeight map[int64]map[string]sdk.Coin
+	latestBlock      int64
+	healthProbes     int
+}
+
+func newFakeTendermintServer(t *testing.T) *fakeTendermintServer {
+	s := &fakeTendermintServer{
+		t:                t,
+		txsByQuery:       make(map[string][]*ctypes.ResultTx),
+		blockTimes:       make(map[int64]time.Time),
+		balances:         make(map[string]sdk.Coin),
+		balancesByHeight: make(map[int64]map[string]sdk.Coin),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handleRPC))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// AddTxs encodes the txs and adds them to the results of the tx search by the query.
+func (s *fakeTendermintServer) AddTxs(query string, txs ...fakeCoreumTx) {
+	clientCtx := createClientContext(Config{CoreumRPCURLs: []string{s.URL}}, NewHTTPClient(HTTPConfig{}))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tx := range txs {
+		txBuilder := clientCtx.TxConfig.NewTxBuilder()
+		msgs := tx.Msgs
+		if len(msgs) == 0 {
+			msgs = []sdk.Msg{&banktypes.MsgSend{
+				FromAddress: tx.From,
+				ToAddress:   tx.To,
+				Amount:      sdk.NewCoins(sdk.NewInt64Coin("ucore", tx.Amount)),
+			}}
+		}
+		require.NoError(s.t, txBuilder.SetMsgs(msgs...))
+		txBuilder.SetMemo(tx.Memo)
+		txBytes, err := clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+		require.NoError(s.t, err)
+
+		// the fee and the sent amount are spent by the sender
+		events := make([]abci.Event, 0)
+		for _, amount := range []int64{tx.Fee, tx.Amount} {
+			if amount == 0 {
+				continue
+			}
+			events = append(events, abci.Event{
+				Type: banktypes.EventTypeCoinSpent,
+				Attributes: []abci.EventAttribute{
+					{Key: []byte(banktypes.AttributeKeySpender), Value: []byte(tx.From)},
+					{Key: []byte(sdk.AttributeKeyAmount), Value: []byte(sdk.NewInt64Coin("ucore", amount).String())},
+				},
+			})
+		}
+		// the sent amount is received by the recipient
+		if tx.Amount != 0 {
+			events = append(events, abci.Event{
+				Type: banktypes.EventTypeCoinReceived,
+				Attributes: []abci.EventAttribute{
+					{Key: []byte(banktypes.AttributeKeyReceiver), Value: []byte(tx.To)},
+					{Key: []byte(sdk.AttributeKeyAmount), Value: []byte(sdk.NewInt64Coin("ucore", tx.Amount).String())},
+				},
+			})
+		}
+		s.txsByQuery[query] = append(s.txsByQuery[query], &ctypes.ResultTx{
+			Hash:     tmtypes.Tx(txBytes).Hash(),
+			Height:   tx.Height,
+			Tx:       txBytes,
+			TxResult: abci.ResponseDeliverTx{Events: events},
+		})
+		s.blockTimes[tx.Height] = tx.Time
+		if tx.Height > s.latestBlock {
+			s.latestBlock = tx.Height
+		}
+	}
+}
+
+// HealthProbes returns the number of the served health probes, the status requests of the URI form.
+func (s *fakeTendermintServer) HealthProbes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.healthProbes
+}
+
+// SetBalance sets the balance returned by the bank balance query, at the heights without the SetBalanceAt balances too.
+func (s *fakeTendermintServer) SetBalance(address string, coin sdk.Coin) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balances[address+coin.Denom] = coin
+}
+
+// SetBalanceAt sets the balance returned by the bank balance query at the height.
+func (s *fakeTendermintServer) SetBalanceAt(height int64, address string, coin sdk.Coin) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.balancesByHeight[height]; !ok {
+		s.balancesByHeight[height] = make(map[string]sdk.Coin)
+	}
+	s.balancesByHeight[height][address+coin.Denom] = coin
+}
+
+func (s *fakeTendermintServer) handleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == "/status" {
+		s.mu.Lock()
+		s.healthProbes++
+		latestBlock := s.latestBlock
+		s.mu.Unlock()
+		_, err := fmt.Fprintf(w, `{"result":{"sync_info":{"latest_block_height":"%d","catching_up":false}}}`, latestBlock)
+		require.NoError(s.t, err)
+		return
+	}
+
+	var req rpctypes.RPCRequest
+	require.NoError(s.t, json.NewDecoder(r.Body).Decode(&req))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result interface{}
+	switch req.Method {
+	case "tx_search":
+		var params struct {
+			Query   string `json:"query"`
+			Page    *int   `json:"page"`
+			PerPage *int   `json:"per_page"`
+		}
+		require.NoError(s.t, tmjson.Unmarshal(req.Params, &params))
+		// the first condition selects the txs, the optional second one pins them to the height
+		conditions := strings.Split(params.Query, " AND ")
+		txs := s.txsByQuery[conditions[0]]
+		if len(conditions) > 1 {
+			require.Len(s.t, conditions, 2)
+			maxHeight, err := strconv.ParseInt(strings.TrimPrefix(conditions[1], "tx.height<="), 10, 64)
+			require.NoError(s.t, err)
+			txs = lo.Filter(txs, func(tx *ctypes.ResultTx, _ int) bool {
+				return tx.Height <= maxHeight
+			})
+		}
+		start := (*params.Page - 1) * *params.PerPage
+		end := start + *params.PerPage
+		if start > len(txs) {
+			start = len(txs)
+		}
+		if end > len(txs) {
+			end = len(txs)
+		}
+		result = &ctypes.ResultTxSearch{
+			Txs:        txs[start:end],
+			TotalCount: len(txs),
+		}
+	case "block":
+		var params struct {
+			Height *int64 `json:"height"`
+		}
+		require.NoError(s.t, tmjson.Unmarshal(req.Params, &params))
+		result = &ctypes.ResultBlock{
+			Block: &tmtypes.Block{
+				Header: tmtypes.Header{
+					Height: *params.Height,
+					Time:   s.blockTimes[*params.Height],
+				},
+			},
+		}
+	case "status":
+		result = &ctypes.ResultStatus{
+			SyncInfo: ctypes.SyncInfo{
+				LatestBlockHeight: s.latestBlock,
+				LatestBlockTime:   s.blockTimes[s.latestBlock],
+			},
+		}
+	case "abci_query":
+		var params struct {
+			Path   string           `json:"path"`
+			Data   tmbytes.HexBytes `json:"data"`
+			Height int64            `json:"height,string"`
+		}
+		require.NoError(s.t, tmjson.Unmarshal(req.Params, &params))
+		require.Equal(s.t, "/cosmos.bank.v1beta1.Query/Balance", params.Path)
+		var balanceReq banktypes.QueryBalanceRequest
+		require.NoError(s.t, balanceReq.Unmarshal(params.Data))
+		balances, height := s.balances, s.latestBlock
+		// the balances which aren't set at the height are the latest ones
+		if params.Height > 0 {
+			height = params.Height
+			if balancesAt, ok := s.balancesByHeight[params.Height]; ok {
+				balances = balancesAt
+			}
+		}
+		balance, ok := balances[balanceReq.Address+balanceReq.Denom]
+		if !ok {
+			balance = sdk.NewInt64Coin(balanceReq.Denom, 0)
+		}
+		value, err := (&banktypes.QueryBalanceResponse{Balance: &balance}).Marshal()
+		require.NoError(s.t, err)
+		result = &ctypes.ResultABCIQuery{
+			Response: abci.ResponseQuery{
+				Value:  value,
+				Height: height,
+			},
+		}
+	default:
+		s.t.Errorf("unexpected tendermint rpc method %q", req.Method)
+		result = nil
+	}
+
+	res := rpctypes.NewRPCSuccessResponse(req.ID, result)
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(s.t, json.NewEncoder(w).Encode(res))
+}
+
+// fakeMultichainServer serves the multichain rescan API.
+type fakeMultichainServer struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	failedHashes map[string]struct{}
+	txHashes     []string
+}
+
+func newFakeMultichainServer(t *testing.T, failedHashes ...string) *fakeMultichainServer {
+	s := &fakeMultichainServer{
+		failedHashes: make(map[string]struct{}),
+	}
+	for _, txHash := range failedHashes {
+		s.failedHashes[txHash] = struct{}{}
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/reswaptxns", r.URL.Path)
+		txHash := r.URL.Query().Get("hash")
+
+		s.mu.Lock()
+		s.txHashes = append(s.txHashes, txHash)
+		s.mu.Unlock()
+
+		if _, ok := s.failedHashes[txHash]; ok {
+			writeFakeJSON(t, w, multichainRescanResp{Msg: "Error", Error: "tx not found"})
+			return
+		}
+		writeFakeJSON(t, w, multichainRescanResp{Msg: multichainRescanResStatusSuccess})
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// TxHashes returns the hashes of all the received rescan requests.
+func (s *fakeMultichainServer) TxHashes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.txHashes...)
+}
+
+func writeFakeJSON(t *testing.T, w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(t, json.NewEncoder(w).Encode(body))
+}
+
+func mustParseFloat(value string) *big.Float {
+	parsedValue, _, err := big.ParseFloat(value, 10, 256, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return parsedValue
+}
+
+// fakeCoreumAddress returns the deterministic coreum address for the seed.
+func fakeCoreumAddress(seed byte) string {
+	address, err := bech32.ConvertAndEncode("core", []byte(strings.Repeat(string([]byte{seed}), 20)))
+	if err != nil {
+		panic(err)
+	}
+	return address
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// PerMilleDenominator is the ratio denominator used to express the fee in tenths of a percent.
+	PerMilleDenominator = big.NewInt(1_000)
+	// BasisPointsDenominator is the ratio denominator used to express the fee in hundredths of a percent.
+	BasisPointsDenominator = big.NewInt(10_000)
+)
+
+// FeeModel computes the fee charged by the bridge for the bridged amount.
+// All the implementations use integer math only, since the amounts are in the smallest denomination.
+type FeeModel interface {
+	Fee(amount *big.Int) *big.Int
+}
+
+// RatioFeeModel charges the fee as a ratio of the amount clamped by the min and max fee.
+// fee = amount * Ratio / Denominator
+// if fee <= MinFee, fee = MinFee
+// if fee >= MaxFee, fee = MaxFee
+// The nil MinFee or MaxFee means that the fee isn't clamped from that side.
+type RatioFeeModel struct {
+	Ratio       *big.Int
+	Denominator *big.Int
+	MinFee      *big.Int
+	MaxFee      *big.Int
+}
+
+// NewPerMilleFeeModel returns the RatioFeeModel with the ratio in tenths of a percent.
+func NewPerMilleFeeModel(ratio, minFee, maxFee *big.Int) RatioFeeModel {
+	return RatioFeeModel{
+		Ratio:       ratio,
+		Denominator: PerMilleDenominator,
+		MinFee:      minFee,
+		MaxFee:      maxFee,
+	}
+}
+
+// NewBasisPointsFeeModel returns the RatioFeeModel with the ratio in hundredths of a percent.
+func NewBasisPointsFeeModel(ratio, minFee, maxFee *big.Int) RatioFeeModel {
+	return RatioFeeModel{
+		Ratio:       ratio,
+		Denominator: BasisPointsDenominator,
+		MinFee:      minFee,
+		MaxFee:      maxFee,
+	}
+}
+
+// Fee returns the fee for the amount.
+func (m RatioFeeModel) Fee(amount *big.Int) *big.Int {
+	fee := big.NewInt(0).Div(big.NewInt(0).Mul(amount, m.Ratio), m.Denominator)
+	if m.MinFee != nil && fee.Cmp(m.MinFee) == -1 {
+		fee = m.MinFee
+	}
+	if m.MaxFee != nil && fee.Cmp(m.MaxFee) == 1 {
+		fee = m.MaxFee
+	}
+
+	return big.NewInt(0).Set(fee)
+}
+
+// FixedFeeModel charges the same fee independent of the amount.
+type FixedFeeModel struct {
+	Amount *big.Int
+}
+
+// Fee returns the fee for the amount.
+func (m FixedFeeModel) Fee(_ *big.Int) *big.Int {
+	return big.NewInt(0).Set(m.Amount)
+}
+
+// FeeTier is a bracket of the TieredFeeModel.
+type FeeTier struct {
+	UpTo  *big.Int // inclusive upper bound of the amount, nil means unbounded
+	Model FeeModel
+}
+
+// TieredFeeModel charges the fee using the model of the first tier the amount fits into.
+// The tiers must be ordered by the UpTo ascending, use NewTieredFeeModel to build the validated model.
+type TieredFeeModel struct {
+	Tiers []FeeTier
+}
+
+// NewTieredFeeModel returns the TieredFeeModel if the tiers have the models, are ordered by the UpTo ascending,
+// and the last tier is unbounded, so any amount fits into a tier.
+func NewTieredFeeModel(tiers ...FeeTier) (TieredFeeModel, error) {
+	if len(tiers) == 0 {
+		return TieredFeeModel{}, errors.New("no fee tiers")
+	}
+	for i, tier := range tiers {
+		if tier.Model == nil {
+			return TieredFeeModel{}, errors.Errorf("empty fee model of the tier %d", i)
+		}
+		if i == len(tiers)-1 {
+			if tier.UpTo != nil {
+				return TieredFeeModel{}, errors.Errorf("last fee tier must be unbounded, up to: %s", tier.UpTo)
+			}
+			break
+		}
+		if tier.UpTo == nil {
+			return TieredFeeModel{}, errors.Errorf("only the last fee tier can be unbounded, tier: %d", i)
+		}
+		if i > 0 && tier.UpTo.Cmp(tiers[i-1].UpTo) != 1 {
+			return TieredFeeModel{}, errors.Errorf("fee tiers aren't ordered by the upper bound, tier: %d", i)
+		}
+	}
+
+	return TieredFeeModel{Tiers: tiers}, nil
+}
+
+// Fee returns the fee for the amount.
+func (m TieredFeeModel) Fee(amount *big.Int) *big.Int {
+	for _, tier := range m.Tiers {
+		if tier.UpTo == nil || amount.Cmp(tier.UpTo) != 1 {
+			return tier.Model.Fee(amount)
+		}
+	}
+
+	// the amount is above all tiers, which isn't possible for the model validated by NewTieredFeeModel
+	return big.NewInt(0)
+}
+
+// DestinationFeeModel charges the fee of the underlying model on the destination side, which means that
+// the fee is computed from the received amount instead of the sent one.
+// received + Model.Fee(received) = amount
+// Since the fee might be not continuous, the received amount is the max value which satisfies
+// received + Model.Fee(received) <= amount.
+// The received amount is found by the binary search, so received + Model.Fee(received) must not decrease when the
+// received amount grows. It holds for the ratio and fixed models, the tiered model is checked by
+// NewDestinationFeeModel at the tier bounds.
+type DestinationFeeModel struct {
+	Model FeeModel
+}
+
+// NewDestinationFeeModel returns the DestinationFeeModel if the model is set and the sent amount doesn't decrease at
+// the bounds of the tiered model.
+func NewDestinationFeeModel(model FeeModel) (DestinationFeeModel, error) {
+	if model == nil {
+		return DestinationFeeModel{}, errors.New("empty fee model")
+	}
+	if tieredModel, ok := model.(TieredFeeModel); ok {
+		one := big.NewInt(1)
+		for _, tier := range tieredModel.Tiers {
+			if tier.UpTo == nil {
+				continue
+			}
+			next := big.NewInt(0).Add(tier.UpTo, one)
+			sent := big.NewInt(0).Add(tier.UpTo, model.Fee(tier.UpTo))
+			nextSent := big.NewInt(0).Add(next, model.Fee(next))
+			if sent.Cmp(nextSent) == 1 {
+				return DestinationFeeModel{}, errors.Errorf(
+					"sent amount decreases at the fee tier bound %s, the received amount can't be found", tier.UpTo,
+				)
+			}
+		}
+	}
+
+	return DestinationFeeModel{Model: model}, nil
+}
+
+// Fee returns the fee for the amount.
+func (m DestinationFeeModel) Fee(amount *big.Int) *big.Int {
+	if amount.Sign() != 1 {
+		return big.NewInt(0)
+	}
+
+	// binary search of the max received amount in the [0, amount] range
+	low := big.NewInt(0)
+	high := big.NewInt(0).Set(amount)
+	one := big.NewInt(1)
+	for low.Cmp(high) == -1 {
+		mid := big.NewInt(0).Add(low, high)
+		mid.Add(mid, one)
+		mid.Rsh(mid, 1)
+		if big.NewInt(0).Add(mid, m.Model.Fee(mid)).Cmp(amount) == 1 {
+			high = mid.Sub(mid, one)
+			continue
+		}
+		low = mid
+	}
+
+	return big.NewInt(0).Sub(amount, low)
+}
+
+// computeAmountWithoutFee computes the amount expected to be received based on the fee config.
+// The nil amount is treated as zero.
+func computeAmountWithoutFee(amount *big.Int, config FeeConfig) *big.Int {
+	amount = bigIntOrZero(amount)
+	if config.FeeModel == nil {
+		return big.NewInt(0).Set(amount)
+	}
+
+	return big.NewInt(0).Sub(amount, config.FeeModel.Fee(amount))
+}
+
+// bigIntOrZero returns zero for the nil value.
+func bigIntOrZero(value *big.Int) *big.Int {
+	if value == nil {
+		return big.NewInt(0)
+	}
+	return value
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_computeAmountWithFees(t *testing.T) {
+	feeConfig := FeeConfig{
+		FeeModel: NewPerMilleFeeModel(
+			big.NewInt(1),     // 0.1%
+			big.NewInt(7000),  // 0.007 CORE
+			big.NewInt(50000), // 0.05 CORE
+		),
+	}
+
+	type args struct {
+		amount *big.Int
+		config FeeConfig
+	}
+	tests := []struct {
+		name string
+		args args
+		want *big.Int
+	}{
+		{
+			name: "min_fee",
+			args: args{
+				amount: big.NewInt(1_000_000),
+				config: feeConfig,
+			},
+			want: big.NewInt(993000),
+		},
+		{
+			name: "max_fee",
+			args: args{
+				amount: big.NewInt(100_000_000_000),
+				config: feeConfig,
+			},
+			want: big.NewInt(99999950000),
+		},
+		{
+			name: "fee_percent",
+			args: args{
+				amount: big.NewInt(10_000_000),
+				config: feeConfig,
+			},
+			want: big.NewInt(9990000),
+		},
+		{
+			name: "no_fee_model",
+			args: args{
+				amount: big.NewInt(10_000_000),
+				config: FeeConfig{},
+			},
+			want: big.NewInt(10_000_000),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeAmountWithoutFee(tt.args.amount, tt.args.config)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFeeModels(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  FeeModel
+		amount *big.Int
+		want   *big.Int
+	}{
+		{
+			name:   "per_mille_ratio",
+			model:  NewPerMilleFeeModel(big.NewInt(1), nil, nil),
+			amount: big.NewInt(10_000_000),
+			want:   big.NewInt(10_000),
+		},
+		{
+			name:   "basis_points_ratio",
+			model:  NewBasisPointsFeeModel(big.NewInt(25), nil, nil), // 0.25%
+			amount: big.NewInt(10_000_000),
+			want:   big.NewInt(25_000),
+		},
+		{
+			name:   "basis_points_ratio_rounded_down",
+			model:  NewBasisPointsFeeModel(big.NewInt(25), nil, nil),
+			amount: big.NewInt(1_999),
+			want:   big.NewInt(4),
+		},
+		{
+			name:   "basis_points_ratio_min_fee",
+			model:  NewBasisPointsFeeModel(big.NewInt(25), big.NewInt(100_000), nil),
+			amount: big.NewInt(10_000_000),
+			want:   big.NewInt(100_000),
+		},
+		{
+			name:   "basis_points_ratio_max_fee",
+			model:  NewBasisPointsFeeModel(big.NewInt(25), nil, big.NewInt(1_000)),
+			amount: big.NewInt(10_000_000),
+			want:   big.NewInt(1_000),
+		},
+		{
+			name:   "fixed",
+			model:  FixedFeeModel{Amount: big.NewInt(1_500_000)},
+			amount: big.NewInt(10_000_000),
+			want:   big.NewInt(1_500_000),
+		},
+		{
+			name: "tiered_first_tier_inclusive",
+			model: TieredFeeModel{
+				Tiers: []FeeTier{
+					{UpTo: big.NewInt(1_000_000), Model: FixedFeeModel{Amount: big.NewInt(10_000)}},
+					{UpTo: nil, Model: NewBasisPointsFeeModel(big.NewInt(10), nil, nil)},
+				},
+			},
+			amount: big.NewInt(1_000_000),
+			want:   big.NewInt(10_000),
+		},
+		{
+			name: "tiered_unbounded_tier",
+			model: TieredFeeModel{
+				Tiers: []FeeTier{
+					{UpTo: big.NewInt(1_000_000), Model: FixedFeeModel{Amount: big.NewInt(10_000)}},
+					{UpTo: nil, Model: NewBasisPointsFeeModel(big.NewInt(10), nil, nil)},
+				},
+			},
+			amount: big.NewInt(2_000_000),
+			want:   big.NewInt(2_000),
+		},
+		{
+			name:   "destination_ratio",
+			model:  DestinationFeeModel{Model: NewPerMilleFeeModel(big.NewInt(1), nil, nil)},
+			amount: big.NewInt(1_001_000),
+			want:   big.NewInt(1_000), // 1_000_000 received + 1_000 fee
+		},
+		{
+			name:   "destination_ratio_not_exact",
+			model:  DestinationFeeModel{Model: NewPerMilleFeeModel(big.NewInt(1), nil, nil)},
+			amount: big.NewInt(1_000_999),
+			want:   big.NewInt(1_000), // 999_999 received + 999 fee, the remainder is charged as well
+		},
+		{
+			name:   "destination_fixed",
+			model:  DestinationFeeModel{Model: FixedFeeModel{Amount: big.NewInt(5)}},
+			amount: big.NewInt(100),
+			want:   big.NewInt(5),
+		},
+		{
+			name:   "destination_zero_amount",
+			model:  DestinationFeeModel{Model: FixedFeeModel{Amount: big.NewInt(5)}},
+			amount: big.NewInt(0),
+			want:   big.NewInt(0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.model.Fee(tt.amount)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewTieredFeeModel(t *testing.T) {
+	model, err := NewTieredFeeModel(
+		FeeTier{UpTo: big.NewInt(1_000_000), Model: FixedFeeModel{Amount: big.NewInt(10_000)}},
+		FeeTier{UpTo: big.NewInt(2_000_000), Model: FixedFeeModel{Amount: big.NewInt(15_000)}},
+		FeeTier{Model: NewBasisPointsFeeModel(big.NewInt(10), nil, nil)},
+	)
+	require.NoError(t, err)
+	require.Len(t, model.Tiers, 3)
+
+	for _, tt := range []struct {
+		name    string
+		tiers   []FeeTier
+		wantErr string
+	}{
+		{name: "no_tiers", wantErr: "no fee tiers"},
+		{
+			name:    "nil_model",
+			tiers:   []FeeTier{{UpTo: big.NewInt(1_000_000)}, {Model: FixedFeeModel{Amount: big.NewInt(1)}}},
+			wantErr: "empty fee model of the tier 0",
+		},
+		{
+			name:    "bounded_last_tier",
+			tiers:   []FeeTier{{UpTo: big.NewInt(1_000_000), Model: FixedFeeModel{Amount: big.NewInt(1)}}},
+			wantErr: "last fee tier must be unbounded",
+		},
+		{
+			name: "unbounded_middle_tier",
+			tiers: []FeeTier{
+				{Model: FixedFeeModel{Amount: big.NewInt(1)}},
+				{Model: FixedFeeModel{Amount: big.NewInt(2)}},
+			},
+			wantErr: "only the last fee tier can be unbounded",
+		},
+		{
+			name: "unordered_tiers",
+			tiers: []FeeTier{
+				{UpTo: big.NewInt(2_000_000), Model: FixedFeeModel{Amount: big.NewInt(1)}},
+				{UpTo: big.NewInt(1_000_000), Model: FixedFeeModel{Amount: big.NewInt(2)}},
+				{Model: FixedFeeModel{Amount: big.NewInt(3)}},
+			},
+			wantErr: "fee tiers aren't ordered",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTieredFeeModel(tt.tiers...)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewDestinationFeeModel(t *testing.T) {
+	_, err := NewDestinationFeeModel(nil)
+	require.ErrorContains(t, err, "empty fee model")
+
+	// the fee grows at the bound, so the sent amount grows as well
+	tieredModel, err := NewTieredFeeModel(
+		FeeTier{UpTo: big.NewInt(1_000), Model: FixedFeeModel{Amount: big.NewInt(5)}},
+		FeeTier{Model: FixedFeeModel{Amount: big.NewInt(10)}},
+	)
+	require.NoError(t, err)
+	model, err := NewDestinationFeeModel(tieredModel)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(10), model.Fee(big.NewInt(1_011)))
+	// the amount between the tiers, 1_000 received + 5 fee, the remainder is charged as well
+	require.Equal(t, big.NewInt(8), model.Fee(big.NewInt(1_008)))
+
+	// the fee drops at the bound more than the amount grows, so the binary search can't be used
+	tieredModel, err = NewTieredFeeModel(
+		FeeTier{UpTo: big.NewInt(1_000), Model: FixedFeeModel{Amount: big.NewInt(10)}},
+		FeeTier{Model: FixedFeeModel{Amount: big.NewInt(5)}},
+	)
+	require.NoError(t, err)
+	_, err = NewDestinationFeeModel(tieredModel)
+	require.ErrorContains(t, err, "sent amount decreases at the fee tier bound 1000")
+}
+
+func FuzzComputeAmountWithoutFee(f *testing.F) {
+	f.Add(int64(10_000_000), int64(1), int64(2_400000), int64(477_000000), false)
+	f.Add(int64(0), int64(5), int64(0), int64(0), true)
+	f.Add(int64(-1), int64(0), int64(1), int64(1), false)
+
+	f.Fuzz(func(t *testing.T, amount, ratio, minFee, maxFee int64, nilAmount bool) {
+		if ratio < 0 || minFee < 0 || maxFee < minFee {
+			t.Skip()
+		}
+		feeConfig := FeeConfig{
+			FeeModel: NewPerMilleFeeModel(big.NewInt(ratio), big.NewInt(minFee), big.NewInt(maxFee)),
+		}
+		var amountInt *big.Int
+		if !nilAmount {
+			amountInt = big.NewInt(amount)
+		}
+
+		// the nil amount is treated as zero
+		amountWithoutFee := computeAmountWithoutFee(amountInt, feeConfig)
+		fee := big.NewInt(0).Sub(bigIntOrZero(amountInt), amountWithoutFee)
+		require.True(t, fee.Cmp(big.NewInt(minFee)) >= 0 && fee.Cmp(big.NewInt(maxFee)) <= 0, "fee %s", fee)
+
+		// the amount isn't modified
+		if !nilAmount {
+			require.Equal(t, big.NewInt(amount), amountInt)
+		}
+
+		// without the fee model the amount is the same
+		require.Equal(t, bigIntOrZero(amountInt).String(), computeAmountWithoutFee(amountInt, FeeConfig{}).String())
+	})
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const fixtureManifestFileName = "manifest.json"
+
+// FixtureManifest describes the recorded fixtures.
+type FixtureManifest struct {
+	// RecordedAt is the time of the recording, the replay uses it as the current time to send the same requests.
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// Fixture is the recorded request and response.
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest is the recorded request.
+type FixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// FixtureResponse is the recorded response.
+type FixtureResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// WriteFixtureManifest writes the manifest to the fixtures dir.
+func WriteFixtureManifest(dir string, manifest FixtureManifest) error {
+	if err := os.MkdirAll(dir, fs.ModePerm); err != nil {
+		return errors.Errorf("can't create dir, path: %s, err: %s", dir, err)
+	}
+
+	return writeJSONFile(filepath.Join(dir, fixtureManifestFileName), manifest)
+}
+
+// ReadFixtureManifest reads the manifest from the fixtures dir.
+func ReadFixtureManifest(dir string) (FixtureManifest, error) {
+	var manifest FixtureManifest
+	if err := readJSONFile(filepath.Join(dir, fixtureManifestFileName), &manifest); err != nil {
+		return FixtureManifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// fixtureTransport is the http.RoundTripper which records the responses to the fixtures dir or replays them
+// without network. The fixture is found by the hash of the method, url and body without the JSON-RPC id,
+// since the id is changed on each run.
+type fixtureTransport struct {
+	base   http.RoundTripper
+	dir    string
+	replay bool
+
+	mu sync.Mutex
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	// the request must not be modified by the transport, so the clone with the read body is sent
+	req = req.Clone(req.Context())
+	if reqBody != nil {
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	key, err := fixtureKey(req.Method, req.URL.String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(t.dir, key+".json")
+
+	if t.replay {
+		return t.replayFixture(req, reqBody, path)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fixture := Fixture{
+		Request: FixtureRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Body:   string(reqBody),
+		},
+		Response: FixtureResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       string(respBody),
+		},
+	}
+	// the retried request overwrites the fixture, so the last response is replayed
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := writeJSONFile(path, fixture); err != nil {
+		return nil, Permanent(err)
+	}
+
+	return resp, nil
+}
+
+func (t *fixtureTransport) replayFixture(req *http.Request, reqBody []byte, path string) (*http.Response, error) {
+	var fixture Fixture
+	if err := readJSONFile(path, &fixture); err != nil {
+		return nil, Permanent(errors.Errorf("no recorded response for %s %s, err: %s", req.Method, req.URL, err))
+	}
+
+	respBody, err := replaceJSONRPCID([]byte(fixture.Response.Body), reqBody)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(fixture.Response.StatusCode),
+		StatusCode:    fixture.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        fixture.Response.Header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads and closes the body of the request.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, errors.Errorf("can't read the request body, err: %s", err)
+	}
+
+	return data, nil
+}
+
+// fixtureKey returns the hash of the request, the JSON-RPC id is excluded.
+func fixtureKey(method, url string, body []byte) (string, error) {
+	normalizedBody := body
+	var jsonRPCReq map[string]json.RawMessage
+	if json.Unmarshal(body, &jsonRPCReq) == nil {
+		if _, ok := jsonRPCReq["id"]; ok {
+			delete(jsonRPCReq, "id")
+			var err error
+			normalizedBody, err = json.Marshal(jsonRPCReq)
+			if err != nil {
+				return "", errors.Errorf("can't marshal the request body, err: %s", err)
+			}
+		}
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{'\n'})
+	hash.Write([]byte(url))
+	hash.Write([]byte{'\n'})
+	hash.Write(normalizedBody)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// replaceJSONRPCID sets the JSON-RPC id of the request to the response, since the clients validate it.
+func replaceJSONRPCID(respBody, reqBody []byte) ([]byte, error) {
+	var jsonRPCReq map[string]json.RawMessage
+	if json.Unmarshal(reqBody, &jsonRPCReq) != nil {
+		return respBody, nil
+	}
+	id, ok := jsonRPCReq["id"]
+	if !ok {
+		return respBody, nil
+	}
+	var jsonRPCResp map[string]json.RawMessage
+	if json.Unmarshal(respBody, &jsonRPCResp) != nil {
+		return respBody, nil
+	}
+	if _, ok := jsonRPCResp["id"]; !ok {
+		return respBody, nil
+	}
+	jsonRPCResp["id"] = id
+	replacedRespBody, err := json.Marshal(jsonRPCResp)
+	if err != nil {
+		return nil, errors.Errorf("can't marshal the response body, err: %s", err)
+	}
+
+	return replacedRespBody, nil
+}
+
+func writeJSONFile(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.Errorf("can't encode json, path: %s, err: %s", path, err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return errors.Errorf("can't write file, path: %s, err: %s", path, err)
+	}
+
+	return nil
+}
+
+func readJSONFile(path string, value interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Errorf("can't read file, path: %s, err: %s", path, err)
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return errors.Errorf("can't decode json, path: %s, err: %s", path, err)
+	}
+
+	return nil
+}
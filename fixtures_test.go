@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	env := newFakeAuditEnv(t)
+	fixturesDir := t.TempDir()
+	recordedPath := filepath.Join(t.TempDir(), "recorded.csv")
+	replayedPath := filepath.Join(t.TempDir(), "replayed.csv")
+
+	require.NoError(t, env.run(t, "discrepancy", "export", "--"+includeAllFlag,
+		"--"+recordFlag, fixturesDir,
+		"--"+outputDocumentFlag, recordedPath,
+	))
+
+	// the replay doesn't use network
+	env.xrpl.Close()
+	env.tendermint.Close()
+
+	require.NoError(t, env.run(t, "discrepancy", "export", "--"+includeAllFlag,
+		"--"+replayFlag, fixturesDir,
+		"--"+outputDocumentFlag, replayedPath,
+	))
+
+	recorded, err := os.ReadFile(recordedPath)
+	require.NoError(t, err)
+	replayed, err := os.ReadFile(replayedPath)
+	require.NoError(t, err)
+	require.Equal(t, string(recorded), string(replayed))
+
+	// the request which isn't recorded fails without retries
+	err = env.run(t, "summary", "print", "--"+replayFlag, fixturesDir)
+	require.ErrorContains(t, err, "no recorded response")
+}
+
+func TestFixtureTransportReplayMiss(t *testing.T) {
+	var calls int32
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, context.DeadlineExceeded
+	})
+	httpClient := &http.Client{
+		Transport: &retryTransport{
+			base:        &fixtureTransport{base: base, dir: t.TempDir(), replay: true},
+			retryPolicy: RetryPolicy{MaxRetries: 5, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		},
+	}
+
+	_, err := httpClient.Get("http://localhost/path")
+	require.ErrorContains(t, err, "no recorded response for GET http://localhost/path")
+	require.Zero(t, atomic.LoadInt32(&calls))
+}
+
+func Test_fixtureKey(t *testing.T) {
+	key1, err := fixtureKey(http.MethodPost, "http://localhost", []byte(`{"jsonrpc":"2.0","id":1,"method":"block"}`))
+	require.NoError(t, err)
+	key2, err := fixtureKey(http.MethodPost, "http://localhost", []byte(`{"jsonrpc":"2.0","id":2,"method":"block"}`))
+	require.NoError(t, err)
+	require.Equal(t, key1, key2)
+
+	key3, err := fixtureKey(http.MethodPost, "http://localhost", []byte(`{"jsonrpc":"2.0","id":1,"method":"status"}`))
+	require.NoError(t, err)
+	require.NotEqual(t, key1, key3)
+
+	key4, err := fixtureKey(http.MethodGet, "http://localhost", nil)
+	require.NoError(t, err)
+	require.NotEqual(t, key1, key4)
+}
+
+func Test_replaceJSONRPCID(t *testing.T) {
+	respBody, err := replaceJSONRPCID([]byte(`{"id":1,"result":{}}`), []byte(`{"id":5,"method":"block"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":5,"result":{}}`, string(respBody))
+
+	// not JSON-RPC
+	respBody, err = replaceJSONRPCID([]byte(`{"result":"success"}`), []byte(`{"method":"tx"}`))
+	require.NoError(t, err)
+	require.Equal(t, `{"result":"success"}`, string(respBody))
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+)
+
+// coreumGenesis is the part of the coreum genesis file with the initial bank balances.
+type coreumGenesis struct {
+	AppState struct {
+		Bank struct {
+			Balances []struct {
+				Address string    `json:"address"`
+				Coins   sdk.Coins `json:"coins"`
+			} `json:"balances"`
+		} `json:"bank"`
+	} `json:"app_state"`
+}
+
+// ReadCoreumGenesisBalances returns the initial balances of the accounts in the denom from the coreum genesis file.
+// The accounts which aren't in the genesis have zero balance.
+func ReadCoreumGenesisBalances(path, denom string, accounts ...string) (map[string]*big.Int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("can't read file, path: %s, err: %s", path, err)
+	}
+	var genesis coreumGenesis
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, errors.Errorf("can't decode coreum genesis, path: %s, err: %s", path, err)
+	}
+
+	balances := make(map[string]*big.Int, len(accounts))
+	for _, account := range accounts {
+		balances[account] = big.NewInt(0)
+	}
+	for _, balance := range genesis.AppState.Bank.Balances {
+		if _, ok := balances[balance.Address]; !ok {
+			continue
+		}
+		// the coins aren't validated, so they might be unsorted
+		for _, coin := range balance.Coins {
+			if coin.Denom == denom {
+				balances[balance.Address] = big.NewInt(0).Add(balances[balance.Address], coin.Amount.BigInt())
+			}
+		}
+	}
+
+	return balances, nil
+}
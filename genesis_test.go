@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCoreumGenesisBalances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "chain_id": "coreum-mainnet-1",
+  "app_state": {
+    "bank": {
+      "balances": [
+        {"address": "`+defaultCoreumAccount+`", "coins": [{"denom": "ucore", "amount": "700000000000"}, {"denom": "other", "amount": "5"}]},
+        {"address": "`+fakeCoreumAddress(1)+`", "coins": [{"denom": "ucore", "amount": "1"}]}
+      ]
+    }
+  }
+}`), 0o600))
+
+	balances, err := ReadCoreumGenesisBalances(path, "ucore", defaultCoreumAccount, defaultCoreumFoundationAccount)
+	require.NoError(t, err)
+	require.Equal(t, map[string]*big.Int{
+		defaultCoreumAccount:           big.NewInt(700_000_000000),
+		defaultCoreumFoundationAccount: big.NewInt(0),
+	}, balances)
+
+	_, err = ReadCoreumGenesisBalances(filepath.Join(t.TempDir(), "missing.json"), "ucore", defaultCoreumAccount)
+	require.ErrorContains(t, err, "can't read file")
+}
@@ -5,12 +5,96 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-func DoJSON(ctx context.Context, method, url string, reqBody, respBody interface{}) error {
+// HTTPConfig is the config of the HTTP client shared by all the HTTP and RPC calls.
+type HTTPConfig struct {
+	RequestTimeout time.Duration // timeout of a single attempt
+	MaxRetries     int
+	MinBackoff     time.Duration
+	MaxBackoff     time.Duration
+	RateLimit      float64 // requests per second per host, zero means unlimited
+	RateBurst      int
+
+	EndpointMaxLag              int64 // max number of blocks or ledgers the endpoint might be behind the others
+	EndpointCooldown            time.Duration
+	EndpointHealthCheckInterval time.Duration
+
+	RecordDir string // dir to record the responses to
+	ReplayDir string // dir to replay the recorded responses from instead of sending the requests
+}
+
+// HTTPClient is the HTTP client with the per host rate limiting, the retries with the exponential backoff and
+// the failover between the redundant endpoints.
+type HTTPClient struct {
+	client      *http.Client
+	retryPolicy RetryPolicy
+	pools       []*EndpointPool
+}
+
+// NewHTTPClient returns new instance of the HTTPClient. The requests addressed to the primary endpoint of
+// any of the pools are distributed across all the endpoints of the pool.
+func NewHTTPClient(cfg HTTPConfig, pools ...*EndpointPool) *HTTPClient {
+	retryPolicy := RetryPolicy{
+		MaxRetries: cfg.MaxRetries,
+		MinBackoff: cfg.MinBackoff,
+		MaxBackoff: cfg.MaxBackoff,
+	}
+
+	// the retry is the outermost layer, so each attempt goes to the next endpoint, and the rate limit is
+	// applied to the host the attempt is sent to
+	var transport http.RoundTripper = &failoverTransport{
+		base: &rateLimitTransport{
+			base:        http.DefaultTransport,
+			rateLimiter: newHostRateLimiter(cfg.RateLimit, cfg.RateBurst),
+		},
+		pools: pools,
+	}
+	// the fixtures are recorded before the failover to be independent of the endpoint serving the request
+	switch {
+	case cfg.ReplayDir != "":
+		transport = &fixtureTransport{base: transport, dir: cfg.ReplayDir, replay: true}
+	case cfg.RecordDir != "":
+		transport = &fixtureTransport{base: transport, dir: cfg.RecordDir}
+	}
+
+	return &HTTPClient{
+		client: &http.Client{
+			Transport: &retryTransport{
+				base:           transport,
+				retryPolicy:    retryPolicy,
+				requestTimeout: cfg.RequestTimeout,
+			},
+		},
+		retryPolicy: retryPolicy,
+		pools:       pools,
+	}
+}
+
+// Pools returns the endpoint pools of the client.
+func (c *HTTPClient) Pools() []*EndpointPool {
+	return c.pools
+}
+
+// Client returns the underlying http client, which can be used by the third party clients.
+func (c *HTTPClient) Client() *http.Client {
+	return c.client
+}
+
+// RetryPolicy returns the retry policy of the client, which can be used for the application level retries.
+func (c *HTTPClient) RetryPolicy() RetryPolicy {
+	return c.retryPolicy
+}
+
+// DoJSON performs the request with the JSON body and decodes the JSON response.
+func (c *HTTPClient) DoJSON(ctx context.Context, method, url string, reqBody, respBody interface{}) error {
 	var reqBodyReader io.Reader
 	if reqBody != nil {
 		reqBodyBytes, err := json.Marshal(reqBody)
@@ -27,7 +111,7 @@ func DoJSON(ctx context.Context, method, url string, reqBody, respBody interface
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return errors.Errorf("can't perform the request, err: %v", err)
 	}
@@ -49,3 +133,270 @@ func DoJSON(ctx context.Context, method, url string, reqBody, respBody interface
 
 	return nil
 }
+
+// RetryPolicy defines the retries with the exponential backoff and jitter.
+type RetryPolicy struct {
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps the error to stop the retries of the RetryPolicy.Do.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Backoff returns the delay before the retry of the attempt, the attempts are counted from zero.
+// The delay grows exponentially and is randomized in the [backoff/2, backoff] range to spread the retries.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.MaxBackoff
+	if attempt < 32 && p.MinBackoff<<attempt < p.MaxBackoff {
+		backoff = p.MinBackoff << attempt
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// Do calls the fn until it succeeds, returns the permanent error or the retries are exhausted.
+// The fn receives the attempt number counted from zero.
+func (p RetryPolicy) Do(ctx context.Context, fn func(attempt int) error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+		var permanentErr permanentError
+		if errors.As(err, &permanentErr) {
+			return permanentErr.err
+		}
+		if attempt >= p.MaxRetries {
+			return errors.Wrapf(err, "retries are exhausted, attempts: %d", attempt+1)
+		}
+		if err := sleep(ctx, p.Backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+type noRetriesKey struct{}
+
+// WithoutRetries returns the context for the requests which must be sent only once.
+func WithoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetriesKey{}, true)
+}
+
+// retryTransport is the http.RoundTripper which retries the failed requests.
+type retryTransport struct {
+	base           http.RoundTripper
+	retryPolicy    RetryPolicy
+	requestTimeout time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	maxRetries := t.retryPolicy.MaxRetries
+	if noRetries, _ := ctx.Value(noRetriesKey{}).(bool); noRetries {
+		maxRetries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			// the body can't be rewound, so the request can't be retried
+			if req.GetBody == nil {
+				return nil, errors.New("can't retry the request with the not rewindable body")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := t.roundTripWithTimeout(attemptReq)
+		if ctx.Err() != nil || !isRetryableResponse(resp, err) || attempt >= maxRetries {
+			return resp, err
+		}
+
+		delay := t.retryPolicy.Backoff(attempt)
+		if resp != nil {
+			retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), t.retryPolicy.MaxBackoff)
+			if ok && retryAfter > delay {
+				delay = retryAfter
+			}
+			// the body is drained to reuse the connection
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *retryTransport) roundTripWithTimeout(req *http.Request) (*http.Response, error) {
+	if t.requestTimeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.requestTimeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the timeout context must live until the body is read
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func isRetryableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		var permanentErr permanentError
+		return !errors.As(err, &permanentErr)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	default:
+		return resp.StatusCode >= http.StatusInternalServerError
+	}
+}
+
+// parseRetryAfter parses the Retry-After header value which is either delay in seconds or the HTTP date.
+// The delay is capped by the positive maxDelay, so the server can't stall the retries.
+func parseRetryAfter(value string, maxDelay time.Duration) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = time.Until(date)
+	} else {
+		return 0, false
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay, true
+}
+
+// rateLimitTransport is the http.RoundTripper which limits the request rate per host.
+type rateLimitTransport struct {
+	base        http.RoundTripper
+	rateLimiter *hostRateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.rateLimiter.Wait(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// hostRateLimiter is the token bucket rate limiter with the separate bucket per host.
+type hostRateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newHostRateLimiter(rate float64, burst int) *hostRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &hostRateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Wait blocks until the request to the host is allowed.
+func (l *hostRateLimiter) Wait(ctx context.Context, host string) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	return sleep(ctx, l.reserve(host))
+}
+
+// reserve takes the token from the host bucket and returns the time to wait until the token is available.
+func (l *hostRateLimiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[host] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.last = now
+	// the token might be taken in advance, the negative balance is the wait time of the caller
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-bucket.tokens / l.rate * float64(time.Second))
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
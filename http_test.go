@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientDoJSONRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"result":"success"}`))
+		}
+	}))
+	defer server.Close()
+
+	httpClient := NewHTTPClient(HTTPConfig{
+		RequestTimeout: time.Second,
+		MaxRetries:     2,
+		MinBackoff:     time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	})
+
+	var resBody struct {
+		Result string `json:"result"`
+	}
+	require.NoError(t, httpClient.DoJSON(context.Background(), http.MethodPost, server.URL, map[string]string{}, &resBody))
+	require.Equal(t, "success", resBody.Result)
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestHTTPClientDoJSONDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	httpClient := NewHTTPClient(HTTPConfig{
+		MaxRetries: 5,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	})
+
+	var resBody struct{}
+	require.Error(t, httpClient.DoJSON(context.Background(), http.MethodGet, server.URL, nil, &resBody))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{
+		MaxRetries: 3,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 4 * time.Millisecond,
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		backoff := policy.Backoff(attempt)
+		require.LessOrEqual(t, backoff, policy.MaxBackoff)
+		require.GreaterOrEqual(t, backoff, policy.MinBackoff/2)
+	}
+
+	attempts := 0
+	temporaryErr := errors.New("temporary")
+	err := policy.Do(context.Background(), func(attempt int) error {
+		attempts++
+		return temporaryErr
+	})
+	// the last error is wrapped, so the callers can check it
+	require.ErrorIs(t, err, temporaryErr)
+	require.ErrorContains(t, err, "retries are exhausted, attempts: 4")
+	require.Equal(t, 4, attempts)
+
+	attempts = 0
+	permanentErr := errors.New("permanent")
+	err = policy.Do(context.Background(), func(attempt int) error {
+		attempts++
+		return Permanent(permanentErr)
+	})
+	require.ErrorIs(t, err, permanentErr)
+	require.Equal(t, 1, attempts)
+}
+
+func TestHostRateLimiter(t *testing.T) {
+	limiter := newHostRateLimiter(10, 2)
+
+	// the burst is available immediately
+	require.Zero(t, limiter.reserve("host1"))
+	require.Zero(t, limiter.reserve("host1"))
+	// the next token is available in 1/10 of a second
+	require.InDelta(t, 100*time.Millisecond, limiter.reserve("host1"), float64(10*time.Millisecond))
+	// the hosts are limited independently
+	require.Zero(t, limiter.reserve("host2"))
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	delay, ok := parseRetryAfter("3", 0)
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, delay)
+
+	delay, ok = parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 0)
+	require.True(t, ok)
+	require.InDelta(t, time.Minute, delay, float64(2*time.Second))
+
+	// the delay is capped by the max backoff
+	delay, ok = parseRetryAfter("3600", 30*time.Second)
+	require.True(t, ok)
+	require.Equal(t, 30*time.Second, delay)
+	delay, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), 30*time.Second)
+	require.True(t, ok)
+	require.Equal(t, 30*time.Second, delay)
+
+	_, ok = parseRetryAfter("invalid", 30*time.Second)
+	require.False(t, ok)
+}
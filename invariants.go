@@ -0,0 +1,194 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// selfCheckBeforeDateTime is the time after any tx, so the self-check doesn't filter the txs out.
+var selfCheckBeforeDateTime = time.Date(9999, time.Month(12), 31, 0, 0, 0, 0, time.UTC)
+
+// SelfCheckAudit finds all the discrepancies between the xrpl and coreum txs and checks that the result is
+// consistent with the input. It returns the violated invariants.
+func SelfCheckAudit(
+	xrplTxs, coreumTxs []AuditTx,
+	feeConfigs []FeeConfig,
+	amountTolerance AmountTolerance,
+	memoCodec MemoCodec,
+	sourceChainID string,
+) []error {
+	// all the txs are included and no time filter is applied to check each of them
+	discrepancies := FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, feeConfigs, amountTolerance, memoCodec, sourceChainID, true, selfCheckBeforeDateTime, time.Time{},
+	)
+
+	return CheckAuditInvariants(xrplTxs, coreumTxs, discrepancies, amountTolerance, memoCodec)
+}
+
+// CheckAuditInvariants checks the invariants of the discrepancies found with the includeAll and without the
+// time filter:
+// - each xrpl tx appears in exactly one row, the txs with the same hash are counted once,
+// - each coreum tx appears in exactly one row,
+// - the xrpl and coreum amounts are conserved,
+// - the rows are consistent with their discrepancy kind.
+func CheckAuditInvariants(
+	xrplTxs, coreumTxs []AuditTx,
+	discrepancies []TxDiscrepancy,
+	amountTolerance AmountTolerance,
+	memoCodec MemoCodec,
+) []error {
+	violations := make([]error, 0)
+
+	// the matching uses the last xrpl tx with the same hash
+	xrplTxsMap := make(map[string]AuditTx)
+	xrplTxHashes := make([]string, 0, len(xrplTxs))
+	for _, xrplTx := range xrplTxs {
+		hash := strings.ToUpper(xrplTx.Hash)
+		if _, ok := xrplTxsMap[hash]; !ok {
+			xrplTxHashes = append(xrplTxHashes, hash)
+		}
+		xrplTxsMap[hash] = xrplTx
+	}
+	expectedXrplAmount := big.NewInt(0)
+	for _, xrplTx := range xrplTxsMap {
+		expectedXrplAmount.Add(expectedXrplAmount, bigIntOrZero(xrplTx.Amount))
+	}
+	expectedCoreumAmount := big.NewInt(0)
+	coreumTxsCount := make(map[string]int)
+	coreumTxHashes := make([]string, 0, len(coreumTxs))
+	for _, coreumTx := range coreumTxs {
+		expectedCoreumAmount.Add(expectedCoreumAmount, bigIntOrZero(coreumTx.Amount))
+		if _, ok := coreumTxsCount[coreumTx.Hash]; !ok {
+			coreumTxHashes = append(coreumTxHashes, coreumTx.Hash)
+		}
+		coreumTxsCount[coreumTx.Hash]++
+	}
+
+	xrplAmount := big.NewInt(0)
+	coreumAmount := big.NewInt(0)
+	xrplRowsCount := make(map[string]int)
+	coreumRowsCount := make(map[string]int)
+	for i, discrepancy := range discrepancies {
+		if discrepancy.XrplTx.Hash != "" {
+			hash := strings.ToUpper(discrepancy.XrplTx.Hash)
+			if _, ok := xrplTxsMap[hash]; !ok {
+				violations = append(violations, errors.Errorf("row %d: xrpl tx %s isn't in the input", i, hash))
+			}
+			xrplRowsCount[hash]++
+			xrplAmount.Add(xrplAmount, bigIntOrZero(discrepancy.XrplTx.Amount))
+		}
+		if discrepancy.CoreumTx.Hash != "" {
+			if _, ok := coreumTxsCount[discrepancy.CoreumTx.Hash]; !ok {
+				violations = append(violations, errors.Errorf("row %d: coreum tx %s isn't in the input", i, discrepancy.CoreumTx.Hash))
+			}
+			coreumRowsCount[discrepancy.CoreumTx.Hash]++
+			coreumAmount.Add(coreumAmount, bigIntOrZero(discrepancy.CoreumTx.Amount))
+		}
+		if err := checkDiscrepancyRow(discrepancy, amountTolerance, memoCodec); err != nil {
+			violations = append(violations, errors.Errorf("row %d: %s", i, err))
+		}
+	}
+
+	for _, hash := range xrplTxHashes {
+		if count := xrplRowsCount[hash]; count != 1 {
+			violations = append(violations, errors.Errorf("xrpl tx %s appears in %d rows", hash, count))
+		}
+	}
+	for _, hash := range coreumTxHashes {
+		if count, expectedCount := coreumRowsCount[hash], coreumTxsCount[hash]; count != expectedCount {
+			violations = append(violations, errors.Errorf("coreum tx %s appears in %d rows, expected: %d", hash, count, expectedCount))
+		}
+	}
+	if xrplAmount.Cmp(expectedXrplAmount) != 0 {
+		violations = append(violations, errors.Errorf(
+			"xrpl amount isn't conserved, input: %s, rows: %s",
+			convertFloatToSixDecimalsFloatText(expectedXrplAmount), convertFloatToSixDecimalsFloatText(xrplAmount),
+		))
+	}
+	if coreumAmount.Cmp(expectedCoreumAmount) != 0 {
+		violations = append(violations, errors.Errorf(
+			"coreum amount isn't conserved, input: %s, rows: %s",
+			convertFloatToSixDecimalsFloatText(expectedCoreumAmount), convertFloatToSixDecimalsFloatText(coreumAmount),
+		))
+	}
+
+	return violations
+}
+
+// checkDiscrepancyRow checks that the row is consistent with its discrepancy kind.
+func checkDiscrepancyRow(discrepancy TxDiscrepancy, amountTolerance AmountTolerance, memoCodec MemoCodec) error {
+	hasXrplTx := discrepancy.XrplTx.Hash != ""
+	hasCoreumTx := discrepancy.CoreumTx.Hash != ""
+
+	switch discrepancy.Discrepancy {
+	case "", DiscrepancyDifferentTargetAddressesOnXrplAndCoreum, DiscrepancyDifferentAmountOnXrplAndCoreum, InfoRoundingDifference:
+		if !hasXrplTx || !hasCoreumTx {
+			return errors.Errorf("%q must have both xrpl and coreum txs", discrepancy.Discrepancy)
+		}
+		if memoHash := decodeXrplTxHashFromCoreumMemo(memoCodec, discrepancy.CoreumTx.Memo); memoHash != strings.ToUpper(discrepancy.XrplTx.Hash) {
+			return errors.Errorf("%q coreum memo hash %s doesn't match xrpl tx %s", discrepancy.Discrepancy, memoHash, discrepancy.XrplTx.Hash)
+		}
+	case DiscrepancyOrphanXrplTx, InfoAmountOutOfRange:
+		if !hasXrplTx || hasCoreumTx {
+			return errors.Errorf("%q must have the xrpl tx only", discrepancy.Discrepancy)
+		}
+	case DiscrepancyUnvalidatedXrplTx:
+		if !hasXrplTx {
+			return errors.Errorf("%q must have the xrpl tx", discrepancy.Discrepancy)
+		}
+	case DiscrepancyOrphanCoreumTx, DiscrepancyDuplicatedXrplTxHashInMemoOnCoreum, DiscrepancyInvalidMemoOnCoreum,
+		DiscrepancyUnexpectedSourceChainInMemoOnCoreum, DiscrepancyUnexpectedAdditionalPayoutOnCoreum, InfoAdditionalPayout,
+		InfoAdditionalPayoutOfDiscrepancy:
+		if hasXrplTx || !hasCoreumTx {
+			return errors.Errorf("%q must have the coreum tx only", discrepancy.Discrepancy)
+		}
+	default:
+		return errors.Errorf("unknown discrepancy %q", discrepancy.Discrepancy)
+	}
+
+	switch discrepancy.Discrepancy {
+	case "":
+		if discrepancy.XrplTx.TargetAddress != discrepancy.CoreumTx.TargetAddress {
+			return errors.Errorf("matched txs have different target addresses")
+		}
+	case DiscrepancyDifferentTargetAddressesOnXrplAndCoreum:
+		if discrepancy.XrplTx.TargetAddress == discrepancy.CoreumTx.TargetAddress {
+			return errors.Errorf("%q has the same target addresses", discrepancy.Discrepancy)
+		}
+	case DiscrepancyDifferentAmountOnXrplAndCoreum, InfoRoundingDifference:
+		if discrepancy.ExpectedAmount == nil || discrepancy.AmountDelta == nil || discrepancy.ImpliedFee == nil {
+			return errors.Errorf("%q must have the expected amount, delta and implied fee", discrepancy.Discrepancy)
+		}
+		coreumTxAmount := bigIntOrZero(discrepancy.CoreumTx.Amount)
+		amountDelta := big.NewInt(0).Sub(coreumTxAmount, discrepancy.ExpectedAmount)
+		if amountDelta.Cmp(discrepancy.AmountDelta) != 0 {
+			return errors.Errorf("%q amount delta %s isn't the coreum amount minus expected amount", discrepancy.Discrepancy, discrepancy.AmountDelta)
+		}
+		impliedFee := big.NewInt(0).Sub(bigIntOrZero(discrepancy.XrplTx.Amount), coreumTxAmount)
+		if impliedFee.Cmp(discrepancy.ImpliedFee) != 0 {
+			return errors.Errorf("%q implied fee %s isn't the xrpl amount minus coreum amount", discrepancy.Discrepancy, discrepancy.ImpliedFee)
+		}
+		if amountDelta.Sign() == 0 {
+			return errors.Errorf("%q has zero amount delta", discrepancy.Discrepancy)
+		}
+		allowed := amountTolerance.Allows(amountDelta, discrepancy.ExpectedAmount)
+		if allowed != (discrepancy.Discrepancy == InfoRoundingDifference) {
+			return errors.Errorf("%q doesn't match the amount tolerance for delta %s", discrepancy.Discrepancy, amountDelta)
+		}
+	case DiscrepancyInvalidMemoOnCoreum:
+		if decodeXrplTxHashFromCoreumMemo(memoCodec, discrepancy.CoreumTx.Memo) != "" {
+			return errors.Errorf("%q has the valid memo", discrepancy.Discrepancy)
+		}
+	case DiscrepancyOrphanCoreumTx, DiscrepancyDuplicatedXrplTxHashInMemoOnCoreum,
+		DiscrepancyUnexpectedSourceChainInMemoOnCoreum, DiscrepancyUnexpectedAdditionalPayoutOnCoreum, InfoAdditionalPayout,
+		InfoAdditionalPayoutOfDiscrepancy:
+		if decodeXrplTxHashFromCoreumMemo(memoCodec, discrepancy.CoreumTx.Memo) == "" {
+			return errors.Errorf("%q has the invalid memo", discrepancy.Discrepancy)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAuditInvariants(t *testing.T) {
+	xrplTxs := []AuditTx{
+		{Hash: "xrplHash1", TargetAddress: "core1", Amount: big.NewInt(10)},
+		{Hash: "xrplHash2", TargetAddress: "core2", Amount: big.NewInt(20)},
+	}
+	coreumTxs := []AuditTx{
+		{Hash: "coreHash1", TargetAddress: "core1", Amount: big.NewInt(10), Memo: "1111:xrplHash1:0"},
+		{Hash: "coreHash2", TargetAddress: "core2", Amount: big.NewInt(19), Memo: "1111:xrplHash2:0"},
+	}
+	discrepancies := FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "", true, selfCheckBeforeDateTime, time.Time{},
+	)
+	require.Empty(t, CheckAuditInvariants(xrplTxs, coreumTxs, discrepancies, AmountTolerance{}, nil))
+
+	// the rows found without the includeAll don't cover the matched txs
+	discrepancies = FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, nil, AmountTolerance{}, nil, "", false, selfCheckBeforeDateTime, time.Time{},
+	)
+	violations := CheckAuditInvariants(xrplTxs, coreumTxs, discrepancies, AmountTolerance{}, nil)
+	require.Len(t, violations, 4)
+	require.ErrorContains(t, violations[0], "xrpl tx XRPLHASH1 appears in 0 rows")
+
+	// the row inconsistent with its kind
+	discrepancies = []TxDiscrepancy{
+		{XrplTx: xrplTxs[0], CoreumTx: coreumTxs[0], Discrepancy: DiscrepancyOrphanXrplTx},
+		{
+			XrplTx:         xrplTxs[1],
+			CoreumTx:       coreumTxs[1],
+			ExpectedAmount: big.NewInt(20),
+			AmountDelta:    big.NewInt(-1),
+			ImpliedFee:     big.NewInt(1),
+			Discrepancy:    InfoRoundingDifference,
+		},
+	}
+	violations = CheckAuditInvariants(xrplTxs, coreumTxs, discrepancies, AmountTolerance{}, nil)
+	require.Len(t, violations, 2)
+	require.ErrorContains(t, violations[0], `row 0: "orphan xrpl tx" must have the xrpl tx only`)
+	require.ErrorContains(t, violations[1], `row 1: "not a discrepancy: rounding difference" doesn't match the amount tolerance`)
+}
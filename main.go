@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	err := rootCmd().Execute()
+	// the context is cancelled on Ctrl-C to stop the long-running fetches
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd().ExecuteContext(ctx)
+	cancel()
 	if err != nil {
 		fmt.Printf("Error: %s", err)
 		os.Exit(1)
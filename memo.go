@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// The memo codecs.
+const (
+	MemoCodecColon          = "colon"
+	MemoCodecJSON           = "json"
+	MemoCodecTyped          = "typed"
+	MemoCodecDestinationTag = "destination-tag"
+)
+
+const xrplMemoFormatJSON = "application/json"
+
+var memoCodecs = []string{
+	MemoCodecColon,
+	MemoCodecJSON,
+	MemoCodecTyped,
+	MemoCodecDestinationTag,
+}
+
+// coreumMemoFormats are the formats of the coreum memo decoded by the typed codec, named as the codecs of the format.
+var coreumMemoFormats = []string{
+	MemoCodecColon,
+	MemoCodecJSON,
+}
+
+// CoreumBridgeMemo is the memo of the coreum payout of the bridged xrpl tx: <source chain id>:0x<xrpl tx hash>:<log
+// index>. The log index distinguishes the several payouts of the same xrpl tx.
+type CoreumBridgeMemo struct {
+	SourceChainID string
+	XrplTxHash    string // upper case without the 0x prefix
+	LogIndex      int64
+}
+
+// MemoCodec decodes the bridge memos of the xrpl txs and the coreum payouts.
+type MemoCodec interface {
+	// DecodeXrplMemo returns the coreum target address and the memo of the xrpl tx, the ok is false if the tx isn't
+	// the bridge tx.
+	DecodeXrplMemo(tx xrplTransaction) (string, string, bool)
+	// DecodeCoreumMemo decodes the memo of the coreum payout.
+	DecodeCoreumMemo(memo string) (CoreumBridgeMemo, error)
+}
+
+// MemoCodecConfig is the config of the memo codec. The MemoType, MemoFormat and CoreumMemoFormat are used by the typed
+// codec, the DestinationTags by the destination-tag codec.
+type MemoCodecConfig struct {
+	Name             string            `json:"name" yaml:"name"`
+	MemoType         string            `json:"memoType,omitempty" yaml:"memoType"`
+	MemoFormat       string            `json:"memoFormat,omitempty" yaml:"memoFormat"`
+	CoreumMemoFormat string            `json:"coreumMemoFormat,omitempty" yaml:"coreumMemoFormat"`
+	DestinationTags  map[uint32]string `json:"destinationTags,omitempty" yaml:"destinationTags"`
+}
+
+// NewMemoCodec returns the memo codec of the config, the xrpl memos are accepted for the bridgeChainIndex only.
+func NewMemoCodec(config MemoCodecConfig, bridgeChainIndex string) (MemoCodec, error) {
+	switch config.Name {
+	case MemoCodecColon:
+		return ColonMemoCodec{BridgeChainIndex: bridgeChainIndex}, nil
+	case MemoCodecJSON:
+		return JSONMemoCodec{BridgeChainIndex: bridgeChainIndex}, nil
+	case MemoCodecTyped:
+		if config.MemoType == "" {
+			return nil, errors.Errorf("empty memo type of the %s memo codec", MemoCodecTyped)
+		}
+		coreumMemoFormat := config.CoreumMemoFormat
+		if coreumMemoFormat == "" {
+			coreumMemoFormat = MemoCodecColon
+		}
+		if !lo.Contains(coreumMemoFormats, coreumMemoFormat) {
+			return nil, errors.Errorf(
+				"invalid coreum memo format %q of the %s memo codec, must be one of %v",
+				coreumMemoFormat, MemoCodecTyped, coreumMemoFormats,
+			)
+		}
+		return TypedMemoCodec{
+			BridgeChainIndex: bridgeChainIndex,
+			MemoType:         config.MemoType,
+			MemoFormat:       config.MemoFormat,
+			CoreumMemoFormat: coreumMemoFormat,
+		}, nil
+	case MemoCodecDestinationTag:
+		if len(config.DestinationTags) == 0 {
+			return nil, errors.Errorf("empty destination tags of the %s memo codec", MemoCodecDestinationTag)
+		}
+		for tag, address := range config.DestinationTags {
+			if address == "" {
+				return nil, errors.Errorf("empty address of the destination tag %d", tag)
+			}
+		}
+		return DestinationTagMemoCodec{DestinationTags: config.DestinationTags}, nil
+	default:
+		return nil, errors.Errorf("invalid memo codec %q, must be one of %v", config.Name, memoCodecs)
+	}
+}
+
+// ReadXrplDestinationTags reads the YAML file with the coreum target addresses of the xrpl destination tags.
+func ReadXrplDestinationTags(path string) (map[uint32]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("can't read file, path: %s, err: %s", path, err)
+	}
+	var file struct {
+		Tags map[uint32]string `yaml:"tags"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Errorf("can't decode destination tags, path: %s, err: %s", path, err)
+	}
+
+	return file.Tags, nil
+}
+
+// ColonMemoCodec decodes the <coreum address>:<bridge chain index> xrpl memo and the
+// <source chain id>:0x<xrpl tx hash>:<log index> coreum memo.
+type ColonMemoCodec struct {
+	BridgeChainIndex string
+}
+
+// DecodeXrplMemo decodes the first memo with the bridge chain index.
+func (c ColonMemoCodec) DecodeXrplMemo(tx xrplTransaction) (string, string, bool) {
+	for _, memoItem := range tx.Memos {
+		if address, memo, ok := decodeXRPLBridgeMemo(memoItem.Memo.MemoData, c.BridgeChainIndex); ok {
+			return address, memo, true
+		}
+	}
+
+	return "", "", false
+}
+
+// DecodeCoreumMemo decodes the colon-delimited coreum memo.
+func (c ColonMemoCodec) DecodeCoreumMemo(memo string) (CoreumBridgeMemo, error) {
+	return ParseCoreumBridgeMemo(memo)
+}
+
+// xrplJSONMemo is the xrpl memo of the JSON memo codec.
+type xrplJSONMemo struct {
+	Address    string `json:"address"`
+	ChainIndex string `json:"chainIndex"`
+}
+
+// coreumJSONMemo is the coreum memo of the JSON memo codec.
+type coreumJSONMemo struct {
+	SourceChainID string `json:"sourceChainId"`
+	TxHash        string `json:"txHash"`
+	LogIndex      *int64 `json:"logIndex"`
+}
+
+// JSONMemoCodec decodes the {"address":...,"chainIndex":...} xrpl memo and the
+// {"sourceChainId":...,"txHash":...,"logIndex":...} coreum memo.
+type JSONMemoCodec struct {
+	BridgeChainIndex string
+}
+
+// DecodeXrplMemo decodes the first JSON memo with the bridge chain index.
+func (c JSONMemoCodec) DecodeXrplMemo(tx xrplTransaction) (string, string, bool) {
+	for _, memoItem := range tx.Memos {
+		if address, memo, ok := decodeXRPLJSONBridgeMemo(memoItem.Memo.MemoData, c.BridgeChainIndex); ok {
+			return address, memo, true
+		}
+	}
+
+	return "", "", false
+}
+
+// DecodeCoreumMemo decodes the JSON coreum memo.
+func (c JSONMemoCodec) DecodeCoreumMemo(memo string) (CoreumBridgeMemo, error) {
+	return parseCoreumJSONBridgeMemo(memo)
+}
+
+// TypedMemoCodec decodes the xrpl memos with the MemoType and the optional MemoFormat only, the memo data is decoded
+// as JSON if the MemoFormat is application/json and as colon-delimited otherwise. The coreum memo is decoded by the
+// CoreumMemoFormat independently of the xrpl one, since the coreum memos are written by the bridge.
+type TypedMemoCodec struct {
+	BridgeChainIndex string
+	MemoType         string
+	MemoFormat       string
+	CoreumMemoFormat string
+}
+
+// DecodeXrplMemo decodes the first memo of the type with the bridge chain index.
+func (c TypedMemoCodec) DecodeXrplMemo(tx xrplTransaction) (string, string, bool) {
+	for _, memoItem := range tx.Memos {
+		memoType, err := hex.DecodeString(memoItem.Memo.MemoType)
+		if err != nil || string(memoType) != c.MemoType {
+			continue
+		}
+		memoFormat, err := hex.DecodeString(memoItem.Memo.MemoFormat)
+		if err != nil || (c.MemoFormat != "" && string(memoFormat) != c.MemoFormat) {
+			continue
+		}
+		decode := decodeXRPLBridgeMemo
+		if string(memoFormat) == xrplMemoFormatJSON {
+			decode = decodeXRPLJSONBridgeMemo
+		}
+		if address, memo, ok := decode(memoItem.Memo.MemoData, c.BridgeChainIndex); ok {
+			return address, memo, true
+		}
+	}
+
+	return "", "", false
+}
+
+// DecodeCoreumMemo decodes the coreum memo of the configured coreum memo format.
+func (c TypedMemoCodec) DecodeCoreumMemo(memo string) (CoreumBridgeMemo, error) {
+	if c.CoreumMemoFormat == MemoCodecJSON {
+		return parseCoreumJSONBridgeMemo(memo)
+	}
+
+	return ParseCoreumBridgeMemo(memo)
+}
+
+// DestinationTagMemoCodec maps the xrpl destination tag to the coreum target address, the memos are ignored. The
+// coreum memo is colon-delimited.
+type DestinationTagMemoCodec struct {
+	DestinationTags map[uint32]string
+}
+
+// DecodeXrplMemo returns the address of the destination tag, the memo is the tag.
+func (c DestinationTagMemoCodec) DecodeXrplMemo(tx xrplTransaction) (string, string, bool) {
+	if tx.DestinationTag == nil {
+		return "", "", false
+	}
+	address, ok := c.DestinationTags[*tx.DestinationTag]
+	if !ok {
+		return "", "", false
+	}
+
+	return address, strconv.FormatUint(uint64(*tx.DestinationTag), 10), true
+}
+
+// DecodeCoreumMemo decodes the colon-delimited coreum memo.
+func (c DestinationTagMemoCodec) DecodeCoreumMemo(memo string) (CoreumBridgeMemo, error) {
+	return ParseCoreumBridgeMemo(memo)
+}
+
+// ParseCoreumBridgeMemo parses the colon-delimited coreum payout memo.
+func ParseCoreumBridgeMemo(memo string) (CoreumBridgeMemo, error) {
+	memoFragments := strings.Split(memo, ":")
+	if len(memoFragments) != 3 {
+		return CoreumBridgeMemo{}, errors.Errorf("invalid memo %q, expected 3 fragments", memo)
+	}
+	logIndex, err := strconv.ParseInt(memoFragments[2], 10, 64)
+	if err != nil {
+		return CoreumBridgeMemo{}, errors.Errorf("invalid memo %q, invalid log index %q", memo, memoFragments[2])
+	}
+
+	return newCoreumBridgeMemo(memo, memoFragments[0], memoFragments[1], logIndex)
+}
+
+func parseCoreumJSONBridgeMemo(memo string) (CoreumBridgeMemo, error) {
+	var jsonMemo coreumJSONMemo
+	if err := json.Unmarshal([]byte(memo), &jsonMemo); err != nil {
+		return CoreumBridgeMemo{}, errors.Errorf("invalid memo %q, err: %s", memo, err)
+	}
+	if jsonMemo.LogIndex == nil {
+		return CoreumBridgeMemo{}, errors.Errorf("invalid memo %q, empty log index", memo)
+	}
+
+	return newCoreumBridgeMemo(memo, jsonMemo.SourceChainID, jsonMemo.TxHash, *jsonMemo.LogIndex)
+}
+
+func newCoreumBridgeMemo(memo, sourceChainID, xrplTxHash string, logIndex int64) (CoreumBridgeMemo, error) {
+	xrplTxHash = strings.ToUpper(strings.ReplaceAll(xrplTxHash, "0x", ""))
+	if xrplTxHash == "" {
+		return CoreumBridgeMemo{}, errors.Errorf("invalid memo %q, empty xrpl tx hash", memo)
+	}
+	if logIndex < 0 {
+		return CoreumBridgeMemo{}, errors.Errorf("invalid memo %q, invalid log index %d", memo, logIndex)
+	}
+
+	return CoreumBridgeMemo{
+		SourceChainID: sourceChainID,
+		XrplTxHash:    xrplTxHash,
+		LogIndex:      logIndex,
+	}, nil
+}
+
+func decodeXRPLJSONBridgeMemo(hexMemo, bridgeChainIndex string) (string, string, bool) {
+	memo, err := hex.DecodeString(hexMemo)
+	if err != nil {
+		return "", "", false
+	}
+	var jsonMemo xrplJSONMemo
+	if err := json.Unmarshal(memo, &jsonMemo); err != nil {
+		return "", "", false
+	}
+	if jsonMemo.Address == "" || jsonMemo.ChainIndex != bridgeChainIndex {
+		return "", "", false
+	}
+
+	return jsonMemo.Address, string(memo), true
+}
+
+// decodeXrplTxHashFromCoreumMemo returns the xrpl tx hash of the coreum payout memo, or empty string if the memo is
+// invalid. The nil codec decodes the colon-delimited memo.
+func decodeXrplTxHashFromCoreumMemo(memoCodec MemoCodec, memo string) string {
+	bridgeMemo, err := memoCodecOrColon(memoCodec).DecodeCoreumMemo(memo)
+	if err != nil {
+		return ""
+	}
+
+	return bridgeMemo.XrplTxHash
+}
+
+// memoCodecOrColon returns the codec, or the colon one if it's nil.
+func memoCodecOrColon(memoCodec MemoCodec) MemoCodec {
+	if memoCodec == nil {
+		return ColonMemoCodec{}
+	}
+
+	return memoCodec
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCoreumBridgeMemo(t *testing.T) {
+	memo, err := ParseCoreumBridgeMemo("1000005788240:0xabcd:2")
+	require.NoError(t, err)
+	require.Equal(t, CoreumBridgeMemo{
+		SourceChainID: "1000005788240",
+		XrplTxHash:    "ABCD",
+		LogIndex:      2,
+	}, memo)
+
+	for _, invalidMemo := range []string{
+		"",
+		"invalid memo",
+		"1000005788240:0xABCD",
+		"1000005788240:0xABCD:0:0",
+		"1000005788240:0x:0",
+		"1000005788240:0xABCD:",
+		"1000005788240:0xABCD:one",
+		"1000005788240:0xABCD:-1",
+	} {
+		_, err := ParseCoreumBridgeMemo(invalidMemo)
+		require.Error(t, err, invalidMemo)
+	}
+}
+
+func TestNewMemoCodec(t *testing.T) {
+	memoCodec, err := NewMemoCodec(MemoCodecConfig{Name: MemoCodecColon}, defaultBridgeChainIndex)
+	require.NoError(t, err)
+	require.Equal(t, ColonMemoCodec{BridgeChainIndex: defaultBridgeChainIndex}, memoCodec)
+
+	_, err = NewMemoCodec(MemoCodecConfig{Name: "base64"}, defaultBridgeChainIndex)
+	require.ErrorContains(t, err, `invalid memo codec "base64"`)
+	_, err = NewMemoCodec(MemoCodecConfig{Name: MemoCodecTyped}, defaultBridgeChainIndex)
+	require.ErrorContains(t, err, "empty memo type")
+	_, err = NewMemoCodec(MemoCodecConfig{Name: MemoCodecDestinationTag}, defaultBridgeChainIndex)
+	require.ErrorContains(t, err, "empty destination tags")
+	_, err = NewMemoCodec(MemoCodecConfig{
+		Name:            MemoCodecDestinationTag,
+		DestinationTags: map[uint32]string{1: ""},
+	}, defaultBridgeChainIndex)
+	require.ErrorContains(t, err, "empty address of the destination tag 1")
+}
+
+func TestMemoCodecsDecodeXrplMemo(t *testing.T) {
+	address := fakeCoreumAddress(1)
+	colonMemo := address + ":" + defaultBridgeChainIndex
+	jsonMemo := `{"address":"` + address + `","chainIndex":"` + defaultBridgeChainIndex + `"}`
+	destinationTag := uint32(12345)
+	newTx := func(memos ...xrplMemoItem) xrplTransaction {
+		tx := xrplTransaction{DestinationTag: &destinationTag}
+		for _, memo := range memos {
+			tx.Memos = append(tx.Memos, xrplMemo{Memo: memo})
+		}
+		return tx
+	}
+	hexString := func(value string) string {
+		return hex.EncodeToString([]byte(value))
+	}
+
+	tests := []struct {
+		name        string
+		config      MemoCodecConfig
+		tx          xrplTransaction
+		wantAddress string
+		wantMemo    string
+	}{
+		{
+			name:        "colon",
+			config:      MemoCodecConfig{Name: MemoCodecColon},
+			tx:          newTx(xrplMemoItem{MemoData: hexString("other")}, xrplMemoItem{MemoData: hexString(colonMemo)}),
+			wantAddress: address,
+			wantMemo:    colonMemo,
+		},
+		{
+			name:   "colon_json_memo",
+			config: MemoCodecConfig{Name: MemoCodecColon},
+			tx:     newTx(xrplMemoItem{MemoData: hexString(jsonMemo)}),
+		},
+		{
+			name:        "json",
+			config:      MemoCodecConfig{Name: MemoCodecJSON},
+			tx:          newTx(xrplMemoItem{MemoData: hexString(jsonMemo)}),
+			wantAddress: address,
+			wantMemo:    jsonMemo,
+		},
+		{
+			name:   "json_other_chain_index",
+			config: MemoCodecConfig{Name: MemoCodecJSON},
+			tx:     newTx(xrplMemoItem{MemoData: hexString(`{"address":"` + address + `","chainIndex":"1"}`)}),
+		},
+		{
+			name:   "typed",
+			config: MemoCodecConfig{Name: MemoCodecTyped, MemoType: "bridge"},
+			tx: newTx(
+				xrplMemoItem{MemoData: hexString(fakeCoreumAddress(2) + ":" + defaultBridgeChainIndex), MemoType: hexString("other")},
+				xrplMemoItem{MemoData: hexString(jsonMemo), MemoType: hexString("bridge"), MemoFormat: hexString(xrplMemoFormatJSON)},
+			),
+			wantAddress: address,
+			wantMemo:    jsonMemo,
+		},
+		{
+			name:   "typed_other_format",
+			config: MemoCodecConfig{Name: MemoCodecTyped, MemoType: "bridge", MemoFormat: "text/plain"},
+			tx: newTx(
+				xrplMemoItem{MemoData: hexString(jsonMemo), MemoType: hexString("bridge"), MemoFormat: hexString(xrplMemoFormatJSON)},
+			),
+		},
+		{
+			name: "destination_tag",
+			config: MemoCodecConfig{
+				Name:            MemoCodecDestinationTag,
+				DestinationTags: map[uint32]string{destinationTag: address},
+			},
+			tx:          newTx(),
+			wantAddress: address,
+			wantMemo:    "12345",
+		},
+		{
+			name: "destination_tag_unknown",
+			config: MemoCodecConfig{
+				Name:            MemoCodecDestinationTag,
+				DestinationTags: map[uint32]string{1: address},
+			},
+			tx: newTx(xrplMemoItem{MemoData: hexString(colonMemo)}),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			memoCodec, err := NewMemoCodec(tt.config, defaultBridgeChainIndex)
+			require.NoError(t, err)
+			gotAddress, gotMemo, ok := memoCodec.DecodeXrplMemo(tt.tx)
+			require.Equal(t, tt.wantAddress != "", ok)
+			require.Equal(t, tt.wantAddress, gotAddress)
+			require.Equal(t, tt.wantMemo, gotMemo)
+		})
+	}
+}
+
+func TestMemoCodecsDecodeCoreumMemo(t *testing.T) {
+	want := CoreumBridgeMemo{SourceChainID: defaultCoreumMemoSourceChainID, XrplTxHash: "ABCD", LogIndex: 1}
+	colonMemo := defaultCoreumMemoSourceChainID + ":0xabcd:1"
+	jsonMemo := `{"sourceChainId":"` + defaultCoreumMemoSourceChainID + `","txHash":"0xabcd","logIndex":1}`
+
+	memo, err := JSONMemoCodec{}.DecodeCoreumMemo(jsonMemo)
+	require.NoError(t, err)
+	require.Equal(t, want, memo)
+	_, err = JSONMemoCodec{}.DecodeCoreumMemo(colonMemo)
+	require.Error(t, err)
+	_, err = JSONMemoCodec{}.DecodeCoreumMemo(`{"sourceChainId":"1","txHash":"0xabcd"}`)
+	require.ErrorContains(t, err, "empty log index")
+
+	memo, err = TypedMemoCodec{CoreumMemoFormat: MemoCodecJSON}.DecodeCoreumMemo(jsonMemo)
+	require.NoError(t, err)
+	require.Equal(t, want, memo)
+	memo, err = TypedMemoCodec{}.DecodeCoreumMemo(colonMemo)
+	require.NoError(t, err)
+	require.Equal(t, want, memo)
+	// the coreum memo format doesn't depend on the xrpl one
+	memoCodec, err := NewMemoCodec(MemoCodecConfig{Name: MemoCodecTyped, MemoType: "bridge", MemoFormat: xrplMemoFormatJSON}, "")
+	require.NoError(t, err)
+	memo, err = memoCodec.DecodeCoreumMemo(colonMemo)
+	require.NoError(t, err)
+	require.Equal(t, want, memo)
+	memoCodec, err = NewMemoCodec(MemoCodecConfig{Name: MemoCodecTyped, MemoType: "bridge", CoreumMemoFormat: MemoCodecJSON}, "")
+	require.NoError(t, err)
+	memo, err = memoCodec.DecodeCoreumMemo(jsonMemo)
+	require.NoError(t, err)
+	require.Equal(t, want, memo)
+	_, err = NewMemoCodec(MemoCodecConfig{Name: MemoCodecTyped, MemoType: "bridge", CoreumMemoFormat: "xml"}, "")
+	require.ErrorContains(t, err, `invalid coreum memo format "xml"`)
+
+	memo, err = DestinationTagMemoCodec{}.DecodeCoreumMemo(colonMemo)
+	require.NoError(t, err)
+	require.Equal(t, want, memo)
+
+	// the nil codec decodes the colon-delimited memo
+	require.Equal(t, "ABCD", decodeXrplTxHashFromCoreumMemo(nil, colonMemo))
+	require.Equal(t, "ABCD", decodeXrplTxHashFromCoreumMemo(JSONMemoCodec{}, jsonMemo))
+}
+
+func TestReadXrplDestinationTags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "destination-tags.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`tags:
+  12345: `+fakeCoreumAddress(1)+`
+  67890: `+fakeCoreumAddress(2)+`
+`), 0o600))
+
+	tags, err := ReadXrplDestinationTags(path)
+	require.NoError(t, err)
+	require.Equal(t, map[uint32]string{
+		12345: fakeCoreumAddress(1),
+		67890: fakeCoreumAddress(2),
+	}, tags)
+
+	require.NoError(t, os.WriteFile(path, []byte("tags:\n  -1: core1\n"), 0o600))
+	_, err = ReadXrplDestinationTags(path)
+	require.ErrorContains(t, err, "can't decode destination tags")
+}
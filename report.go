@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+)
+
+const (
+	reportBundleManifestFileName       = "manifest.json"
+	reportBundleXrplIncomingFileName   = "incoming-on-xrpl.csv"
+	reportBundleCoreumOutgoingFileName = "outgoing-on-coreum.csv"
+	reportBundleCoreumIncomingFileName = "incoming-on-coreum.csv"
+	reportBundleDiscrepanciesFileName  = "discrepancies.csv"
+	reportBundleSummaryFileName        = "summary.txt"
+)
+
+// ReportBundleManifest describes the provenance of the report bundle files.
+type ReportBundleManifest struct {
+	CreatedAt       time.Time          `json:"createdAt"`
+	GitRevision     string             `json:"gitRevision"`
+	CoreumHeight    int64              `json:"coreumHeight"`
+	XrplLedgerIndex int64              `json:"xrplLedgerIndex"`
+	Config          ReportBundleConfig `json:"config"`
+	Files           []ReportBundleFile `json:"files"`
+}
+
+// ReportBundleConfig is the config the report bundle is produced with.
+type ReportBundleConfig struct {
+	Route                   string                  `json:"route,omitempty"`
+	BeforeDateTime          time.Time               `json:"beforeDateTime"`
+	AfterDateTime           time.Time               `json:"afterDateTime"`
+	Denom                   string                  `json:"denom"`
+	CoreumAccount           string                  `json:"coreumAccount"`
+	CoreumFoundationAccount string                  `json:"coreumFoundationAccount"`
+	XrplAccount             string                  `json:"xrplAccount"`
+	XrplCurrency            string                  `json:"xrplCurrency"`
+	XrplIssuer              string                  `json:"xrplIssuer"`
+	BridgeChainIndex        string                  `json:"bridgeChainIndex"`
+	CoreumMemoSourceChainID string                  `json:"coreumMemoSourceChainId,omitempty"`
+	MemoCodec               *MemoCodecConfig        `json:"memoCodec,omitempty"`
+	CoreumHeight            int64                   `json:"coreumHeight,omitempty"`    // the pinned height
+	XrplLedgerIndex         int64                   `json:"xrplLedgerIndex,omitempty"` // the pinned ledger index
+	XrplSupplySource        string                  `json:"xrplSupplySource,omitempty"`
+	IncludeAll              bool                    `json:"includeAll"`
+	AmountTolerance         AmountTolerance         `json:"amountTolerance"`
+	FeeSchedule             []ReportBundleFeeConfig `json:"feeSchedule"`
+	RouteFeeSchedule        []RouteFeeConfig        `json:"routeFeeSchedule,omitempty"`
+	AddressBook             []AddressBookEntry      `json:"addressBook,omitempty"`
+}
+
+// ReportBundleFeeConfig is the FeeConfig with the fee model described as text.
+type ReportBundleFeeConfig struct {
+	StartTime time.Time `json:"startTime"`
+	FeeModel  string    `json:"feeModel"`
+	MinAmount *big.Int  `json:"minAmount"`
+	MaxAmount *big.Int  `json:"maxAmount"`
+}
+
+// ReportBundleFile is the file of the report bundle with its hash.
+type ReportBundleFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// NewReportBundleConfig returns the part of the config which affects the report bundle.
+func NewReportBundleConfig(config Config) ReportBundleConfig {
+	feeSchedule := make([]ReportBundleFeeConfig, 0, len(config.FeeConfigs))
+	for _, feeConfig := range config.FeeConfigs {
+		feeSchedule = append(feeSchedule, ReportBundleFeeConfig{
+			StartTime: feeConfig.StartTime,
+			FeeModel:  describeFeeModel(feeConfig.FeeModel),
+			MinAmount: feeConfig.MinAmount,
+			MaxAmount: feeConfig.MaxAmount,
+		})
+	}
+
+	return ReportBundleConfig{
+		Route:                   config.Route,
+		BeforeDateTime:          config.BeforeDateTime,
+		AfterDateTime:           config.AfterDateTime,
+		Denom:                   config.Denom,
+		CoreumAccount:           config.CoreumAccount,
+		CoreumFoundationAccount: config.CoreumFoundationAccount,
+		XrplAccount:             config.XrplAccount,
+		XrplCurrency:            config.XrplCurrency,
+		XrplIssuer:              config.XrplIssuer,
+		BridgeChainIndex:        config.BridgeChainIndex,
+		CoreumMemoSourceChainID: config.CoreumMemoSourceChainID,
+		MemoCodec:               &config.MemoCodec,
+		CoreumHeight:            config.CoreumHeight,
+		XrplLedgerIndex:         config.XrplLedgerIndex,
+		XrplSupplySource:        config.XrplSupplySource,
+		IncludeAll:              config.IncludeAll,
+		AmountTolerance:         config.AmountTolerance,
+		FeeSchedule:             feeSchedule,
+		RouteFeeSchedule:        config.RouteFeeSchedule,
+		AddressBook:             config.AddressBookEntries,
+	}
+}
+
+// Apply returns the config with the values of the bundle config. The fee schedule of the route or the fee schedule file
+// is restored, the default one isn't applied.
+func (c ReportBundleConfig) Apply(config Config) (Config, error) {
+	config.Route = c.Route
+	if len(c.RouteFeeSchedule) > 0 {
+		feeConfigs, err := convertRouteFeeSchedule(c.RouteFeeSchedule)
+		if err != nil {
+			return Config{}, errors.Errorf("invalid fee schedule of the route %s, err: %s", c.Route, err)
+		}
+		config.FeeConfigs = feeConfigs
+		config.RouteFeeSchedule = c.RouteFeeSchedule
+	}
+	config.BeforeDateTime = c.BeforeDateTime
+	config.AfterDateTime = c.AfterDateTime
+	config.Denom = c.Denom
+	config.CoreumAccount = c.CoreumAccount
+	config.CoreumFoundationAccount = c.CoreumFoundationAccount
+	config.XrplAccount = c.XrplAccount
+	config.XrplCurrency = c.XrplCurrency
+	config.XrplIssuer = c.XrplIssuer
+	config.BridgeChainIndex = c.BridgeChainIndex
+	// the bundles written before the memo source chain validation keep the configured one
+	if c.CoreumMemoSourceChainID != "" {
+		config.CoreumMemoSourceChainID = c.CoreumMemoSourceChainID
+	}
+	// the bundles written before the memo codecs keep the configured one
+	if c.MemoCodec != nil {
+		config.MemoCodec = *c.MemoCodec
+	}
+	config.CoreumHeight = c.CoreumHeight
+	config.XrplLedgerIndex = c.XrplLedgerIndex
+	// the bundles written before the supply source was added keep the configured one
+	if c.XrplSupplySource != "" {
+		config.XrplSupplySource = c.XrplSupplySource
+	}
+	config.IncludeAll = c.IncludeAll
+	config.AmountTolerance = c.AmountTolerance
+	// the bundles written without the address book keep the configured one
+	if len(c.AddressBook) > 0 {
+		config.AddressBookEntries = c.AddressBook
+	}
+
+	return config, nil
+}
+
+// WriteReportBundle fetches all the exports, writes them to the dir with the manifest and returns the manifest.
+func WriteReportBundle(ctx context.Context, config Config, httpClient *HTTPClient, dir string) (ReportBundleManifest, error) {
+	log := logger.Get(ctx)
+	if err := os.MkdirAll(dir, fs.ModePerm); err != nil {
+		return ReportBundleManifest{}, errors.Errorf("can't create dir, path: %s, err: %s", dir, err)
+	}
+
+	clientCtx := createClientContext(config, httpClient)
+	coreumHeight := config.CoreumHeight
+	if coreumHeight == 0 {
+		var err error
+		if coreumHeight, err = GetCoreumLatestHeight(ctx, clientCtx); err != nil {
+			return ReportBundleManifest{}, err
+		}
+	}
+	xrplLedgerIndex := config.XrplLedgerIndex
+	if xrplLedgerIndex == 0 {
+		var err error
+		if xrplLedgerIndex, err = GetXrplValidatedLedgerIndex(ctx, httpClient, config.XrplRPCAPIURLs[0]); err != nil {
+			return ReportBundleManifest{}, err
+		}
+	}
+	// all the data is fetched at the resolved heights, so the bundle is re-fetched with the same data, xrpscan doesn't
+	// support the ledger index, so the pinned supply is read from rippled
+	config.CoreumHeight = coreumHeight
+	config.XrplLedgerIndex = xrplLedgerIndex
+	config.XrplSupplySource = XrplSupplySourceRippled
+	log.Info(fmt.Sprintf("Writing report bundle at coreum height %d and xrpl ledger %d", coreumHeight, xrplLedgerIndex))
+	labels := NewAddressLabels(config.AddressBookEntries)
+
+	// the full history is fetched once, so all the files are built from the same txs
+	xrplIncomingAuditTxs, coreumOutgoingAuditTxs, err := fetchBridgeAuditTxs(ctx, config, httpClient)
+	if err != nil {
+		return ReportBundleManifest{}, err
+	}
+	coreumIncomingAuditTxs, err := fetchCoreumIncomingAuditTxs(ctx, config, httpClient)
+	if err != nil {
+		return ReportBundleManifest{}, err
+	}
+
+	for _, export := range []struct {
+		fileName string
+		txs      []AuditTx
+	}{
+		{fileName: reportBundleXrplIncomingFileName, txs: xrplIncomingAuditTxs},
+		{fileName: reportBundleCoreumOutgoingFileName, txs: coreumOutgoingAuditTxs},
+		{fileName: reportBundleCoreumIncomingFileName, txs: coreumIncomingAuditTxs},
+	} {
+		// the exports contain the txs of the time window only
+		windowTxs := filterAuditTxsByTime(export.txs, config.BeforeDateTime, config.AfterDateTime)
+		if err := WriteAuditTxsToCSV(windowTxs, labels, filepath.Join(dir, export.fileName)); err != nil {
+			return ReportBundleManifest{}, err
+		}
+	}
+
+	discrepancies, err := buildTxDiscrepancies(
+		ctx, config, xrplIncomingAuditTxs, coreumOutgoingAuditTxs, config.IncludeAll,
+	)
+	if err != nil {
+		return ReportBundleManifest{}, err
+	}
+	if err := WriteTxsDiscrepancyToCSV(discrepancies, labels, filepath.Join(dir, reportBundleDiscrepanciesFileName)); err != nil {
+		return ReportBundleManifest{}, err
+	}
+
+	summary, err := buildFetchedSummary(
+		ctx, config, httpClient, xrplIncomingAuditTxs, coreumOutgoingAuditTxs, coreumIncomingAuditTxs,
+	)
+	if err != nil {
+		return ReportBundleManifest{}, err
+	}
+	summaryPath := filepath.Join(dir, reportBundleSummaryFileName)
+	if err := os.WriteFile(summaryPath, []byte(summary.String()+"\n"), 0o600); err != nil {
+		return ReportBundleManifest{}, errors.Errorf("can't write file, path: %s, err: %s", summaryPath, err)
+	}
+
+	manifest := ReportBundleManifest{
+		CreatedAt:       config.Now,
+		GitRevision:     gitRevision(),
+		CoreumHeight:    coreumHeight,
+		XrplLedgerIndex: xrplLedgerIndex,
+		Config:          NewReportBundleConfig(config),
+	}
+	for _, fileName := range reportBundleFileNames() {
+		hash, err := fileSHA256(filepath.Join(dir, fileName))
+		if err != nil {
+			return ReportBundleManifest{}, err
+		}
+		manifest.Files = append(manifest.Files, ReportBundleFile{Name: fileName, SHA256: hash})
+	}
+	if err := writeJSONFile(filepath.Join(dir, reportBundleManifestFileName), manifest); err != nil {
+		return ReportBundleManifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// ReadReportBundleManifest reads the manifest from the report bundle dir.
+func ReadReportBundleManifest(dir string) (ReportBundleManifest, error) {
+	var manifest ReportBundleManifest
+	if err := readJSONFile(filepath.Join(dir, reportBundleManifestFileName), &manifest); err != nil {
+		return ReportBundleManifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// VerifyReportBundleFiles re-computes the hashes of the bundle files and returns the mismatches with the manifest.
+func VerifyReportBundleFiles(dir string, manifest ReportBundleManifest) []error {
+	mismatches := make([]error, 0)
+	for _, file := range manifest.Files {
+		hash, err := fileSHA256(filepath.Join(dir, file.Name))
+		if err != nil {
+			mismatches = append(mismatches, err)
+			continue
+		}
+		if hash != file.SHA256 {
+			mismatches = append(mismatches, errors.Errorf("%s hash mismatch, manifest: %s, file: %s", file.Name, file.SHA256, hash))
+		}
+	}
+
+	return mismatches
+}
+
+// CompareReportBundleManifests compares the config and the file hashes of the re-fetched bundle with the original one
+// and returns the mismatches.
+func CompareReportBundleManifests(manifest, refetchedManifest ReportBundleManifest) []error {
+	mismatches := make([]error, 0)
+	if !reflect.DeepEqual(manifest.Config.FeeSchedule, refetchedManifest.Config.FeeSchedule) {
+		mismatches = append(mismatches, errors.New("fee schedule of the bundle differs from the current one"))
+	}
+
+	refetchedHashes := make(map[string]string)
+	for _, file := range refetchedManifest.Files {
+		refetchedHashes[file.Name] = file.SHA256
+	}
+	for _, file := range manifest.Files {
+		refetchedHash, ok := refetchedHashes[file.Name]
+		if !ok {
+			mismatches = append(mismatches, errors.Errorf("%s isn't re-fetched", file.Name))
+			continue
+		}
+		if refetchedHash != file.SHA256 {
+			mismatches = append(mismatches, errors.Errorf("%s re-fetched data differs, manifest: %s, re-fetched: %s", file.Name, file.SHA256, refetchedHash))
+		}
+	}
+
+	return mismatches
+}
+
+// describeFeeModel returns the type and the JSON of the fee model.
+func describeFeeModel(model FeeModel) string {
+	data, err := json.Marshal(model)
+	if err != nil {
+		return fmt.Sprintf("%T%v", model, model)
+	}
+
+	return fmt.Sprintf("%T%s", model, data)
+}
+
+func reportBundleFileNames() []string {
+	return []string{
+		reportBundleXrplIncomingFileName,
+		reportBundleCoreumOutgoingFileName,
+		reportBundleCoreumIncomingFileName,
+		reportBundleDiscrepanciesFileName,
+		reportBundleSummaryFileName,
+	}
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.Errorf("can't open file, path: %s, err: %s", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", errors.Errorf("can't read file, path: %s, err: %s", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// gitRevision returns the git revision the tool is built from, the "-dirty" suffix is added if the tree is modified.
+func gitRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var (
+		revision string
+		modified bool
+	)
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+
+	return revision
+}
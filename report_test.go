@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReportBundleConfig(t *testing.T) {
+	bundleConfig := NewReportBundleConfig(Config{
+		BridgeChainIndex: "1111",
+		FeeConfigs: []FeeConfig{
+			{
+				StartTime: time.Date(2023, time.Month(3), 24, 17, 0, 0, 0, time.UTC),
+				FeeModel:  NewPerMilleFeeModel(big.NewInt(1), big.NewInt(2_400000), big.NewInt(477_000000)),
+				MinAmount: big.NewInt(4_800000),
+				MaxAmount: big.NewInt(2_400_000_000000),
+			},
+			{
+				StartTime: time.Date(2023, time.Month(3), 17, 13, 0, 0, 0, time.UTC),
+				FeeModel:  FixedFeeModel{Amount: big.NewInt(1)},
+			},
+		},
+	})
+	require.Equal(t, []ReportBundleFeeConfig{
+		{
+			StartTime: time.Date(2023, time.Month(3), 24, 17, 0, 0, 0, time.UTC),
+			FeeModel:  `main.RatioFeeModel{"Ratio":1,"Denominator":1000,"MinFee":2400000,"MaxFee":477000000}`,
+			MinAmount: big.NewInt(4_800000),
+			MaxAmount: big.NewInt(2_400_000_000000),
+		},
+		{
+			StartTime: time.Date(2023, time.Month(3), 17, 13, 0, 0, 0, time.UTC),
+			FeeModel:  `main.FixedFeeModel{"Amount":1}`,
+		},
+	}, bundleConfig.FeeSchedule)
+	config, err := bundleConfig.Apply(Config{})
+	require.NoError(t, err)
+	require.Equal(t, "1111", config.BridgeChainIndex)
+
+	// the route and its fee schedule are restored
+	routeFeeSchedule := []RouteFeeConfig{
+		{StartTime: time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC), FeeModel: RouteFeeModelFixed, Fee: 1000},
+	}
+	bundleConfig = NewReportBundleConfig(Config{Route: "xrpl-coreum-token", RouteFeeSchedule: routeFeeSchedule})
+	config, err = bundleConfig.Apply(Config{FeeConfigs: []FeeConfig{{}}})
+	require.NoError(t, err)
+	require.Equal(t, "xrpl-coreum-token", config.Route)
+	require.Equal(t, routeFeeSchedule, config.RouteFeeSchedule)
+	require.Equal(t, []FeeConfig{
+		{StartTime: time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC), FeeModel: FixedFeeModel{Amount: big.NewInt(1000)}},
+	}, config.FeeConfigs)
+}
+
+func TestVerifyReportBundleFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.csv"), []byte("data"), 0o600))
+	manifest := ReportBundleManifest{
+		Files: []ReportBundleFile{
+			{Name: "file.csv", SHA256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"},
+		},
+	}
+	require.Empty(t, VerifyReportBundleFiles(dir, manifest))
+
+	manifest.Files = append(manifest.Files, ReportBundleFile{Name: "missing.csv"})
+	manifest.Files[0].SHA256 = "0"
+	mismatches := VerifyReportBundleFiles(dir, manifest)
+	require.Len(t, mismatches, 2)
+	require.ErrorContains(t, mismatches[0], "file.csv hash mismatch")
+	require.ErrorContains(t, mismatches[1], "can't open file")
+
+	refetchedManifest := manifest
+	refetchedManifest.Files = []ReportBundleFile{{Name: "file.csv", SHA256: "1"}}
+	mismatches = CompareReportBundleManifests(manifest, refetchedManifest)
+	require.Len(t, mismatches, 2)
+	require.ErrorContains(t, mismatches[0], "file.csv re-fetched data differs")
+	require.ErrorContains(t, mismatches[1], "missing.csv isn't re-fetched")
+}
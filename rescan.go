@@ -1,21 +1,32 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gammazero/workerpool"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
 )
 
 const (
-	multichainRescanRequestTimeout   = 10 * time.Second
 	multichainRescanResStatusSuccess = "Success"
-	multichainRescanRetries          = 10
-	multichainRescanRetryTimout      = time.Second
+)
+
+// rescan statuses.
+const (
+	RescanStatusSubmitted = "submitted"
+	RescanStatusFailed    = "failed"
+	RescanStatusDryRun    = "dry-run"
 )
 
 type multichainRescanResp struct {
@@ -23,39 +34,181 @@ type multichainRescanResp struct {
 	Error string `json:"error"`
 }
 
-func RescanMultichainTxs(ctx context.Context, baseURL string, txHashes []string) error {
+// RescanResult is the result of the tx submission to the multichain rescan.
+type RescanResult struct {
+	Hash        string
+	Status      string
+	LastMessage string
+	Attempts    int
+	Timestamp   time.Time
+}
+
+// RescanMultichainTxs submits the txs to the multichain rescan in parallel. The failed submissions don't stop the
+// others, the result of each submission is returned in the order of the provided hashes.
+func RescanMultichainTxs(
+	ctx context.Context,
+	httpClient *HTTPClient,
+	baseURL, srcChainID, destChainID string,
+	txHashes []string,
+	poolSize int,
+	dryRun bool,
+) []RescanResult {
 	log := logger.Get(ctx)
-	log.Info(fmt.Sprintf("Rescanning %d xrpl txs", len(txHashes)))
-	for _, txHash := range txHashes {
-		log.Info(fmt.Sprintf("Rescanning %q xrpl tx", txHash))
-		err := rescanMultichainTxWithRetry(ctx, baseURL, txHash)
-		if err != nil {
-			return err
+	log.Info(fmt.Sprintf("Rescanning %d txs", len(txHashes)))
+
+	results := make([]RescanResult, len(txHashes))
+	if dryRun {
+		for i, txHash := range txHashes {
+			log.Info(fmt.Sprintf("Dry run, skipping %q tx", txHash))
+			results[i] = RescanResult{
+				Hash:      txHash,
+				Status:    RescanStatusDryRun,
+				Timestamp: time.Now().UTC(),
+			}
+		}
+		return results
+	}
+
+	// allocate limited pool to submit txs in parallel
+	workerPool := workerpool.New(poolSize)
+	defer workerPool.Stop()
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(txHashes))
+	for i, txHash := range txHashes {
+		i, txHash := i, txHash
+		workerPool.Submit(func() {
+			defer wg.Done()
+			log.Info(fmt.Sprintf("Rescanning %q tx", txHash))
+			attempts, lastMessage, err := rescanMultichainTx(ctx, httpClient, baseURL, srcChainID, destChainID, txHash)
+			result := RescanResult{
+				Hash:        txHash,
+				Status:      RescanStatusSubmitted,
+				LastMessage: lastMessage,
+				Attempts:    attempts,
+				Timestamp:   time.Now().UTC(),
+			}
+			if err != nil {
+				log.Error("Can't rescan tx", zap.String("Hash", txHash), zap.Error(err))
+				result.Status = RescanStatusFailed
+				result.LastMessage = err.Error()
+			}
+			// each worker writes to its own index, so no lock is required
+			results[i] = result
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+// MergeRescanResults updates the previous results with the new ones, the attempts are accumulated. The dry-run
+// results don't replace the previous ones, so the failed txs can be rescanned after the dry run.
+func MergeRescanResults(previousResults, results []RescanResult) []RescanResult {
+	indexes := make(map[string]int, len(previousResults))
+	mergedResults := make([]RescanResult, 0, len(previousResults)+len(results))
+	for _, result := range previousResults {
+		indexes[result.Hash] = len(mergedResults)
+		mergedResults = append(mergedResults, result)
+	}
+	for _, result := range results {
+		i, ok := indexes[result.Hash]
+		if !ok {
+			indexes[result.Hash] = len(mergedResults)
+			mergedResults = append(mergedResults, result)
+			continue
+		}
+		if result.Status == RescanStatusDryRun {
+			continue
 		}
+		result.Attempts += mergedResults[i].Attempts
+		mergedResults[i] = result
 	}
 
-	return nil
+	return mergedResults
 }
 
-func rescanMultichainTxWithRetry(ctx context.Context, baseURL, txHash string) error {
-	url := fmt.Sprintf("%s/v2/reswaptxns?hash=%s&srcChainID=XRP&destChainID=ATOM_DCORE", baseURL, txHash)
+// SelectRescanTxHashes returns the unique xrpl tx hashes of the discrepancies of the provided kinds. The multichain
+// rescans the source xrpl txs only, so the xrpl tx hash of the coreum-only discrepancy is decoded from the coreum memo,
+// and the discrepancy is skipped if the memo is invalid.
+func SelectRescanTxHashes(discrepancies []TxDiscrepancy, kinds []string, memoCodec MemoCodec) []string {
+	kindsSet := make(map[string]struct{}, len(kinds))
+	for _, kind := range kinds {
+		kindsSet[kind] = struct{}{}
+	}
+
+	txHashes := make([]string, 0)
+	selectedTxHashes := make(map[string]struct{})
+	for _, discrepancy := range discrepancies {
+		if _, ok := kindsSet[discrepancy.Discrepancy]; !ok {
+			continue
+		}
+		txHash := discrepancy.XrplTx.Hash
+		if txHash == "" {
+			txHash = decodeXrplTxHashFromCoreumMemo(memoCodec, discrepancy.CoreumTx.Memo)
+		}
+		if _, ok := selectedTxHashes[txHash]; ok || txHash == "" {
+			continue
+		}
+		selectedTxHashes[txHash] = struct{}{}
+		txHashes = append(txHashes, txHash)
+	}
+
+	return txHashes
+}
+
+// ReadTxHashes reads the tx hashes, one per line. The empty lines and lines starting with # are skipped.
+func ReadTxHashes(reader io.Reader) ([]string, error) {
+	txHashes := make([]string, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		txHashes = append(txHashes, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Errorf("can't read tx hashes, err: %s", err)
+	}
+
+	return txHashes, nil
+}
+
+// rescanMultichainTx submits the tx to the multichain rescan and returns the number of attempts and the last
+// message returned by the multichain.
+func rescanMultichainTx(
+	ctx context.Context,
+	httpClient *HTTPClient,
+	baseURL, srcChainID, destChainID, txHash string,
+) (int, string, error) {
+	query := url.Values{}
+	query.Set("hash", txHash)
+	query.Set("srcChainID", srcChainID)
+	query.Set("destChainID", destChainID)
+	reqURL := fmt.Sprintf("%s/v2/reswaptxns?%s", baseURL, query.Encode())
 
 	var (
-		resBody multichainRescanResp
-		err     error
+		attempts    int
+		lastMessage string
 	)
-	for i := 0; i < multichainRescanRetries; i++ {
-		reqCtx, reqCtxCancel := context.WithTimeout(ctx, multichainRescanRequestTimeout)
-		err = DoJSON(reqCtx, http.MethodGet, url, nil, &resBody)
-		reqCtxCancel()
-		if err == nil && resBody.Msg == multichainRescanResStatusSuccess {
-			return nil
+	// the transport errors are retried by the client, here we retry the not successful rescan responses
+	err := httpClient.RetryPolicy().Do(ctx, func(attempt int) error {
+		attempts = attempt + 1
+		var resBody multichainRescanResp
+		if err := httpClient.DoJSON(ctx, http.MethodGet, reqURL, nil, &resBody); err != nil {
+			return Permanent(err)
 		}
-		if i != multichainRescanRetries-1 {
-			<-time.After(multichainRescanRetryTimout)
+		lastMessage = resBody.Msg
+		if resBody.Msg != multichainRescanResStatusSuccess {
+			return errors.Errorf("unexpected rescan response: %v", resBody)
 		}
+
+		return nil
+	})
+	if err != nil {
+		return attempts, lastMessage, errors.Wrapf(err, "can't send %s tx to rescan", txHash)
 	}
 
-	return errors.Errorf("can't send %s tx to rescan with %d retries and timeout %s, last response: %v, last err: %s",
-		txHash, multichainRescanRetries, multichainRescanRetryTimout, resBody, err)
+	return attempts, lastMessage, nil
 }
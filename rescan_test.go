@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+)
+
+func TestRescanMultichainTxs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/reswaptxns", r.URL.Path)
+		require.Equal(t, "XRP", r.URL.Query().Get("srcChainID"))
+		require.Equal(t, "ATOM_DCORE", r.URL.Query().Get("destChainID"))
+		if r.URL.Query().Get("hash") == "failingHash" {
+			_, _ = w.Write([]byte(`{"msg":"Error","error":"tx not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"msg":"Success"}`))
+	}))
+	defer server.Close()
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	httpClient := NewHTTPClient(HTTPConfig{
+		MaxRetries: 1,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	})
+
+	results := RescanMultichainTxs(
+		ctx, httpClient, server.URL, "XRP", "ATOM_DCORE", []string{"hash1", "failingHash", "hash2"}, 2, false,
+	)
+	require.Len(t, results, 3)
+	require.Equal(t, "hash1", results[0].Hash)
+	require.Equal(t, RescanStatusSubmitted, results[0].Status)
+	require.Equal(t, 1, results[0].Attempts)
+	require.Equal(t, "failingHash", results[1].Hash)
+	require.Equal(t, RescanStatusFailed, results[1].Status)
+	require.Equal(t, 2, results[1].Attempts)
+	require.Equal(t, "hash2", results[2].Hash)
+	require.Equal(t, RescanStatusSubmitted, results[2].Status)
+
+	results = RescanMultichainTxs(ctx, httpClient, server.URL, "XRP", "ATOM_DCORE", []string{"hash1"}, 2, true)
+	require.Len(t, results, 1)
+	require.Equal(t, RescanStatusDryRun, results[0].Status)
+	require.Zero(t, results[0].Attempts)
+}
+
+func TestMergeRescanResults(t *testing.T) {
+	previousResults := []RescanResult{
+		{Hash: "hash1", Status: RescanStatusSubmitted, Attempts: 1},
+		{Hash: "hash2", Status: RescanStatusFailed, Attempts: 3},
+	}
+	results := []RescanResult{
+		{Hash: "hash2", Status: RescanStatusSubmitted, Attempts: 1},
+		{Hash: "hash3", Status: RescanStatusFailed, Attempts: 2},
+	}
+
+	require.Equal(t, []RescanResult{
+		{Hash: "hash1", Status: RescanStatusSubmitted, Attempts: 1},
+		{Hash: "hash2", Status: RescanStatusSubmitted, Attempts: 4},
+		{Hash: "hash3", Status: RescanStatusFailed, Attempts: 2},
+	}, MergeRescanResults(previousResults, results))
+
+	// the dry run keeps the previous statuses
+	require.Equal(t, []RescanResult{
+		{Hash: "hash1", Status: RescanStatusSubmitted, Attempts: 1},
+		{Hash: "hash2", Status: RescanStatusFailed, Attempts: 3},
+		{Hash: "hash3", Status: RescanStatusDryRun},
+	}, MergeRescanResults(previousResults, []RescanResult{
+		{Hash: "hash2", Status: RescanStatusDryRun},
+		{Hash: "hash3", Status: RescanStatusDryRun},
+	}))
+}
+
+func TestSelectRescanTxHashes(t *testing.T) {
+	discrepancies := []TxDiscrepancy{
+		{XrplTx: AuditTx{Hash: "xrplHash1"}, Discrepancy: DiscrepancyOrphanXrplTx},
+		{XrplTx: AuditTx{Hash: "xrplHash2"}, CoreumTx: AuditTx{Hash: "coreumHash2"}, Discrepancy: DiscrepancyDifferentAmountOnXrplAndCoreum},
+		// the xrpl tx hash of the coreum-only discrepancy is taken from the memo
+		{CoreumTx: AuditTx{Hash: "coreumHash3", Memo: "1:0xabcd3:1"}, Discrepancy: DiscrepancyOrphanCoreumTx},
+		{CoreumTx: AuditTx{Hash: "coreumHash5", Memo: "invalid"}, Discrepancy: DiscrepancyInvalidMemoOnCoreum},
+		{XrplTx: AuditTx{Hash: "xrplHash1"}, Discrepancy: DiscrepancyOrphanXrplTx},
+		{XrplTx: AuditTx{Hash: "xrplHash4"}, CoreumTx: AuditTx{Hash: "coreumHash4"}},
+	}
+
+	require.Equal(t, []string{"xrplHash1"}, SelectRescanTxHashes(discrepancies, []string{DiscrepancyOrphanXrplTx}, nil))
+	require.Equal(t, []string{"xrplHash2", "ABCD3"}, SelectRescanTxHashes(
+		discrepancies, []string{DiscrepancyDifferentAmountOnXrplAndCoreum, DiscrepancyOrphanCoreumTx}, nil,
+	))
+	// the coreum tx hash isn't rescanned
+	require.Empty(t, SelectRescanTxHashes(discrepancies, []string{DiscrepancyInvalidMemoOnCoreum}, nil))
+	require.Empty(t, SelectRescanTxHashes(discrepancies, nil, nil))
+
+	jsonMemoDiscrepancies := []TxDiscrepancy{
+		{CoreumTx: AuditTx{Hash: "coreumHash6", Memo: `{"sourceChainId":"1","txHash":"0xabcd6","logIndex":1}`}, Discrepancy: DiscrepancyOrphanCoreumTx},
+	}
+	require.Equal(t, []string{"ABCD6"}, SelectRescanTxHashes(
+		jsonMemoDiscrepancies, []string{DiscrepancyOrphanCoreumTx}, JSONMemoCodec{},
+	))
+}
+
+func TestReadTxHashes(t *testing.T) {
+	txHashes, err := ReadTxHashes(strings.NewReader("hash1\n\n  hash2  \n# comment\nhash3"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"hash1", "hash2", "hash3"}, txHashes)
+}
+
+func TestRescanResultsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rescan-results.csv")
+	results := []RescanResult{
+		{
+			Hash:        "hash1",
+			Status:      RescanStatusSubmitted,
+			LastMessage: "Success",
+			Attempts:    1,
+			Timestamp:   time.Date(2023, time.Month(7), 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			Hash:        "hash2",
+			Status:      RescanStatusFailed,
+			LastMessage: "can't send hash2 tx to rescan, err: a, b",
+			Attempts:    10,
+			Timestamp:   time.Date(2023, time.Month(7), 1, 12, 31, 0, 0, time.UTC),
+		},
+	}
+
+	require.NoError(t, WriteRescanResultsToCSV(results, path))
+	got, err := ReadRescanResultsFromCSV(path)
+	require.NoError(t, err)
+	require.Equal(t, results, got)
+}
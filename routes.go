@@ -0,0 +1,318 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// The fee models of the route fee schedule.
+const (
+	RouteFeeModelPerMille    = "per-mille"
+	RouteFeeModelBasisPoints = "basis-points"
+	RouteFeeModelFixed       = "fixed"
+	RouteFeeModelTiered      = "tiered"
+)
+
+var routeFeeModels = []string{
+	RouteFeeModelPerMille,
+	RouteFeeModelBasisPoints,
+	RouteFeeModelFixed,
+	RouteFeeModelTiered,
+}
+
+// routeFeeTierModels are the fee models of the tier of the tiered fee model.
+var routeFeeTierModels = []string{
+	RouteFeeModelPerMille,
+	RouteFeeModelBasisPoints,
+	RouteFeeModelFixed,
+}
+
+// Route is the named bridge pair of the routes file. The identity fields (the denom, accounts, xrpl currency, issuer
+// and bridge chain index) are required, since the flag defaults are the ones of the CORE bridge, the rest empty fields
+// keep the values of the flags.
+type Route struct {
+	Name                    string           `yaml:"name"`
+	Denom                   string           `yaml:"denom"`
+	CoreumAccount           string           `yaml:"coreumAccount"`
+	CoreumFoundationAccount string           `yaml:"coreumFoundationAccount"`
+	XrplAccount             string           `yaml:"xrplAccount"`
+	XrplCurrency            string           `yaml:"xrplCurrency"`
+	XrplIssuer              string           `yaml:"xrplIssuer"`
+	BridgeChainIndex        string           `yaml:"bridgeChainIndex"`
+	CoreumMemoSourceChainID string           `yaml:"coreumMemoSourceChainId"`
+	MemoCodec               *MemoCodecConfig `yaml:"memoCodec"`
+	FeeSchedule             []RouteFeeConfig `yaml:"feeSchedule"`
+	RescanSrcChainID        string           `yaml:"rescanSrcChainId"`
+	RescanDestChainID       string           `yaml:"rescanDestChainId"`
+}
+
+// RouteFeeConfig is the FeeConfig of the routes file, the amounts are in the smallest denomination. The Ratio, MinFee
+// and MaxFee are used by the ratio fee models, the Fee by the fixed one and the Tiers by the tiered one. The Destination
+// charges the fee of the model on the destination side.
+type RouteFeeConfig struct {
+	StartTime   time.Time      `json:"startTime" yaml:"startTime"`
+	FeeModel    string         `json:"feeModel" yaml:"feeModel"`
+	Ratio       int64          `json:"ratio,omitempty" yaml:"ratio"`
+	MinFee      *int64         `json:"minFee,omitempty" yaml:"minFee"`
+	MaxFee      *int64         `json:"maxFee,omitempty" yaml:"maxFee"`
+	Fee         int64          `json:"fee,omitempty" yaml:"fee"`
+	Tiers       []RouteFeeTier `json:"tiers,omitempty" yaml:"tiers"`
+	Destination bool           `json:"destination,omitempty" yaml:"destination"`
+	MinAmount   *int64         `json:"minAmount,omitempty" yaml:"minAmount"`
+	MaxAmount   *int64         `json:"maxAmount,omitempty" yaml:"maxAmount"`
+}
+
+// RouteFeeTier is the FeeTier of the routes file, the empty UpTo means the unbounded tier.
+type RouteFeeTier struct {
+	UpTo     *int64 `json:"upTo,omitempty" yaml:"upTo"`
+	FeeModel string `json:"feeModel" yaml:"feeModel"`
+	Ratio    int64  `json:"ratio,omitempty" yaml:"ratio"`
+	MinFee   *int64 `json:"minFee,omitempty" yaml:"minFee"`
+	MaxFee   *int64 `json:"maxFee,omitempty" yaml:"maxFee"`
+	Fee      int64  `json:"fee,omitempty" yaml:"fee"`
+}
+
+// ReadRoutes reads and validates the routes YAML file with the list of the routes.
+func ReadRoutes(path string) ([]Route, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("can't read file, path: %s, err: %s", path, err)
+	}
+	var file struct {
+		Routes []Route `yaml:"routes"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Errorf("can't decode routes, path: %s, err: %s", path, err)
+	}
+	if len(file.Routes) == 0 {
+		return nil, errors.Errorf("no routes in the file, path: %s", path)
+	}
+
+	names := make(map[string]struct{}, len(file.Routes))
+	for _, route := range file.Routes {
+		if route.Name == "" {
+			return nil, errors.Errorf("empty route name, path: %s", path)
+		}
+		if _, ok := names[route.Name]; ok {
+			return nil, errors.Errorf("duplicated route %s, path: %s", route.Name, path)
+		}
+		names[route.Name] = struct{}{}
+		for _, field := range []struct {
+			name  string
+			value string
+		}{
+			{name: "denom", value: route.Denom},
+			{name: "coreumAccount", value: route.CoreumAccount},
+			{name: "coreumFoundationAccount", value: route.CoreumFoundationAccount},
+			{name: "xrplAccount", value: route.XrplAccount},
+			{name: "xrplCurrency", value: route.XrplCurrency},
+			{name: "xrplIssuer", value: route.XrplIssuer},
+			{name: "bridgeChainIndex", value: route.BridgeChainIndex},
+		} {
+			if field.value == "" {
+				return nil, errors.Errorf("empty %s of the route %s, path: %s", field.name, route.Name, path)
+			}
+		}
+		if _, err := route.FeeConfigs(); err != nil {
+			return nil, errors.Errorf("invalid route %s, path: %s, err: %s", route.Name, path, err)
+		}
+	}
+
+	return file.Routes, nil
+}
+
+// ReadFeeSchedule reads and validates the YAML file with the fee schedule in the format of the route fee schedule.
+func ReadFeeSchedule(path string) ([]RouteFeeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("can't read file, path: %s, err: %s", path, err)
+	}
+	var file struct {
+		FeeSchedule []RouteFeeConfig `yaml:"feeSchedule"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Errorf("can't decode fee schedule, path: %s, err: %s", path, err)
+	}
+	if len(file.FeeSchedule) == 0 {
+		return nil, errors.Errorf("no fee configs in the file, path: %s", path)
+	}
+	if _, err := convertRouteFeeSchedule(file.FeeSchedule); err != nil {
+		return nil, errors.Errorf("invalid fee schedule, path: %s, err: %s", path, err)
+	}
+
+	return file.FeeSchedule, nil
+}
+
+// FindRoute returns the route with the name.
+func FindRoute(routes []Route, name string) (Route, error) {
+	route, ok := lo.Find(routes, func(route Route) bool { return route.Name == name })
+	if !ok {
+		return Route{}, errors.Errorf(
+			"route %s not found, must be one of %v", name, lo.Map(routes, func(route Route, _ int) string { return route.Name }),
+		)
+	}
+
+	return route, nil
+}
+
+// FeeConfigs returns the fee configs of the route fee schedule.
+func (r Route) FeeConfigs() ([]FeeConfig, error) {
+	return convertRouteFeeSchedule(r.FeeSchedule)
+}
+
+// convertRouteFeeSchedule converts the route fee schedule to the fee configs.
+func convertRouteFeeSchedule(feeSchedule []RouteFeeConfig) ([]FeeConfig, error) {
+	feeConfigs := make([]FeeConfig, 0, len(feeSchedule))
+	for _, routeFeeConfig := range feeSchedule {
+		feeModel, err := convertRouteFeeModel(routeFeeConfig)
+		if err != nil {
+			return nil, err
+		}
+		feeConfigs = append(feeConfigs, FeeConfig{
+			StartTime: routeFeeConfig.StartTime.UTC(),
+			FeeModel:  feeModel,
+			MinAmount: int64PtrToBigInt(routeFeeConfig.MinAmount),
+			MaxAmount: int64PtrToBigInt(routeFeeConfig.MaxAmount),
+		})
+	}
+
+	return feeConfigs, nil
+}
+
+// convertRouteFeeModel converts the fee model of the route fee config, the tiered and destination models are built by
+// their constructors to validate them.
+func convertRouteFeeModel(routeFeeConfig RouteFeeConfig) (FeeModel, error) {
+	if !lo.Contains(routeFeeModels, routeFeeConfig.FeeModel) {
+		return nil, errors.Errorf("invalid fee model %q, must be one of %v", routeFeeConfig.FeeModel, routeFeeModels)
+	}
+	if routeFeeConfig.FeeModel != RouteFeeModelTiered && len(routeFeeConfig.Tiers) > 0 {
+		return nil, errors.Errorf("fee tiers are set for the %s fee model", routeFeeConfig.FeeModel)
+	}
+
+	var feeModel FeeModel
+	switch routeFeeConfig.FeeModel {
+	case RouteFeeModelTiered:
+		feeTiers := make([]FeeTier, 0, len(routeFeeConfig.Tiers))
+		for i, routeFeeTier := range routeFeeConfig.Tiers {
+			tierFeeModel, err := convertRouteFeeTierModel(routeFeeTier)
+			if err != nil {
+				return nil, errors.Errorf("invalid fee tier %d, err: %s", i, err)
+			}
+			feeTiers = append(feeTiers, FeeTier{
+				UpTo:  int64PtrToBigInt(routeFeeTier.UpTo),
+				Model: tierFeeModel,
+			})
+		}
+		tieredFeeModel, err := NewTieredFeeModel(feeTiers...)
+		if err != nil {
+			return nil, err
+		}
+		feeModel = tieredFeeModel
+	default:
+		var err error
+		feeModel, err = convertRouteFeeTierModel(RouteFeeTier{
+			FeeModel: routeFeeConfig.FeeModel,
+			Ratio:    routeFeeConfig.Ratio,
+			MinFee:   routeFeeConfig.MinFee,
+			MaxFee:   routeFeeConfig.MaxFee,
+			Fee:      routeFeeConfig.Fee,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !routeFeeConfig.Destination {
+		return feeModel, nil
+	}
+
+	destinationFeeModel, err := NewDestinationFeeModel(feeModel)
+	if err != nil {
+		return nil, err
+	}
+
+	return destinationFeeModel, nil
+}
+
+// convertRouteFeeTierModel converts the non-tiered fee model.
+func convertRouteFeeTierModel(routeFeeTier RouteFeeTier) (FeeModel, error) {
+	switch routeFeeTier.FeeModel {
+	case RouteFeeModelPerMille:
+		return NewPerMilleFeeModel(
+			big.NewInt(routeFeeTier.Ratio), int64PtrToBigInt(routeFeeTier.MinFee), int64PtrToBigInt(routeFeeTier.MaxFee),
+		), nil
+	case RouteFeeModelBasisPoints:
+		return NewBasisPointsFeeModel(
+			big.NewInt(routeFeeTier.Ratio), int64PtrToBigInt(routeFeeTier.MinFee), int64PtrToBigInt(routeFeeTier.MaxFee),
+		), nil
+	case RouteFeeModelFixed:
+		return FixedFeeModel{Amount: big.NewInt(routeFeeTier.Fee)}, nil
+	default:
+		return nil, errors.Errorf("invalid fee model %q, must be one of %v", routeFeeTier.FeeModel, routeFeeTierModels)
+	}
+}
+
+// Apply returns the config with the non-empty values of the route, the route fee schedule is kept in the config to
+// record it in the report bundle.
+func (r Route) Apply(config Config) (Config, error) {
+	config.Route = r.Name
+	for _, field := range []struct {
+		value  string
+		target *string
+	}{
+		{value: r.Denom, target: &config.Denom},
+		{value: r.CoreumAccount, target: &config.CoreumAccount},
+		{value: r.CoreumFoundationAccount, target: &config.CoreumFoundationAccount},
+		{value: r.XrplAccount, target: &config.XrplAccount},
+		{value: r.XrplCurrency, target: &config.XrplCurrency},
+		{value: r.XrplIssuer, target: &config.XrplIssuer},
+		{value: r.BridgeChainIndex, target: &config.BridgeChainIndex},
+		{value: r.CoreumMemoSourceChainID, target: &config.CoreumMemoSourceChainID},
+		{value: r.RescanSrcChainID, target: &config.RescanSrcChainID},
+		{value: r.RescanDestChainID, target: &config.RescanDestChainID},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+	if r.MemoCodec != nil {
+		config.MemoCodec = *r.MemoCodec
+	}
+	if _, err := NewMemoCodec(config.MemoCodec, config.BridgeChainIndex); err != nil {
+		return Config{}, errors.Errorf("invalid route %s, err: %s", r.Name, err)
+	}
+	if len(r.FeeSchedule) > 0 {
+		feeConfigs, err := r.FeeConfigs()
+		if err != nil {
+			return Config{}, errors.Errorf("invalid route %s, err: %s", r.Name, err)
+		}
+		config.FeeConfigs = feeConfigs
+		config.RouteFeeSchedule = r.FeeSchedule
+	}
+
+	return config, nil
+}
+
+// routeFilePath returns the path of the per-route file, the route name is appended to the file name.
+func routeFilePath(path, routeName string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+
+	return strings.TrimSuffix(path, ext) + "-" + routeName + ext
+}
+
+func int64PtrToBigInt(value *int64) *big.Int {
+	if value == nil {
+		return nil
+	}
+
+	return big.NewInt(*value)
+}
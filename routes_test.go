@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testRouteIdentity returns the required identity fields of the route in the routes YAML.
+func testRouteIdentity(denom, currency, bridgeChainIndex string) string {
+	return fmt.Sprintf(`    denom: %s
+    coreumAccount: %s
+    coreumFoundationAccount: %s
+    xrplAccount: %s
+    xrplCurrency: %s
+    xrplIssuer: %s
+    bridgeChainIndex: "%s"
+`, denom, defaultCoreumAccount, defaultCoreumFoundationAccount, defaultXrplAccount, currency, defaultXrplIssuer, bridgeChainIndex)
+}
+
+func TestReadRoutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`routes:
+  - name: xrpl-coreum-core
+`+testRouteIdentity("ucore", defaultXrplCurrency, defaultBridgeChainIndex)+`  - name: xrpl-coreum-token
+`+testRouteIdentity("utoken", "544F4B454E000000000000000000000000000000", "1")+`    coreumMemoSourceChainId: "2"
+    rescanSrcChainId: XRP
+    rescanDestChainId: ATOM_DTOKEN
+    memoCodec:
+      name: json
+    feeSchedule:
+      - startTime: 2023-03-24T17:00:00Z
+        feeModel: per-mille
+        ratio: 1
+        minFee: 2400000
+        maxFee: 477000000
+        minAmount: 4800000
+      - startTime: 2023-02-01T00:00:00Z
+        feeModel: tiered
+        destination: true
+        tiers:
+          - upTo: 1000000
+            feeModel: fixed
+            fee: 1000
+          - feeModel: basis-points
+            ratio: 10
+      - startTime: 2023-01-01T00:00:00Z
+        feeModel: fixed
+        fee: 1000
+`), 0o600))
+
+	routes, err := ReadRoutes(path)
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+
+	route, err := FindRoute(routes, "xrpl-coreum-token")
+	require.NoError(t, err)
+	config, err := route.Apply(Config{
+		Denom:            "ucore",
+		XrplAccount:      "rOtherAccount",
+		XrplCurrency:     defaultXrplCurrency,
+		BridgeChainIndex: defaultBridgeChainIndex,
+		MemoCodec:        MemoCodecConfig{Name: MemoCodecColon},
+		FeeConfigs:       []FeeConfig{{}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, Config{
+		Route:                   "xrpl-coreum-token",
+		Denom:                   "utoken",
+		CoreumAccount:           defaultCoreumAccount,
+		CoreumFoundationAccount: defaultCoreumFoundationAccount,
+		XrplAccount:             defaultXrplAccount,
+		XrplCurrency:            "544F4B454E000000000000000000000000000000",
+		XrplIssuer:              defaultXrplIssuer,
+		BridgeChainIndex:        "1",
+		CoreumMemoSourceChainID: "2",
+		RescanSrcChainID:        "XRP",
+		RescanDestChainID:       "ATOM_DTOKEN",
+		MemoCodec:               MemoCodecConfig{Name: MemoCodecJSON},
+		FeeConfigs: []FeeConfig{
+			{
+				StartTime: time.Date(2023, time.Month(3), 24, 17, 0, 0, 0, time.UTC),
+				FeeModel:  NewPerMilleFeeModel(big.NewInt(1), big.NewInt(2_400000), big.NewInt(477_000000)),
+				MinAmount: big.NewInt(4_800000),
+			},
+			{
+				StartTime: time.Date(2023, time.Month(2), 1, 0, 0, 0, 0, time.UTC),
+				FeeModel: DestinationFeeModel{Model: TieredFeeModel{Tiers: []FeeTier{
+					{UpTo: big.NewInt(1_000000), Model: FixedFeeModel{Amount: big.NewInt(1000)}},
+					{Model: NewBasisPointsFeeModel(big.NewInt(10), nil, nil)},
+				}}},
+			},
+			{
+				StartTime: time.Date(2023, time.Month(1), 1, 0, 0, 0, 0, time.UTC),
+				FeeModel:  FixedFeeModel{Amount: big.NewInt(1000)},
+			},
+		},
+		RouteFeeSchedule: route.FeeSchedule,
+	}, config)
+
+	// the optional fields which aren't set keep the config
+	route, err = FindRoute(routes, "xrpl-coreum-core")
+	require.NoError(t, err)
+	config, err = route.Apply(Config{
+		CoreumMemoSourceChainID: defaultCoreumMemoSourceChainID,
+		MemoCodec:               MemoCodecConfig{Name: MemoCodecColon},
+		FeeConfigs:              []FeeConfig{{}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, defaultCoreumMemoSourceChainID, config.CoreumMemoSourceChainID)
+	require.Equal(t, MemoCodecConfig{Name: MemoCodecColon}, config.MemoCodec)
+	require.Equal(t, []FeeConfig{{}}, config.FeeConfigs)
+	require.Empty(t, config.RouteFeeSchedule)
+
+	_, err = FindRoute(routes, "missing")
+	require.ErrorContains(t, err, "route missing not found")
+
+	identity := testRouteIdentity("ucore", defaultXrplCurrency, defaultBridgeChainIndex)
+	for _, invalidRoutes := range []struct {
+		routes  string
+		wantErr string
+	}{
+		{routes: "routes: []\n", wantErr: "no routes in the file"},
+		{routes: "routes:\n  - xrplAccount: r1\n", wantErr: "empty route name"},
+		{routes: "routes:\n  - name: a\n" + identity + "  - name: a\n" + identity, wantErr: "duplicated route a"},
+		// the missing identity field isn't taken from the flags
+		{routes: "routes:\n  - name: a\n    denom: utoken\n", wantErr: "empty coreumAccount of the route a"},
+		{
+			routes:  "routes:\n  - name: a\n" + identity + "    feeSchedule:\n      - feeModel: percent\n",
+			wantErr: `invalid fee model "percent"`,
+		},
+		{
+			routes: "routes:\n  - name: a\n" + identity + "    feeSchedule:\n      - feeModel: fixed\n" +
+				"        tiers:\n          - feeModel: fixed\n",
+			wantErr: "fee tiers are set for the fixed fee model",
+		},
+		{
+			routes: "routes:\n  - name: a\n" + identity + "    feeSchedule:\n      - feeModel: tiered\n" +
+				"        tiers:\n          - feeModel: tiered\n",
+			wantErr: `invalid fee tier 0, err: invalid fee model "tiered"`,
+		},
+		// the tiers are validated by NewTieredFeeModel
+		{
+			routes: "routes:\n  - name: a\n" + identity + "    feeSchedule:\n      - feeModel: tiered\n" +
+				"        tiers:\n          - upTo: 100\n            feeModel: fixed\n",
+			wantErr: "last fee tier must be unbounded",
+		},
+		{
+			routes: "routes:\n  - name: a\n" + identity + "    feeSchedule:\n      - feeModel: tiered\n" +
+				"        tiers:\n          - upTo: 100\n            feeModel: fixed\n            fee: 1\n" +
+				"          - upTo: 50\n            feeModel: fixed\n            fee: 1\n          - feeModel: fixed\n",
+			wantErr: "fee tiers aren't ordered by the upper bound",
+		},
+		// the destination model is validated by NewDestinationFeeModel
+		{
+			routes: "routes:\n  - name: a\n" + identity + "    feeSchedule:\n      - feeModel: tiered\n" +
+				"        destination: true\n        tiers:\n          - upTo: 100\n            feeModel: fixed\n" +
+				"            fee: 50\n          - feeModel: fixed\n",
+			wantErr: "sent amount decreases at the fee tier bound 100",
+		},
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(invalidRoutes.routes), 0o600))
+		_, err := ReadRoutes(path)
+		require.ErrorContains(t, err, invalidRoutes.wantErr)
+	}
+}
+
+func TestReadFeeSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fee-schedule.yaml")
+	// the default fee schedule
+	require.NoError(t, os.WriteFile(path, []byte(`feeSchedule:
+  - startTime: 2023-03-24T17:00:00Z
+    feeModel: per-mille
+    ratio: 1
+    minFee: 2400000
+    maxFee: 477000000
+    minAmount: 4800000
+    maxAmount: 2400000000000
+  - startTime: 2023-03-17T13:00:00Z
+    feeModel: per-mille
+    ratio: 1
+    minFee: 7000
+    maxFee: 50000
+    minAmount: 8000
+    maxAmount: 100000000
+  - startTime: 2023-01-01T00:00:00Z
+    feeModel: per-mille
+    ratio: 1
+    minFee: 2400000
+    maxFee: 477000000
+    minAmount: 4800000
+    maxAmount: 2400000000000
+`), 0o600))
+	feeSchedule, err := ReadFeeSchedule(path)
+	require.NoError(t, err)
+	require.Equal(t, defaultFeeSchedule, feeSchedule)
+
+	for _, invalidFeeSchedule := range []struct {
+		feeSchedule string
+		wantErr     string
+	}{
+		{feeSchedule: "feeSchedule: []\n", wantErr: "no fee configs in the file"},
+		{feeSchedule: "feeSchedule:\n  - feeModel: percent\n", wantErr: `invalid fee model "percent"`},
+		{
+			feeSchedule: "feeSchedule:\n  - feeModel: tiered\n    tiers:\n      - upTo: 100\n        feeModel: fixed\n",
+			wantErr:     "last fee tier must be unbounded",
+		},
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(invalidFeeSchedule.feeSchedule), 0o600))
+		_, err := ReadFeeSchedule(path)
+		require.ErrorContains(t, err, invalidFeeSchedule.wantErr)
+	}
+}
+
+func TestRouteFilePath(t *testing.T) {
+	require.Equal(t, "datafiles/discrepancies-xrpl-coreum-core.csv", routeFilePath("datafiles/discrepancies.csv", "xrpl-coreum-core"))
+	require.Equal(t, "tracks-xrpl-coreum-core", routeFilePath("tracks", "xrpl-coreum-core"))
+	require.Equal(t, "", routeFilePath("", "xrpl-coreum-core"))
+}
@@ -3,6 +3,10 @@ package main
 import (
 	"fmt"
 	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
 )
 
 // Summary represents the summary report data.
@@ -10,25 +14,64 @@ type Summary struct {
 	CoreumIncomeAmount           *big.Int
 	CoreumOutcomeAmount          *big.Int
 	CoreumBalance                *big.Int
+	CoreumOpeningBalance         *big.Int
+	CoreumGasFeesAmount          *big.Int
+	CoreumUnexplainedAmount      *big.Int // the balance not explained by the opening balance, txs and gas fees
 	XrplBurntAmount              *big.Int
 	XrplOrphanTxCount            int
 	XrplOrphanTxAmount           *big.Int
 	XrplSupply                   *big.Int
 	FeesAmount                   *big.Int
 	NoneOrphanDiscrepanciesCount int
+	CoreumIncomeByCounterparty   map[string]*big.Int // the amounts received in the time window per counterparty class
+	CoreumOutcomeByCounterparty  map[string]*big.Int // the amounts sent in the time window per counterparty class
+	CoreumIncomeByLabel          map[string]*big.Int // the amounts received in the time window per address label
+	CoreumOutcomeByLabel         map[string]*big.Int // the amounts sent in the time window per address label
 }
 
 func (r Summary) String() string {
-	return fmt.Sprintf(
-		"Coreum [IncomeAmount:%s, OutcomeAmount:%s, Balance:%s] \n"+
+	summary := fmt.Sprintf(
+		"Coreum [IncomeAmount:%s, OutcomeAmount:%s, Balance:%s, OpeningBalance:%s, GasFees:%s, Unexplained:%s] \n"+
 			"Xrpl   [Burnt:%s, Supply:%s, OrphanTxs:%d, OrphanTxAmount:%s] \n"+
 			"Fees: %s \n"+
 			"NoneOrphanDiscrepancies: %d",
 		convertFloatToSixDecimalsFloatText(r.CoreumIncomeAmount), convertFloatToSixDecimalsFloatText(r.CoreumOutcomeAmount), convertFloatToSixDecimalsFloatText(r.CoreumBalance),
+		convertFloatToSixDecimalsFloatText(r.CoreumOpeningBalance), convertFloatToSixDecimalsFloatText(r.CoreumGasFeesAmount), convertFloatToSixDecimalsFloatText(r.CoreumUnexplainedAmount),
 		convertFloatToSixDecimalsFloatText(r.XrplBurntAmount), convertFloatToSixDecimalsFloatText(r.XrplSupply), r.XrplOrphanTxCount, convertFloatToSixDecimalsFloatText(r.XrplOrphanTxAmount),
 		convertFloatToSixDecimalsFloatText(r.FeesAmount),
 		r.NoneOrphanDiscrepanciesCount,
 	)
+	if r.CoreumIncomeByCounterparty != nil {
+		summary += "\nCoreum incoming by counterparty [" + formatAmountsByCounterparty(r.CoreumIncomeByCounterparty) + "]"
+	}
+	if r.CoreumOutcomeByCounterparty != nil {
+		summary += "\nCoreum outgoing by counterparty [" + formatAmountsByCounterparty(r.CoreumOutcomeByCounterparty) + "]"
+	}
+	if len(r.CoreumIncomeByLabel) > 0 {
+		summary += "\nCoreum incoming by label [" + formatAmountsByLabel(r.CoreumIncomeByLabel) + "]"
+	}
+	if len(r.CoreumOutcomeByLabel) > 0 {
+		summary += "\nCoreum outgoing by label [" + formatAmountsByLabel(r.CoreumOutcomeByLabel) + "]"
+	}
+
+	return summary
+}
+
+func formatAmountsByCounterparty(amounts map[string]*big.Int) string {
+	items := make([]string, 0, len(counterpartyClasses))
+	for _, class := range counterpartyClasses {
+		items = append(items, fmt.Sprintf("%s:%s", class, convertFloatToSixDecimalsFloatText(bigIntOrZero(amounts[class]))))
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// CoreumBalanceSources are the sources of the coreum account balance: the opening (genesis) balance and all the
+// incoming and outgoing txs of the account with their gas fees.
+type CoreumBalanceSources struct {
+	OpeningBalance *big.Int
+	IncomingTxs    []AuditTx
+	OutgoingTxs    []AuditTx
 }
 
 func BuildSummary(
@@ -36,10 +79,11 @@ func BuildSummary(
 	coreumIncomingTxs []AuditTx,
 	coreumBalance,
 	xrplSupply *big.Int,
+	coreumBalanceSources CoreumBalanceSources,
 ) Summary {
 	coreumIncomeAmount := big.NewInt(0)
 	for _, coreumInTx := range coreumIncomingTxs {
-		coreumIncomeAmount = big.NewInt(0).Add(coreumIncomeAmount, coreumInTx.Amount)
+		coreumIncomeAmount = big.NewInt(0).Add(coreumIncomeAmount, bigIntOrZero(coreumInTx.Amount))
 	}
 
 	coreumOutcomeAmount := big.NewInt(0)
@@ -50,10 +94,16 @@ func BuildSummary(
 	noneOrphanDiscrepanciesCount := 0
 	for _, discrepancy := range discrepancies {
 		switch discrepancy.Discrepancy {
-		case "":
+		case "", InfoRoundingDifference:
 			xrplBurntAmount = big.NewInt(0).Add(xrplBurntAmount, discrepancy.XrplTx.Amount)
 			coreumOutcomeAmount = big.NewInt(0).Add(coreumOutcomeAmount, discrepancy.CoreumTx.Amount)
 			feesAmount = big.NewInt(0).Add(feesAmount, big.NewInt(0).Sub(discrepancy.XrplTx.Amount, discrepancy.CoreumTx.Amount))
+		case InfoAdditionalPayout:
+			// the fee of the xrpl tx is counted with the first payout, so the additional one reduces it
+			coreumOutcomeAmount = big.NewInt(0).Add(coreumOutcomeAmount, discrepancy.CoreumTx.Amount)
+			feesAmount = big.NewInt(0).Sub(feesAmount, discrepancy.CoreumTx.Amount)
+		case InfoAdditionalPayoutOfDiscrepancy:
+			// the payout is counted with the discrepancy of its xrpl tx
 		case InfoAmountOutOfRange:
 			xrplBurntAmount = big.NewInt(0).Add(xrplBurntAmount, discrepancy.XrplTx.Amount)
 		case DiscrepancyOrphanXrplTx:
@@ -65,10 +115,26 @@ func BuildSummary(
 		}
 	}
 
+	// the balance is explained by the opening balance plus incoming minus outgoing txs and gas fees
+	coreumOpeningBalance := bigIntOrZero(coreumBalanceSources.OpeningBalance)
+	coreumGasFeesAmount := big.NewInt(0)
+	coreumExplainedBalance := big.NewInt(0).Set(coreumOpeningBalance)
+	for _, coreumTx := range coreumBalanceSources.IncomingTxs {
+		coreumExplainedBalance.Add(coreumExplainedBalance, bigIntOrZero(coreumTx.Amount))
+	}
+	for _, coreumTx := range coreumBalanceSources.OutgoingTxs {
+		coreumExplainedBalance.Sub(coreumExplainedBalance, bigIntOrZero(coreumTx.Amount))
+		coreumGasFeesAmount.Add(coreumGasFeesAmount, bigIntOrZero(coreumTx.Fee))
+	}
+	coreumExplainedBalance.Sub(coreumExplainedBalance, coreumGasFeesAmount)
+
 	return Summary{
 		CoreumIncomeAmount:           coreumIncomeAmount,
 		CoreumOutcomeAmount:          coreumOutcomeAmount,
 		CoreumBalance:                coreumBalance,
+		CoreumOpeningBalance:         coreumOpeningBalance,
+		CoreumGasFeesAmount:          coreumGasFeesAmount,
+		CoreumUnexplainedAmount:      big.NewInt(0).Sub(bigIntOrZero(coreumBalance), coreumExplainedBalance),
 		XrplBurntAmount:              xrplBurntAmount,
 		XrplSupply:                   xrplSupply,
 		XrplOrphanTxCount:            xrplOrphanTxCount,
@@ -77,3 +143,122 @@ func BuildSummary(
 		NoneOrphanDiscrepanciesCount: noneOrphanDiscrepanciesCount,
 	}
 }
+
+func formatAmountsByLabel(amounts map[string]*big.Int) string {
+	labels := lo.Keys(amounts)
+	sort.Strings(labels)
+	items := make([]string, 0, len(amounts))
+	for _, label := range labels {
+		items = append(items, fmt.Sprintf("%s:%s", label, convertFloatToSixDecimalsFloatText(amounts[label])))
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// SummaryAsset is the bridged asset of the route summary, the summaries of the different assets can't be added up.
+type SummaryAsset struct {
+	Denom        string
+	XrplCurrency string
+	XrplIssuer   string
+}
+
+func (a SummaryAsset) String() string {
+	return fmt.Sprintf("%s/%s.%s", a.Denom, a.XrplCurrency, a.XrplIssuer)
+}
+
+// RouteSummary is the summary of the route with the asset and the coreum account it's built for.
+type RouteSummary struct {
+	Route         string
+	Asset         SummaryAsset
+	CoreumAccount string
+	Summary       Summary
+}
+
+// AssetSummary is the summary aggregated over the routes of the asset.
+type AssetSummary struct {
+	Asset   SummaryAsset
+	Routes  []string
+	Summary Summary
+}
+
+// AggregateSummaries returns the summaries of the routes aggregated per asset, ordered as the assets first appear.
+// The routes of the same coreum account share its txs and balance, so the coreum account amounts are counted once per
+// account, and the xrpl supply is counted once per asset.
+func AggregateSummaries(routeSummaries []RouteSummary) []AssetSummary {
+	assetSummaries := make([]AssetSummary, 0)
+	assetIndexes := make(map[SummaryAsset]int)
+	assetAccounts := make(map[SummaryAsset]map[string]struct{})
+	for _, routeSummary := range routeSummaries {
+		i, ok := assetIndexes[routeSummary.Asset]
+		if !ok {
+			i = len(assetSummaries)
+			assetIndexes[routeSummary.Asset] = i
+			assetAccounts[routeSummary.Asset] = make(map[string]struct{})
+			assetSummaries = append(assetSummaries, AssetSummary{
+				Asset:   routeSummary.Asset,
+				Summary: newZeroSummary(),
+			})
+			assetSummaries[i].Summary.XrplSupply = bigIntOrZero(routeSummary.Summary.XrplSupply)
+		}
+		assetSummary := &assetSummaries[i]
+		assetSummary.Routes = append(assetSummary.Routes, routeSummary.Route)
+		aggregated, summary := &assetSummary.Summary, routeSummary.Summary
+		add := func(target **big.Int, value *big.Int) {
+			*target = big.NewInt(0).Add(*target, bigIntOrZero(value))
+		}
+
+		add(&aggregated.CoreumOutcomeAmount, summary.CoreumOutcomeAmount)
+		add(&aggregated.XrplBurntAmount, summary.XrplBurntAmount)
+		add(&aggregated.XrplOrphanTxAmount, summary.XrplOrphanTxAmount)
+		add(&aggregated.FeesAmount, summary.FeesAmount)
+		aggregated.XrplOrphanTxCount += summary.XrplOrphanTxCount
+		aggregated.NoneOrphanDiscrepanciesCount += summary.NoneOrphanDiscrepanciesCount
+
+		if _, ok := assetAccounts[routeSummary.Asset][routeSummary.CoreumAccount]; ok {
+			continue
+		}
+		assetAccounts[routeSummary.Asset][routeSummary.CoreumAccount] = struct{}{}
+		add(&aggregated.CoreumIncomeAmount, summary.CoreumIncomeAmount)
+		add(&aggregated.CoreumBalance, summary.CoreumBalance)
+		add(&aggregated.CoreumOpeningBalance, summary.CoreumOpeningBalance)
+		add(&aggregated.CoreumGasFeesAmount, summary.CoreumGasFeesAmount)
+		add(&aggregated.CoreumUnexplainedAmount, summary.CoreumUnexplainedAmount)
+		aggregated.CoreumIncomeByCounterparty = addAmountsByKey(aggregated.CoreumIncomeByCounterparty, summary.CoreumIncomeByCounterparty)
+		aggregated.CoreumOutcomeByCounterparty = addAmountsByKey(aggregated.CoreumOutcomeByCounterparty, summary.CoreumOutcomeByCounterparty)
+		aggregated.CoreumIncomeByLabel = addAmountsByKey(aggregated.CoreumIncomeByLabel, summary.CoreumIncomeByLabel)
+		aggregated.CoreumOutcomeByLabel = addAmountsByKey(aggregated.CoreumOutcomeByLabel, summary.CoreumOutcomeByLabel)
+	}
+
+	return assetSummaries
+}
+
+func newZeroSummary() Summary {
+	return Summary{
+		CoreumIncomeAmount:      big.NewInt(0),
+		CoreumOutcomeAmount:     big.NewInt(0),
+		CoreumBalance:           big.NewInt(0),
+		CoreumOpeningBalance:    big.NewInt(0),
+		CoreumGasFeesAmount:     big.NewInt(0),
+		CoreumUnexplainedAmount: big.NewInt(0),
+		XrplBurntAmount:         big.NewInt(0),
+		XrplOrphanTxAmount:      big.NewInt(0),
+		XrplSupply:              big.NewInt(0),
+		FeesAmount:              big.NewInt(0),
+	}
+}
+
+// addAmountsByKey returns the sum of the amounts with the same key, the nil amounts stay nil if both are nil.
+func addAmountsByKey(amounts, otherAmounts map[string]*big.Int) map[string]*big.Int {
+	if otherAmounts == nil {
+		return amounts
+	}
+	sum := make(map[string]*big.Int, len(amounts)+len(otherAmounts))
+	for key, amount := range amounts {
+		sum[key] = amount
+	}
+	for key, amount := range otherAmounts {
+		sum[key] = big.NewInt(0).Add(bigIntOrZero(sum[key]), bigIntOrZero(amount))
+	}
+
+	return sum
+}
@@ -27,6 +27,21 @@ func TestBuildSummary(t *testing.T) {
 				Amount: big.NewInt(80),
 			},
 		},
+		// no discrepancy with the additional payout
+		{
+			XrplTx: AuditTx{
+				Amount: big.NewInt(50),
+			},
+			CoreumTx: AuditTx{
+				Amount: big.NewInt(35),
+			},
+		},
+		{
+			CoreumTx: AuditTx{
+				Amount: big.NewInt(10),
+			},
+			Discrepancy: InfoAdditionalPayout,
+		},
 		// orphan discrepancy
 		{
 			XrplTx: AuditTx{
@@ -48,6 +63,13 @@ func TestBuildSummary(t *testing.T) {
 			},
 			Discrepancy: DiscrepancyDifferentAmountOnXrplAndCoreum,
 		},
+		// the additional payout is counted with the discrepancy of its xrpl tx
+		{
+			CoreumTx: AuditTx{
+				Amount: big.NewInt(5),
+			},
+			Discrepancy: InfoAdditionalPayoutOfDiscrepancy,
+		},
 	}
 	coreumIncomingTxs := []AuditTx{
 		{
@@ -61,18 +83,119 @@ func TestBuildSummary(t *testing.T) {
 	coreumBalance := big.NewInt(333)
 	xrplSupply := big.NewInt(555)
 
-	got := BuildSummary(discrepancies, coreumIncomingTxs, coreumBalance, xrplSupply)
+	coreumBalanceSources := CoreumBalanceSources{
+		OpeningBalance: big.NewInt(100),
+		IncomingTxs:    append(coreumIncomingTxs, AuditTx{Amount: big.NewInt(5)}),
+		OutgoingTxs: []AuditTx{
+			{Amount: big.NewInt(90), Fee: big.NewInt(2)},
+			{Amount: big.NewInt(80), Fee: big.NewInt(3)},
+			{Amount: big.NewInt(1)},
+		},
+	}
+
+	got := BuildSummary(discrepancies, coreumIncomingTxs, coreumBalance, xrplSupply, coreumBalanceSources)
 	want := Summary{
-		CoreumIncomeAmount:           big.NewInt(370),
-		CoreumOutcomeAmount:          big.NewInt(170),
-		CoreumBalance:                big.NewInt(333),
-		XrplBurntAmount:              big.NewInt(225),
+		CoreumIncomeAmount:   big.NewInt(370),
+		CoreumOutcomeAmount:  big.NewInt(215),
+		CoreumBalance:        big.NewInt(333),
+		CoreumOpeningBalance: big.NewInt(100),
+		CoreumGasFeesAmount:  big.NewInt(5),
+		// 333 - (100 + 375 - 171 - 5)
+		CoreumUnexplainedAmount:      big.NewInt(34),
+		XrplBurntAmount:              big.NewInt(275),
 		XrplSupply:                   big.NewInt(555),
 		XrplOrphanTxCount:            2,
 		XrplOrphanTxAmount:           big.NewInt(35),
-		FeesAmount:                   big.NewInt(20),
+		FeesAmount:                   big.NewInt(25),
 		NoneOrphanDiscrepanciesCount: 1,
 	}
 
 	require.Equal(t, want, got)
 }
+
+func TestAggregateSummaries(t *testing.T) {
+	coreAsset := SummaryAsset{Denom: "ucore", XrplCurrency: "COR", XrplIssuer: "rIssuer"}
+	otherAsset := SummaryAsset{Denom: "uother", XrplCurrency: "OTH", XrplIssuer: "rIssuer"}
+	routeSummaries := []RouteSummary{
+		{
+			Route:         "core",
+			Asset:         coreAsset,
+			CoreumAccount: "core1",
+			Summary: Summary{
+				CoreumIncomeAmount:           big.NewInt(100),
+				CoreumOutcomeAmount:          big.NewInt(30),
+				CoreumBalance:                big.NewInt(50),
+				XrplSupply:                   big.NewInt(1000),
+				XrplOrphanTxCount:            1,
+				XrplOrphanTxAmount:           big.NewInt(10),
+				NoneOrphanDiscrepanciesCount: 2,
+				CoreumIncomeByCounterparty:   map[string]*big.Int{CounterpartyFoundation: big.NewInt(100)},
+			},
+		},
+		// the other asset isn't added up with the core one
+		{
+			Route:         "other",
+			Asset:         otherAsset,
+			CoreumAccount: "core1",
+			Summary: Summary{
+				CoreumIncomeAmount: big.NewInt(7),
+				CoreumBalance:      big.NewInt(7),
+				XrplSupply:         big.NewInt(9),
+			},
+		},
+		// the route of the same account and asset shares the account amounts and the supply
+		{
+			Route:         "core-other-chain",
+			Asset:         coreAsset,
+			CoreumAccount: "core1",
+			Summary: Summary{
+				CoreumIncomeAmount:         big.NewInt(100),
+				CoreumOutcomeAmount:        big.NewInt(5),
+				CoreumBalance:              big.NewInt(50),
+				XrplSupply:                 big.NewInt(1000),
+				XrplOrphanTxCount:          3,
+				CoreumIncomeByCounterparty: map[string]*big.Int{CounterpartyFoundation: big.NewInt(100)},
+			},
+		},
+		// the route of the other account of the asset
+		{
+			Route:         "core-other-account",
+			Asset:         coreAsset,
+			CoreumAccount: "core2",
+			Summary: Summary{
+				CoreumIncomeAmount:         big.NewInt(20),
+				CoreumBalance:              big.NewInt(5),
+				XrplSupply:                 big.NewInt(1000),
+				CoreumIncomeByCounterparty: map[string]*big.Int{CounterpartyFoundation: big.NewInt(20), CounterpartyOperator: big.NewInt(1)},
+				CoreumOutcomeByLabel:       map[string]*big.Int{"Distribution address": big.NewInt(7)},
+			},
+		},
+	}
+
+	assetSummaries := AggregateSummaries(routeSummaries)
+	require.Len(t, assetSummaries, 2)
+	require.Equal(t, AssetSummary{
+		Asset:  coreAsset,
+		Routes: []string{"core", "core-other-chain", "core-other-account"},
+		Summary: Summary{
+			CoreumIncomeAmount:           big.NewInt(120),
+			CoreumOutcomeAmount:          big.NewInt(35),
+			CoreumBalance:                big.NewInt(55),
+			CoreumOpeningBalance:         big.NewInt(0),
+			CoreumGasFeesAmount:          big.NewInt(0),
+			CoreumUnexplainedAmount:      big.NewInt(0),
+			XrplBurntAmount:              big.NewInt(0),
+			XrplSupply:                   big.NewInt(1000),
+			XrplOrphanTxCount:            4,
+			XrplOrphanTxAmount:           big.NewInt(10),
+			FeesAmount:                   big.NewInt(0),
+			NoneOrphanDiscrepanciesCount: 2,
+			CoreumIncomeByCounterparty:   map[string]*big.Int{CounterpartyFoundation: big.NewInt(120), CounterpartyOperator: big.NewInt(1)},
+			CoreumOutcomeByLabel:         map[string]*big.Int{"Distribution address": big.NewInt(7)},
+		},
+	}, assetSummaries[0])
+	require.Equal(t, otherAsset, assetSummaries[1].Asset)
+	require.Equal(t, []string{"other"}, assetSummaries[1].Routes)
+	require.Equal(t, big.NewInt(7), assetSummaries[1].Summary.CoreumBalance)
+	require.Equal(t, big.NewInt(9), assetSummaries[1].Summary.XrplSupply)
+}
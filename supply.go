@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	supplyHistorySVGWidth  = 960
+	supplyHistorySVGHeight = 480
+	supplyHistorySVGMargin = 80
+)
+
+// SupplyHistoryDay is the xrpl supply and the coreum locked balance at the end of the day, with the changes
+// during the day.
+type SupplyHistoryDay struct {
+	Date                time.Time
+	XrplIssuedAmount    *big.Int
+	XrplBurntAmount     *big.Int
+	XrplSupply          *big.Int
+	CoreumIncomeAmount  *big.Int
+	CoreumOutcomeAmount *big.Int
+	CoreumGasFeesAmount *big.Int
+	CoreumLockedAmount  *big.Int
+	Difference          *big.Int // coreum locked amount minus xrpl supply
+}
+
+// BuildSupplyHistory reconstructs the daily xrpl supply from the issuer payments, and the coreum locked balance from
+// the same sources the summary explains the coreum balance with: the opening balance plus the incoming minus the
+// outgoing txs and their gas fees. The txs must cover the full history since the supply and balance are accumulated
+// from the start of the bridge, the days from the fromDateTime to the toDateTime are returned.
+func BuildSupplyHistory(
+	xrplIssuedTxs, xrplBurntTxs []AuditTx,
+	coreumBalanceSources CoreumBalanceSources,
+	fromDateTime, toDateTime time.Time,
+) []SupplyHistoryDay {
+	fromDate := truncateToDay(fromDateTime)
+	toDate := truncateToDay(toDateTime)
+
+	xrplIssuedAmounts := sumAuditTxsByDay(xrplIssuedTxs)
+	xrplBurntAmounts := sumAuditTxsByDay(xrplBurntTxs)
+	coreumIncomeAmounts := sumAuditTxsByDay(coreumBalanceSources.IncomingTxs)
+	coreumOutcomeAmounts := sumAuditTxsByDay(coreumBalanceSources.OutgoingTxs)
+	coreumGasFeesAmounts := sumAuditTxFeesByDay(coreumBalanceSources.OutgoingTxs)
+
+	// the amounts before the first day are accumulated to the opening supply and balance
+	startDate := fromDate
+	for _, amounts := range []map[time.Time]*big.Int{
+		xrplIssuedAmounts, xrplBurntAmounts, coreumIncomeAmounts, coreumOutcomeAmounts, coreumGasFeesAmounts,
+	} {
+		for date := range amounts {
+			if date.Before(startDate) {
+				startDate = date
+			}
+		}
+	}
+
+	xrplSupply := big.NewInt(0)
+	coreumLockedAmount := big.NewInt(0).Set(bigIntOrZero(coreumBalanceSources.OpeningBalance))
+	history := make([]SupplyHistoryDay, 0)
+	for date := startDate; !date.After(toDate); date = date.AddDate(0, 0, 1) {
+		day := SupplyHistoryDay{
+			Date:                date,
+			XrplIssuedAmount:    bigIntOrZero(xrplIssuedAmounts[date]),
+			XrplBurntAmount:     bigIntOrZero(xrplBurntAmounts[date]),
+			CoreumIncomeAmount:  bigIntOrZero(coreumIncomeAmounts[date]),
+			CoreumOutcomeAmount: bigIntOrZero(coreumOutcomeAmounts[date]),
+			CoreumGasFeesAmount: bigIntOrZero(coreumGasFeesAmounts[date]),
+		}
+		xrplSupply = big.NewInt(0).Add(xrplSupply, big.NewInt(0).Sub(day.XrplIssuedAmount, day.XrplBurntAmount))
+		coreumLockedAmount = big.NewInt(0).Add(coreumLockedAmount, big.NewInt(0).Sub(day.CoreumIncomeAmount, day.CoreumOutcomeAmount))
+		coreumLockedAmount.Sub(coreumLockedAmount, day.CoreumGasFeesAmount)
+		if date.Before(fromDate) {
+			continue
+		}
+		day.XrplSupply = xrplSupply
+		day.CoreumLockedAmount = coreumLockedAmount
+		day.Difference = big.NewInt(0).Sub(coreumLockedAmount, xrplSupply)
+		history = append(history, day)
+	}
+
+	return history
+}
+
+// WriteSupplyHistoryToSVG writes the plot of the daily xrpl supply, coreum locked balance and their difference.
+func WriteSupplyHistoryToSVG(history []SupplyHistoryDay, path string) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(plotSupplyHistory(history)); err != nil {
+		return errors.Errorf("can't write file, path: %s, err: %s", path, err)
+	}
+
+	return nil
+}
+
+// plotSupplyHistory returns the SVG line chart of the supply history, the y axis covers all the series and zero.
+func plotSupplyHistory(history []SupplyHistoryDay) string {
+	series := []struct {
+		name   string
+		color  string
+		amount func(day SupplyHistoryDay) *big.Int
+	}{
+		{name: "XrplSupply", color: "#1f77b4", amount: func(day SupplyHistoryDay) *big.Int { return day.XrplSupply }},
+		{name: "CoreumLockedAmount", color: "#ff7f0e", amount: func(day SupplyHistoryDay) *big.Int { return day.CoreumLockedAmount }},
+		{name: "Difference", color: "#7f7f7f", amount: func(day SupplyHistoryDay) *big.Int { return day.Difference }},
+	}
+
+	minValue, maxValue := 0.0, 0.0
+	for _, day := range history {
+		for _, s := range series {
+			value := amountToPlotValue(s.amount(day))
+			if value < minValue {
+				minValue = value
+			}
+			if value > maxValue {
+				maxValue = value
+			}
+		}
+	}
+	if maxValue == minValue {
+		maxValue = minValue + 1
+	}
+
+	plotWidth := float64(supplyHistorySVGWidth - 2*supplyHistorySVGMargin)
+	plotHeight := float64(supplyHistorySVGHeight - 2*supplyHistorySVGMargin)
+	x := func(i int) float64 {
+		if len(history) < 2 {
+			return supplyHistorySVGMargin
+		}
+		return supplyHistorySVGMargin + float64(i)*plotWidth/float64(len(history)-1)
+	}
+	y := func(value float64) float64 {
+		return supplyHistorySVGMargin + (maxValue-value)/(maxValue-minValue)*plotHeight
+	}
+
+	svg := strings.Builder{}
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		supplyHistorySVGWidth, supplyHistorySVGHeight, supplyHistorySVGWidth, supplyHistorySVGHeight)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="white"/>`+"\n", supplyHistorySVGWidth, supplyHistorySVGHeight)
+	// the axes with the zero line and the labels of the range
+	fmt.Fprintf(&svg, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n",
+		supplyHistorySVGMargin, supplyHistorySVGMargin, supplyHistorySVGMargin, supplyHistorySVGHeight-supplyHistorySVGMargin)
+	fmt.Fprintf(&svg, `<line x1="%d" y1="%.2f" x2="%d" y2="%.2f" stroke="black"/>`+"\n",
+		supplyHistorySVGMargin, y(0), supplyHistorySVGWidth-supplyHistorySVGMargin, y(0))
+	fmt.Fprintf(&svg, `<text x="%d" y="%.2f" font-size="12" text-anchor="end">%.6f</text>`+"\n",
+		supplyHistorySVGMargin-4, y(maxValue)+4, maxValue)
+	fmt.Fprintf(&svg, `<text x="%d" y="%.2f" font-size="12" text-anchor="end">%.6f</text>`+"\n",
+		supplyHistorySVGMargin-4, y(minValue)+4, minValue)
+	if len(history) > 0 {
+		fmt.Fprintf(&svg, `<text x="%.2f" y="%d" font-size="12" text-anchor="start">%s</text>`+"\n",
+			x(0), supplyHistorySVGHeight-supplyHistorySVGMargin+20, history[0].Date.Format(time.DateOnly))
+		fmt.Fprintf(&svg, `<text x="%.2f" y="%d" font-size="12" text-anchor="end">%s</text>`+"\n",
+			x(len(history)-1), supplyHistorySVGHeight-supplyHistorySVGMargin+20, history[len(history)-1].Date.Format(time.DateOnly))
+	}
+	for i, s := range series {
+		points := make([]string, 0, len(history))
+		for j, day := range history {
+			points = append(points, fmt.Sprintf("%.2f,%.2f", x(j), y(amountToPlotValue(s.amount(day)))))
+		}
+		fmt.Fprintf(&svg, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", s.color, strings.Join(points, " "))
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" font-size="12" fill="%s">%s</text>`+"\n",
+			supplyHistorySVGMargin+i*200, supplyHistorySVGMargin/2, s.color, s.name)
+	}
+	svg.WriteString("</svg>\n")
+
+	return svg.String()
+}
+
+// amountToPlotValue returns the amount in the smallest denomination as the float of the whole tokens.
+func amountToPlotValue(amount *big.Int) float64 {
+	value, _ := big.NewFloat(0).Quo(big.NewFloat(0).SetInt(bigIntOrZero(amount)), oneMillionFloat).Float64()
+
+	return value
+}
+
+// sumAuditTxsByDay returns the sum of the tx amounts per UTC day.
+func sumAuditTxsByDay(txs []AuditTx) map[time.Time]*big.Int {
+	amounts := make(map[time.Time]*big.Int)
+	for _, tx := range txs {
+		date := truncateToDay(tx.Timestamp)
+		amounts[date] = big.NewInt(0).Add(bigIntOrZero(amounts[date]), bigIntOrZero(tx.Amount))
+	}
+
+	return amounts
+}
+
+// sumAuditTxFeesByDay returns the sum of the tx fees per UTC day, the days without the fees are skipped.
+func sumAuditTxFeesByDay(txs []AuditTx) map[time.Time]*big.Int {
+	fees := make(map[time.Time]*big.Int)
+	for _, tx := range txs {
+		if tx.Fee == nil {
+			continue
+		}
+		date := truncateToDay(tx.Timestamp)
+		fees[date] = big.NewInt(0).Add(bigIntOrZero(fees[date]), tx.Fee)
+	}
+
+	return fees
+}
+
+func truncateToDay(dateTime time.Time) time.Time {
+	dateTime = dateTime.UTC()
+
+	return time.Date(dateTime.Year(), dateTime.Month(), dateTime.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSupplyHistory(t *testing.T) {
+	day := time.Date(2023, time.Month(6), 1, 0, 0, 0, 0, time.UTC)
+	auditTx := func(amount int64, timestamp time.Time) AuditTx {
+		return AuditTx{Amount: big.NewInt(amount), Timestamp: timestamp}
+	}
+
+	history := BuildSupplyHistory(
+		[]AuditTx{
+			// before the first day, accumulated to the opening supply
+			auditTx(100, day.Add(-48*time.Hour)),
+			auditTx(10, day.Add(23*time.Hour)),
+		},
+		[]AuditTx{
+			auditTx(30, day.Add(25*time.Hour)),
+			// after the last day
+			auditTx(5, day.Add(72*time.Hour)),
+		},
+		CoreumBalanceSources{
+			OpeningBalance: big.NewInt(50),
+			IncomingTxs: []AuditTx{
+				auditTx(200, day.Add(-time.Hour)),
+			},
+			OutgoingTxs: []AuditTx{
+				{Amount: big.NewInt(20), Fee: big.NewInt(2), Timestamp: day.Add(time.Hour)},
+				// the fee before the first day is accumulated to the opening balance
+				{Amount: big.NewInt(0), Fee: big.NewInt(1), Timestamp: day.Add(-24 * time.Hour)},
+				auditTx(7, day.Add(47*time.Hour)),
+			},
+		},
+		day.Add(10*time.Hour),
+		day.Add(24*time.Hour),
+	)
+	require.Equal(t, []SupplyHistoryDay{
+		{
+			Date:                day,
+			XrplIssuedAmount:    big.NewInt(10),
+			XrplBurntAmount:     big.NewInt(0),
+			XrplSupply:          big.NewInt(110),
+			CoreumIncomeAmount:  big.NewInt(0),
+			CoreumOutcomeAmount: big.NewInt(20),
+			CoreumGasFeesAmount: big.NewInt(2),
+			CoreumLockedAmount:  big.NewInt(227),
+			Difference:          big.NewInt(117),
+		},
+		{
+			Date:                day.Add(24 * time.Hour),
+			XrplIssuedAmount:    big.NewInt(0),
+			XrplBurntAmount:     big.NewInt(30),
+			XrplSupply:          big.NewInt(80),
+			CoreumIncomeAmount:  big.NewInt(0),
+			CoreumOutcomeAmount: big.NewInt(7),
+			CoreumGasFeesAmount: big.NewInt(0),
+			CoreumLockedAmount:  big.NewInt(220),
+			Difference:          big.NewInt(140),
+		},
+	}, history)
+}
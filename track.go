@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// rescan tracking statuses.
+const (
+	TrackStatusPending  = "pending"
+	TrackStatusMatched  = "matched"
+	TrackStatusOrphaned = "orphaned"
+	TrackStatusNotFound = "not found"
+)
+
+// RescanTrackCheck is the result of the single matching of the rescanned tx.
+type RescanTrackCheck struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Status      string    `json:"status"`
+	Discrepancy string    `json:"discrepancy,omitempty"`
+}
+
+// RescanTrack is the tracking history of the rescanned tx.
+type RescanTrack struct {
+	Hash         string             `json:"hash"`
+	RescannedAt  time.Time          `json:"rescannedAt"`
+	Status       string             `json:"status"`
+	CoreumTxHash string             `json:"coreumTxHash,omitempty"`
+	MatchedAt    *time.Time         `json:"matchedAt,omitempty"`
+	Checks       []RescanTrackCheck `json:"checks"`
+}
+
+// TimeToMatch returns the duration between the rescan and the coreum tx, zero if the tx isn't matched.
+func (t RescanTrack) TimeToMatch() time.Duration {
+	if t.MatchedAt == nil {
+		return 0
+	}
+	return t.MatchedAt.Sub(t.RescannedAt)
+}
+
+// PendingRescanTracks returns the tracks which are still pending, the matched, orphaned and not found tracks are final.
+func PendingRescanTracks(tracks []RescanTrack) []RescanTrack {
+	pendingTracks := make([]RescanTrack, 0)
+	for _, track := range tracks {
+		if track.Status == TrackStatusPending {
+			pendingTracks = append(pendingTracks, track)
+		}
+	}
+
+	return pendingTracks
+}
+
+// StartRescanTracks adds the submitted txs of the rescan results to the tracks. The rescan time of the already
+// tracked tx is updated and its tracking is restarted if it has been resubmitted.
+func StartRescanTracks(tracks []RescanTrack, results []RescanResult) []RescanTrack {
+	indexes := make(map[string]int, len(tracks))
+	for i, track := range tracks {
+		indexes[strings.ToUpper(track.Hash)] = i
+	}
+	for _, result := range results {
+		if result.Status != RescanStatusSubmitted {
+			continue
+		}
+		i, ok := indexes[strings.ToUpper(result.Hash)]
+		if !ok {
+			indexes[strings.ToUpper(result.Hash)] = len(tracks)
+			tracks = append(tracks, RescanTrack{
+				Hash:        result.Hash,
+				RescannedAt: result.Timestamp,
+				Status:      TrackStatusPending,
+				Checks:      make([]RescanTrackCheck, 0),
+			})
+			continue
+		}
+		if tracks[i].Status != TrackStatusMatched && result.Timestamp.After(tracks[i].RescannedAt) {
+			tracks[i].RescannedAt = result.Timestamp
+			tracks[i].Status = TrackStatusPending
+		}
+	}
+
+	return tracks
+}
+
+// TrackRescannedTxs matches the pending tracked txs with the coreum txs and appends the check to their history.
+// The tx which is still orphan longer than orphanAfter since the rescan is marked as orphaned.
+func TrackRescannedTxs(
+	tracks []RescanTrack,
+	xrplTxs, coreumTxs []AuditTx,
+	feeConfigs []FeeConfig,
+	amountTolerance AmountTolerance,
+	memoCodec MemoCodec,
+	sourceChainID string,
+	orphanAfter time.Duration,
+	now time.Time,
+) []RescanTrack {
+	// the txs aren't filtered by time since the tracked txs are already selected
+	discrepancies := FindAuditTxDiscrepancies(
+		xrplTxs, coreumTxs, feeConfigs, amountTolerance, memoCodec, sourceChainID, true, now, time.Time{},
+	)
+	xrplTxHashToDiscrepancy := make(map[string]TxDiscrepancy, len(discrepancies))
+	for _, discrepancy := range discrepancies {
+		if discrepancy.XrplTx.Hash != "" {
+			xrplTxHashToDiscrepancy[strings.ToUpper(discrepancy.XrplTx.Hash)] = discrepancy
+		}
+	}
+
+	for i := range tracks {
+		track := &tracks[i]
+		if track.Status != TrackStatusPending {
+			continue
+		}
+
+		check := RescanTrackCheck{
+			Timestamp: now,
+		}
+		discrepancy, ok := xrplTxHashToDiscrepancy[strings.ToUpper(track.Hash)]
+		switch {
+		case !ok:
+			check.Status = TrackStatusNotFound
+		case discrepancy.Discrepancy == DiscrepancyOrphanXrplTx, discrepancy.Discrepancy == DiscrepancyUnvalidatedXrplTx,
+			discrepancy.CoreumTx.Hash == "":
+			// the discrepancies without the coreum tx (e.g. amount out of range) aren't matched
+			check.Status = TrackStatusPending
+			if discrepancy.Discrepancy != DiscrepancyOrphanXrplTx {
+				check.Discrepancy = discrepancy.Discrepancy
+			}
+			if now.Sub(track.RescannedAt) > orphanAfter {
+				check.Status = TrackStatusOrphaned
+			}
+		default:
+			// the coreum tx is found, the rest of the discrepancies aren't fixed by the rescan
+			check.Status = TrackStatusMatched
+			check.Discrepancy = discrepancy.Discrepancy
+			matchedAt := discrepancy.CoreumTx.Timestamp
+			track.MatchedAt = &matchedAt
+			track.CoreumTxHash = discrepancy.CoreumTx.Hash
+		}
+		track.Status = check.Status
+		track.Checks = append(track.Checks, check)
+	}
+
+	return tracks
+}
+
+// BuildRescanTrackReport returns the human-readable report of the tracked txs.
+func BuildRescanTrackReport(tracks []RescanTrack, now time.Time) string {
+	sortedTracks := make([]RescanTrack, len(tracks))
+	copy(sortedTracks, tracks)
+	sort.SliceStable(sortedTracks, func(i, j int) bool {
+		return sortedTracks[i].Status < sortedTracks[j].Status
+	})
+
+	counts := make(map[string]int)
+	lines := make([]string, 0, len(sortedTracks))
+	for _, track := range sortedTracks {
+		counts[track.Status]++
+		switch track.Status {
+		case TrackStatusMatched:
+			lines = append(lines, fmt.Sprintf(
+				"%s: matched with %s in %s", track.Hash, track.CoreumTxHash, track.TimeToMatch().Round(time.Second),
+			))
+		case TrackStatusOrphaned:
+			lines = append(lines, fmt.Sprintf(
+				"%s: still orphaned after %.1f hours", track.Hash, now.Sub(track.RescannedAt).Hours(),
+			))
+		default:
+			lines = append(lines, fmt.Sprintf(
+				"%s: %s, rescanned %s ago", track.Hash, track.Status, now.Sub(track.RescannedAt).Round(time.Second),
+			))
+		}
+	}
+
+	return fmt.Sprintf(
+		"Tracked: %d [Matched:%d, Pending:%d, Orphaned:%d, NotFound:%d] \n%s",
+		len(tracks),
+		counts[TrackStatusMatched], counts[TrackStatusPending], counts[TrackStatusOrphaned], counts[TrackStatusNotFound],
+		strings.Join(lines, "\n"),
+	)
+}
+
+// ReadRescanTracksFromJSON reads the tracks history, the missing file is treated as empty history.
+func ReadRescanTracksFromJSON(path string) ([]RescanTrack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make([]RescanTrack, 0), nil
+		}
+		return nil, errors.Errorf("can't read file, path: %s, err: %s", path, err)
+	}
+
+	var tracks []RescanTrack
+	if err := json.Unmarshal(data, &tracks); err != nil {
+		return nil, errors.Errorf("can't decode rescan tracks, path: %s, err: %s", path, err)
+	}
+
+	return tracks, nil
+}
+
+// WriteRescanTracksToJSON writes the tracks history.
+func WriteRescanTracksToJSON(tracks []RescanTrack, path string) error {
+	data, err := json.MarshalIndent(tracks, "", "  ")
+	if err != nil {
+		return errors.Errorf("can't encode rescan tracks, err: %s", err)
+	}
+
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return errors.Errorf("can't write file, path: %s, err: %s", path, err)
+	}
+
+	return nil
+}
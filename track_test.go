@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackRescannedTxs(t *testing.T) {
+	rescannedAt := time.Date(2023, time.Month(7), 1, 12, 0, 0, 0, time.UTC)
+	results := []RescanResult{
+		{Hash: "hash1", Status: RescanStatusSubmitted, Timestamp: rescannedAt},
+		{Hash: "hash2", Status: RescanStatusSubmitted, Timestamp: rescannedAt},
+		{Hash: "hash3", Status: RescanStatusFailed, Timestamp: rescannedAt},
+		{Hash: "hash4", Status: RescanStatusSubmitted, Timestamp: rescannedAt},
+		{Hash: "hash5", Status: RescanStatusSubmitted, Timestamp: rescannedAt},
+	}
+	tracks := StartRescanTracks(nil, results)
+	require.Len(t, tracks, 4)
+
+	xrplTxs := []AuditTx{
+		{Hash: "hash1", TargetAddress: "address1", Amount: big.NewInt(100), Timestamp: rescannedAt.Add(-time.Hour)},
+		{Hash: "hash2", TargetAddress: "address2", Amount: big.NewInt(200), Timestamp: rescannedAt.Add(-time.Hour)},
+		// the amount is out of range, so the tx isn't bridged
+		{Hash: "hash4", TargetAddress: "address4", Amount: big.NewInt(2_000_000), Timestamp: rescannedAt.Add(-time.Hour)},
+	}
+
+	feeConfigs := []FeeConfig{
+		{
+			StartTime: time.Time{},
+			MinAmount: big.NewInt(0),
+			MaxAmount: big.NewInt(1_000_000),
+		},
+	}
+
+	// first check, nothing is matched yet
+	now := rescannedAt.Add(time.Hour)
+	tracks = TrackRescannedTxs(tracks, xrplTxs, nil, feeConfigs, AmountTolerance{}, nil, "", 24*time.Hour, now)
+	require.Equal(t, TrackStatusPending, tracks[0].Status)
+	require.Equal(t, TrackStatusPending, tracks[1].Status)
+	require.Equal(t, TrackStatusPending, tracks[2].Status)
+	require.Equal(t, InfoAmountOutOfRange, tracks[2].Checks[0].Discrepancy)
+	require.Equal(t, TrackStatusNotFound, tracks[3].Status)
+	require.Len(t, PendingRescanTracks(tracks), 3)
+
+	// second check, the first tx is matched, and the second one is orphaned
+	now = rescannedAt.Add(25 * time.Hour)
+	coreumTxs := []AuditTx{
+		{
+			Hash:          "coreumHash1",
+			TargetAddress: "address1",
+			Amount:        big.NewInt(100),
+			Memo:          "1:hash1:1",
+			Timestamp:     rescannedAt.Add(30 * time.Minute),
+		},
+	}
+	tracks = TrackRescannedTxs(tracks, xrplTxs, coreumTxs, feeConfigs, AmountTolerance{}, nil, "", 24*time.Hour, now)
+	require.Equal(t, TrackStatusMatched, tracks[0].Status)
+	require.Equal(t, "coreumHash1", tracks[0].CoreumTxHash)
+	require.Equal(t, 30*time.Minute, tracks[0].TimeToMatch())
+	require.Len(t, tracks[0].Checks, 2)
+	require.Equal(t, TrackStatusOrphaned, tracks[1].Status)
+	require.Len(t, tracks[1].Checks, 2)
+	require.Equal(t, TrackStatusOrphaned, tracks[2].Status)
+	require.Empty(t, tracks[2].CoreumTxHash)
+	require.Len(t, tracks[3].Checks, 1)
+	require.Empty(t, PendingRescanTracks(tracks))
+
+	// the final tracks aren't checked again
+	tracks = TrackRescannedTxs(tracks, xrplTxs, nil, feeConfigs, AmountTolerance{}, nil, "", 24*time.Hour, now)
+	require.Len(t, tracks[0].Checks, 2)
+	require.Len(t, tracks[1].Checks, 2)
+	require.Len(t, tracks[2].Checks, 2)
+
+	// the resubmitted tx restarts the tracking
+	tracks = StartRescanTracks(tracks, []RescanResult{
+		{Hash: "HASH2", Status: RescanStatusSubmitted, Timestamp: now},
+	})
+	require.Len(t, tracks, 4)
+	require.Equal(t, now, tracks[1].RescannedAt)
+	require.Equal(t, TrackStatusPending, tracks[1].Status)
+	require.Len(t, PendingRescanTracks(tracks), 1)
+
+	path := filepath.Join(t.TempDir(), "rescan-track.json")
+	require.NoError(t, WriteRescanTracksToJSON(tracks, path))
+	got, err := ReadRescanTracksFromJSON(path)
+	require.NoError(t, err)
+	require.Equal(t, tracks, got)
+
+	report := BuildRescanTrackReport(tracks, now)
+	require.Contains(t, report, "Tracked: 4 [Matched:1, Pending:1, Orphaned:1, NotFound:1]")
+	require.Contains(t, report, "hash1: matched with coreumHash1 in 30m0s")
+}
+
+func TestReadRescanTracksFromJSONMissingFile(t *testing.T) {
+	tracks, err := ReadRescanTracksFromJSON(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	require.Empty(t, tracks)
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"net/http"
@@ -12,22 +14,36 @@ import (
 	"time"
 
 	"github.com/gammazero/workerpool"
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
 )
 
 var (
-	xrplRequestTimeout          = 10 * time.Second
 	xrplHistoricalDataPageLimit = 1000 // this limit is maximum for the historical API
 	xrplReceivedTxType          = "received"
+	xrplSentTxType              = "sent"
 	oneMillionFloat             = big.NewFloat(1_000_000)
 	xrplResStatusSuccess        = "success"
-	xrplGetTxRetries            = 10
-	xrplGetTxRetryTimout        = 500 * time.Millisecond
+	xrplResStatusError          = "error"
+	xrplTxResultSuccess         = "tesSUCCESS"
+	xrplTxResultUnknown         = "unknown" // the tx is returned without the result
+	// the xrpl node errors which aren't fixed by the retry
+	xrplPermanentErrors = []string{"txnNotFound", "invalidParams", "notImpl", "unknownCmd"}
 )
 
+// xrpl currency supply sources.
+const (
+	XrplSupplySourceXrpscan    = "xrpscan"
+	XrplSupplySourceRippled    = "rippled"
+	XrplSupplySourceCrossCheck = "cross-check"
+)
+
+var xrplSupplySources = []string{XrplSupplySourceXrpscan, XrplSupplySourceRippled, XrplSupplySourceCrossCheck}
+
 // Historical models
 
 type xrplAccountTransactionPayment struct {
@@ -59,12 +75,14 @@ type xrplMetaDeliveredAmount struct {
 }
 
 type xrplMeta struct {
-	DeliveredAmount xrplMetaDeliveredAmount `json:"delivered_amount"`
+	DeliveredAmount   xrplMetaDeliveredAmount `json:"delivered_amount"`
+	TransactionResult string                  `json:"TransactionResult"`
 }
 
 type xrplMemoItem struct {
-	MemoData string `json:"MemoData"` // hex string
-	MemoType string `json:"MemoType"` // hex string
+	MemoData   string `json:"MemoData"`   // hex string
+	MemoType   string `json:"MemoType"`   // hex string
+	MemoFormat string `json:"MemoFormat"` // hex string
 }
 
 type xrplMemo struct {
@@ -72,14 +90,19 @@ type xrplMemo struct {
 }
 
 type xrplTransaction struct {
-	Account         string     `json:"Account"`
-	Destination     string     `json:"Destination"`
-	Meta            xrplMeta   `json:"meta"`
-	Memos           []xrplMemo `json:"Memos"`
-	Hash            string     `json:"hash"`
-	TransactionType string     `json:"TransactionType"`
-	Status          string     `json:"status"`
-	Date            int        `json:"date"`
+	Error           string                  `json:"error"`
+	Account         string                  `json:"Account"`
+	Destination     string                  `json:"Destination"`
+	DestinationTag  *uint32                 `json:"DestinationTag,omitempty"`
+	Amount          xrplMetaDeliveredAmount `json:"Amount"` // the failed payments don't have the delivered amount
+	Meta            xrplMeta                `json:"meta"`
+	Memos           []xrplMemo              `json:"Memos"`
+	Hash            string                  `json:"hash"`
+	TransactionType string                  `json:"TransactionType"`
+	Status          string                  `json:"status"`
+	Date            int                     `json:"date"`
+	LedgerIndex     int64                   `json:"ledger_index"`
+	Validated       bool                    `json:"validated"`
 }
 
 type xrplTransactionResp struct {
@@ -91,33 +114,226 @@ type xrplCurrencySupply struct {
 	Value    *big.Float `json:"value,string"`
 }
 
+// xrplLedgerIndex is the ledger index request param, the zero index is the latest validated ledger.
+type xrplLedgerIndex int64
+
+func (i xrplLedgerIndex) MarshalJSON() ([]byte, error) {
+	if i == 0 {
+		return json.Marshal("validated")
+	}
+
+	return json.Marshal(int64(i))
+}
+
+func (i *xrplLedgerIndex) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		if name != "validated" {
+			return errors.Errorf("unexpected ledger index %q", name)
+		}
+		*i = 0
+		return nil
+	}
+
+	return json.Unmarshal(data, (*int64)(i))
+}
+
+type xrplGatewayBalancesRequestParams struct {
+	Account     string          `json:"account"`
+	LedgerIndex xrplLedgerIndex `json:"ledger_index"`
+	Strict      bool            `json:"strict"`
+}
+
+type xrplGatewayBalancesRequest struct {
+	Method string                             `json:"method"`
+	Params []xrplGatewayBalancesRequestParams `json:"params"`
+}
+
+type xrplGatewayBalancesResp struct {
+	Result struct {
+		Status      string            `json:"status"`
+		Error       string            `json:"error"`
+		Obligations map[string]string `json:"obligations"`
+	} `json:"result"`
+}
+
 // GetXRPLAuditTransactions returns the list of the valid xrpl bridge transaction converted to the audit model.
+// The non-zero ledgerIndex pins the txs to the ledger, the txs of the later ledgers are skipped.
 func GetXRPLAuditTransactions(
 	ctx context.Context,
-	fetcherPoolSize int,
-	rpcAPIURL, historicalAPIURL, account, currency, issuer, bridgeChainIndex string,
+	httpClient *HTTPClient,
+	fetcherPoolSize, crossCheckSampleSize int,
+	rpcAPIURLs []string,
+	historicalAPIURL, account, currency, issuer string,
+	memoCodec MemoCodec,
+	ledgerIndex int64,
 	beforeDateTime, afterDateTime time.Time,
 ) ([]AuditTx, error) {
+	// the requests to the first url are distributed across all the urls by the client
 	txs, err := getXRPLPaymentTransactions(
-		ctx, fetcherPoolSize, rpcAPIURL, historicalAPIURL, account, currency, issuer, beforeDateTime, afterDateTime,
+		ctx, httpClient, fetcherPoolSize, rpcAPIURLs[0], historicalAPIURL, account, xrplReceivedTxType, currency, issuer,
+		beforeDateTime, afterDateTime,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	filteredTxs := filterXRPLBridgeTransactionsAndConvertToTxAudit(bridgeChainIndex, txs)
+	if err := crossCheckXRPLTxs(ctx, httpClient, rpcAPIURLs, txs, crossCheckSampleSize); err != nil {
+		return nil, err
+	}
+	txs = pinXRPLTxsToLedger(txs, ledgerIndex)
+
+	filteredTxs := filterXRPLBridgeTransactionsAndConvertToTxAudit(memoCodec, txs)
 	logger.Get(ctx).Info(fmt.Sprintf("Found xrpl txs total after bridge related filtration: %d", len(filteredTxs)))
+	if unvalidatedTxsCount := lo.CountBy(filteredTxs, isUnvalidatedXrplTx); unvalidatedTxsCount > 0 {
+		logger.Get(ctx).Warn(fmt.Sprintf("Found xrpl txs not validated or failed: %d", unvalidatedTxsCount))
+	}
 
 	return filteredTxs, nil
 }
 
-// GetXrplCurrencySupply returns the supply of the currency on xrpl.
-func GetXrplCurrencySupply(ctx context.Context, baseURL, issuer, currency string) (*big.Int, error) {
+// GetXRPLIssuerAuditTransactions returns the payments of the currency sent by the issuer (issued) and received by it
+// (burnt) converted to the audit model. The non-zero ledgerIndex pins the txs to the ledger.
+func GetXRPLIssuerAuditTransactions(
+	ctx context.Context,
+	httpClient *HTTPClient,
+	fetcherPoolSize, crossCheckSampleSize int,
+	rpcAPIURLs []string,
+	historicalAPIURL, currency, issuer string,
+	ledgerIndex int64,
+	beforeDateTime, afterDateTime time.Time,
+) ([]AuditTx, []AuditTx, error) {
+	txsByType := make(map[string][]AuditTx)
+	for _, txType := range []string{xrplSentTxType, xrplReceivedTxType} {
+		txs, err := getXRPLPaymentTransactions(
+			ctx, httpClient, fetcherPoolSize, rpcAPIURLs[0], historicalAPIURL, issuer, txType, currency, issuer,
+			beforeDateTime, afterDateTime,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := crossCheckXRPLTxs(ctx, httpClient, rpcAPIURLs, txs, crossCheckSampleSize); err != nil {
+			return nil, nil, err
+		}
+		txs = pinXRPLTxsToLedger(txs, ledgerIndex)
+
+		auditTxs := make([]AuditTx, 0, len(txs))
+		for _, tx := range txs {
+			if tx.Meta.DeliveredAmount.Currency != currency || tx.Meta.DeliveredAmount.Issuer != issuer {
+				continue
+			}
+			// the payments to self and the not validated or failed payments don't change the supply
+			if tx.Account == tx.Destination || !tx.Validated || tx.Meta.TransactionResult != xrplTxResultSuccess {
+				continue
+			}
+			if (txType == xrplSentTxType) != (tx.Account == issuer) {
+				continue
+			}
+			amount := convertFloatToSixDecimalsInt(tx.Meta.DeliveredAmount.Value)
+			if amount.Sign() != 1 {
+				continue
+			}
+			auditTxs = append(auditTxs, AuditTx{
+				Hash:        tx.Hash,
+				FromAddress: tx.Account,
+				ToAddress:   tx.Destination,
+				Amount:      amount,
+				Timestamp:   convertXRPLDateToTime(tx.Date),
+				Height:      tx.LedgerIndex,
+				Result:      xrplTxResult(tx),
+			})
+		}
+		sortAuditTxs(auditTxs)
+		txsByType[txType] = auditTxs
+	}
+	logger.Get(ctx).Info(fmt.Sprintf(
+		"Found xrpl issuer txs, issued: %d, burnt: %d", len(txsByType[xrplSentTxType]), len(txsByType[xrplReceivedTxType]),
+	))
+
+	return txsByType[xrplSentTxType], txsByType[xrplReceivedTxType], nil
+}
+
+// GetXRPLAuditTransactionsByHashes returns the xrpl bridge transactions with the provided hashes converted to the
+// audit model. The txs which aren't bridge txs are skipped.
+func GetXRPLAuditTransactionsByHashes(
+	ctx context.Context,
+	httpClient *HTTPClient,
+	rpcAPIURL string,
+	memoCodec MemoCodec,
+	txHashes []string,
+) ([]AuditTx, error) {
+	txs := make([]xrplTransaction, 0, len(txHashes))
+	for _, txHash := range txHashes {
+		tx, err := getXRPLTx(ctx, httpClient, rpcAPIURL, txHash)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+
+	return filterXRPLBridgeTransactionsAndConvertToTxAudit(memoCodec, txs), nil
+}
+
+// GetXrplCurrencySupplyAtLedger returns the supply of the currency on xrpl as of the ledger, using the rippled
+// gateway_balances method. The zero ledgerIndex is the latest validated ledger.
+func GetXrplCurrencySupplyAtLedger(
+	ctx context.Context,
+	httpClient *HTTPClient,
+	rpcAPIURL, issuer, currency string,
+	ledgerIndex int64,
+) (*big.Int, error) {
+	reqBody := xrplGatewayBalancesRequest{
+		Method: "gateway_balances",
+		Params: []xrplGatewayBalancesRequestParams{
+			{
+				Account:     issuer,
+				LedgerIndex: xrplLedgerIndex(ledgerIndex),
+				Strict:      true,
+			},
+		},
+	}
+	var resBody xrplGatewayBalancesResp
+	if err := httpClient.DoJSON(ctx, http.MethodPost, rpcAPIURL, reqBody, &resBody); err != nil {
+		return nil, err
+	}
+	if resBody.Result.Status != xrplResStatusSuccess {
+		return nil, errors.Errorf("receive unexpected result status: %s, error: %s", resBody.Result.Status, resBody.Result.Error)
+	}
+
+	value, ok := resBody.Result.Obligations[currency]
+	if !ok {
+		return nil, errors.Errorf("currency %s not found for %s at ledger %d", currency, issuer, ledgerIndex)
+	}
+	supply, ok := big.NewFloat(0).SetString(value)
+	if !ok {
+		return nil, errors.Errorf("can't parse supply %q of currency %s", value, currency)
+	}
+
+	return convertFloatToSixDecimalsInt(supply), nil
+}
+
+// GetXrplValidatedLedgerIndex returns the latest validated ledger index.
+func GetXrplValidatedLedgerIndex(ctx context.Context, httpClient *HTTPClient, rpcAPIURL string) (int64, error) {
+	reqBody := xrplServerInfoRequest{
+		Method: "server_info",
+		Params: []map[string]interface{}{{}},
+	}
+	var resBody xrplServerInfoResp
+	if err := httpClient.DoJSON(ctx, http.MethodPost, rpcAPIURL, reqBody, &resBody); err != nil {
+		return 0, err
+	}
+	if resBody.Result.Status != xrplResStatusSuccess {
+		return 0, errors.Errorf("receive unexpected result status: %s", resBody.Result.Status)
+	}
+
+	return resBody.Result.Info.ValidatedLedger.Seq, nil
+}
+
+// GetXrplCurrencySupply returns the supply of the currency on xrpl from the xrpscan API.
+func GetXrplCurrencySupply(ctx context.Context, httpClient *HTTPClient, baseURL, issuer, currency string) (*big.Int, error) {
 	url := fmt.Sprintf("%s/api/v1/account/%s/obligations", baseURL, issuer)
-	reqCtx, reqCtxCancel := context.WithTimeout(ctx, xrplRequestTimeout)
-	defer reqCtxCancel()
 	var resBody []xrplCurrencySupply
-	err := DoJSON(reqCtx, http.MethodGet, url, nil, &resBody)
+	err := httpClient.DoJSON(ctx, http.MethodGet, url, nil, &resBody)
 	if err != nil {
 		return nil, err
 	}
@@ -133,25 +349,21 @@ func GetXrplCurrencySupply(ctx context.Context, baseURL, issuer, currency string
 
 // filterXRPLBridgeTransactionsAndConvertToTxAudit filters the list of the xrpl transactions to leave the bridge only
 // and converts them all to tx audit transactions.
-func filterXRPLBridgeTransactionsAndConvertToTxAudit(bridgeChainIndex string, txs []xrplTransaction) []AuditTx {
+func filterXRPLBridgeTransactionsAndConvertToTxAudit(memoCodec MemoCodec, txs []xrplTransaction) []AuditTx {
 	filteredTxs := make([]AuditTx, 0)
 	for _, tx := range txs {
-		var (
-			memo    string
-			address string
-			ok      bool
-		)
-		for _, memoItem := range tx.Memos {
-			address, memo, ok = decodeXRPLBridgeMemo(memoItem.Memo.MemoData, bridgeChainIndex)
-			if ok {
-				break
-			}
-		}
+		address, memo, ok := memoCodec.DecodeXrplMemo(tx)
 		if !ok {
 			continue
 		}
 		amount := convertFloatToSixDecimalsInt(tx.Meta.DeliveredAmount.Value)
-		if amount.Cmp(big.NewInt(0)) != 1 {
+		result := xrplTxResult(tx)
+		// nothing is delivered by the unvalidated and failed txs, so they are reported with the amount to deliver
+		unvalidated := !tx.Validated || result != xrplTxResultSuccess
+		if unvalidated && amount.Sign() != 1 {
+			amount = convertFloatToSixDecimalsInt(tx.Amount.Value)
+		}
+		if amount.Sign() != 1 {
 			continue
 		}
 
@@ -165,30 +377,55 @@ func filterXRPLBridgeTransactionsAndConvertToTxAudit(bridgeChainIndex string, tx
 			Amount:        amount,
 			Memo:          memo,
 			Timestamp:     timestamp,
+			Height:        tx.LedgerIndex,
+			Result:        result,
+			Unvalidated:   !tx.Validated,
 		})
 	}
 
-	sort.Slice(filteredTxs, func(i, j int) bool {
-		return filteredTxs[i].Timestamp.After(filteredTxs[j].Timestamp)
-	})
+	sortAuditTxs(filteredTxs)
 
 	return filteredTxs
 }
 
-// getXRPLPaymentTransactions fetches all payment transactions from xrpl for the specified account and fill them with
-// the full set of required attributes.
+// pinXRPLTxsToLedger skips the txs after the non-zero ledgerIndex, the historical API doesn't support the ledger index.
+func pinXRPLTxsToLedger(txs []xrplTransaction, ledgerIndex int64) []xrplTransaction {
+	if ledgerIndex == 0 {
+		return txs
+	}
+	pinnedTxs := make([]xrplTransaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.LedgerIndex <= ledgerIndex {
+			pinnedTxs = append(pinnedTxs, tx)
+		}
+	}
+
+	return pinnedTxs
+}
+
+// getXRPLPaymentTransactions fetches all payment transactions of the type (sent or received) from xrpl for the
+// specified account and fill them with the full set of required attributes.
 func getXRPLPaymentTransactions(
 	ctx context.Context,
+	httpClient *HTTPClient,
 	fetcherPoolSize int,
-	rpcAPIURL, historicalAPIURL, account, currency, issuer string,
+	rpcAPIURL, historicalAPIURL, account, txType, currency, issuer string,
 	beforeDateTime, afterDateTime time.Time,
 ) ([]xrplTransaction, error) {
 	log := logger.Get(ctx)
 	log.Info(fmt.Sprintf("Fetching xrpl txs before: %s, after: %s ...", beforeDateTime.Format(time.DateTime), afterDateTime.Format(time.DateTime)))
 
+	// the fetch context is cancelled on the first failed fetch to stop the in-flight and queued fetches
+	fetchCtx, fetchCtxCancel := context.WithCancel(ctx)
+	defer fetchCtxCancel()
+
 	wg := sync.WaitGroup{}
 	mu := sync.Mutex{}
 	txs := make([]xrplTransaction, 0)
+	var (
+		fetchErr     error
+		failedHashes []string
+	)
 
 	// allocate limited pool to fetch tx in parallel
 	workerPool := workerpool.New(fetcherPoolSize)
@@ -204,33 +441,51 @@ func getXRPLPaymentTransactions(
 
 		log.Info("Fetching", zap.String("Page", fmt.Sprintf("%d", page)))
 		txHashes, marker, err = getXRPLHistoricalPaymentTxHashes(
-			ctx, historicalAPIURL, account, currency, issuer, marker, beforeDateTime, afterDateTime,
+			fetchCtx, httpClient, historicalAPIURL, account, txType, currency, issuer, marker, beforeDateTime, afterDateTime,
 		)
 		if err != nil {
 			return nil, err
 		}
 		page++
+		// the txs are fetched in parallel, so each tx is put to its place to preserve the page order
+		pageTxs := make([]xrplTransaction, len(txHashes))
 		wg.Add(len(txHashes))
-		for _, txHash := range txHashes {
+		for i, txHash := range txHashes {
+			txIndex := i
 			txHashCopy := txHash
 			workerPool.Submit(
 				func() {
 					defer wg.Done()
-					var tx xrplTransaction
-					tx, err = getXRPLTxWithRetry(ctx, rpcAPIURL, txHashCopy)
-					if err != nil {
+					// the fetch is cancelled, skip the queued tx
+					if fetchCtx.Err() != nil {
 						return
 					}
+					tx, err := getXRPLTx(fetchCtx, httpClient, rpcAPIURL, txHashCopy)
 					mu.Lock()
 					defer mu.Unlock()
-					txs = append(txs, tx)
+					if err != nil {
+						// the errors caused by the cancellation are the consequence of the first error
+						if fetchCtx.Err() != nil && errors.Is(err, context.Canceled) {
+							return
+						}
+						log.Error("Can't fetch xrpl tx", zap.String("Hash", txHashCopy), zap.Error(err))
+						failedHashes = append(failedHashes, txHashCopy)
+						fetchErr = multierror.Append(fetchErr, err)
+						fetchCtxCancel()
+						return
+					}
+					pageTxs[txIndex] = tx
 				},
 			)
 		}
 		wg.Wait()
-		if err != nil {
-			return nil, err
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrap(err, "fetching of the xrpl txs is interrupted")
 		}
+		if fetchErr != nil {
+			return nil, errors.Errorf("can't fetch xrpl txs, failed hashes: %s, err: %s", strings.Join(failedHashes, ","), fetchErr)
+		}
+		txs = append(txs, pageTxs...)
 		// if marker is empty no pages are left
 		if marker == "" {
 			break
@@ -244,14 +499,13 @@ func getXRPLPaymentTransactions(
 
 func getXRPLHistoricalPaymentTxHashes(
 	ctx context.Context,
-	baseURL, account, currency, issuer, marker string, beforeDateTime, afterDateTime time.Time,
+	httpClient *HTTPClient,
+	baseURL, account, txType, currency, issuer, marker string, beforeDateTime, afterDateTime time.Time,
 ) ([]string, string, error) {
 	url := fmt.Sprintf("%s/v2/accounts/%s/payments/?type=%s&currency=%s&issuer=%s&marker=%s&limit=%d&end=%s&start=%s",
-		baseURL, account, xrplReceivedTxType, currency, issuer, marker, xrplHistoricalDataPageLimit, beforeDateTime.Format(time.RFC3339), afterDateTime.Format(time.RFC3339))
-	reqCtx, reqCtxCancel := context.WithTimeout(ctx, xrplRequestTimeout)
-	defer reqCtxCancel()
+		baseURL, account, txType, currency, issuer, marker, xrplHistoricalDataPageLimit, beforeDateTime.Format(time.RFC3339), afterDateTime.Format(time.RFC3339))
 	var resBody xrplAccountTransactionsResp
-	err := DoJSON(reqCtx, http.MethodGet, url, nil, &resBody)
+	err := httpClient.DoJSON(ctx, http.MethodGet, url, nil, &resBody)
 	if err != nil {
 		return nil, "", err
 	}
@@ -266,7 +520,7 @@ func getXRPLHistoricalPaymentTxHashes(
 	return txs, resBody.Marker, nil
 }
 
-func getXRPLTxWithRetry(ctx context.Context, baseURL, txHash string) (xrplTransaction, error) {
+func getXRPLTx(ctx context.Context, httpClient *HTTPClient, baseURL, txHash string) (xrplTransaction, error) {
 	reqBody := xrplTransactionRequest{
 		Method: "tx",
 		Params: []xrplTransactionRequestParams{
@@ -277,24 +531,104 @@ func getXRPLTxWithRetry(ctx context.Context, baseURL, txHash string) (xrplTransa
 		},
 	}
 
-	var (
-		resBody xrplTransactionResp
-		err     error
-	)
-	for i := 0; i < xrplGetTxRetries; i++ {
-		reqCtx, reqCtxCancel := context.WithTimeout(ctx, xrplRequestTimeout)
-		err := DoJSON(reqCtx, http.MethodPost, baseURL, reqBody, &resBody)
-		reqCtxCancel()
-		if err == nil {
-			return resBody.Result, nil
+	var resBody xrplTransactionResp
+	// the transport errors are retried by the client, here we retry the errors returned by the xrpl node,
+	// since the node might be not synced or overloaded, except the errors of the missing tx or the invalid request
+	err := httpClient.RetryPolicy().Do(ctx, func(_ int) error {
+		if err := httpClient.DoJSON(ctx, http.MethodPost, baseURL, reqBody, &resBody); err != nil {
+			return Permanent(err)
 		}
-		if i != xrplGetTxRetries-1 {
-			<-time.After(xrplGetTxRetryTimout)
+		if resBody.Result.Status == xrplResStatusError {
+			err := errors.Errorf("xrpl node responded with error: %s", resBody.Result.Error)
+			if lo.Contains(xrplPermanentErrors, resBody.Result.Error) {
+				return Permanent(err)
+			}
+			return err
 		}
+
+		return nil
+	})
+	if err != nil {
+		return xrplTransaction{}, errors.Wrapf(err, "can't get xrpl tx %s by hash", txHash)
 	}
 
-	return xrplTransaction{}, errors.Errorf("can't get xrpl tx %s by hash with %d retries and timeout %s, last response: %v, last err: %s",
-		txHash, xrplGetTxRetries, xrplGetTxRetryTimout, resBody, err)
+	return resBody.Result, nil
+}
+
+// crossCheckXRPLTxs fetches the sample of the txs from each of the xrpl RPC endpoints and compares them with the
+// already fetched txs. Any disagreement means that at least one endpoint can't be trusted, so the disagreements are
+// reported in the log, but don't stop the audit.
+func crossCheckXRPLTxs(
+	ctx context.Context,
+	httpClient *HTTPClient,
+	rpcAPIURLs []string,
+	txs []xrplTransaction,
+	sampleSize int,
+) error {
+	if sampleSize <= 0 || len(rpcAPIURLs) < 2 || len(txs) == 0 {
+		return nil
+	}
+	sampleTxs := sampleXRPLTxs(txs, sampleSize)
+
+	log := logger.Get(ctx)
+	log.Info(fmt.Sprintf("Cross-checking %d xrpl txs between %d endpoints", len(sampleTxs), len(rpcAPIURLs)))
+
+	disagreements := make([]string, 0)
+	for _, sampleTx := range sampleTxs {
+		expectedTx, err := json.Marshal(sampleTx)
+		if err != nil {
+			return errors.Errorf("can't marshal xrpl tx %s, err: %s", sampleTx.Hash, err)
+		}
+		for _, rpcAPIURL := range rpcAPIURLs {
+			tx, err := getXRPLTx(WithDirectEndpoint(ctx), httpClient, rpcAPIURL, sampleTx.Hash)
+			if err != nil {
+				return errors.Wrapf(err, "can't cross-check xrpl tx with %s", rpcAPIURL)
+			}
+			actualTx, err := json.Marshal(tx)
+			if err != nil {
+				return errors.Errorf("can't marshal xrpl tx %s, err: %s", tx.Hash, err)
+			}
+			if !bytes.Equal(expectedTx, actualTx) {
+				log.Error(
+					"Xrpl endpoints disagree on tx",
+					zap.String("Hash", sampleTx.Hash),
+					zap.String("URL", rpcAPIURL),
+					zap.ByteString("Expected", expectedTx),
+					zap.ByteString("Actual", actualTx),
+				)
+				disagreements = append(disagreements, fmt.Sprintf("%s@%s", sampleTx.Hash, rpcAPIURL))
+			}
+		}
+	}
+	if len(disagreements) != 0 {
+		log.Error(fmt.Sprintf(
+			"Xrpl endpoints disagree on %d of %d cross-checked txs: %s",
+			len(disagreements), len(sampleTxs), strings.Join(disagreements, ","),
+		))
+		return nil
+	}
+	log.Info("Xrpl endpoints agree on all the cross-checked txs")
+
+	return nil
+}
+
+// sampleXRPLTxs returns the evenly spaced sample of the txs sorted by hash, so the same txs are always cross-checked.
+func sampleXRPLTxs(txs []xrplTransaction, sampleSize int) []xrplTransaction {
+	sortedTxs := make([]xrplTransaction, len(txs))
+	copy(sortedTxs, txs)
+	sort.Slice(sortedTxs, func(i, j int) bool {
+		return sortedTxs[i].Hash < sortedTxs[j].Hash
+	})
+	if sampleSize >= len(sortedTxs) {
+		return sortedTxs
+	}
+
+	sampleTxs := make([]xrplTransaction, 0, sampleSize)
+	for i := 0; i < sampleSize; i++ {
+		sampleTxs = append(sampleTxs, sortedTxs[i*len(sortedTxs)/sampleSize])
+	}
+
+	return sampleTxs
 }
 
 func decodeXRPLBridgeMemo(hexMemo, bridgeChainIndex string) (string, string, bool) {
@@ -314,6 +648,15 @@ func decodeXRPLBridgeMemo(hexMemo, bridgeChainIndex string) (string, string, boo
 	return memoFragments[0], string(memo), true
 }
 
+// xrplTxResult returns the TransactionResult of the tx, or unknown if the tx doesn't have it.
+func xrplTxResult(tx xrplTransaction) string {
+	if tx.Meta.TransactionResult == "" {
+		return xrplTxResultUnknown
+	}
+
+	return tx.Meta.TransactionResult
+}
+
 func convertFloatToSixDecimalsInt(amount *big.Float) *big.Int {
 	if amount == nil {
 		return big.NewInt(0)
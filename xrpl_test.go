@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+)
+
+func TestGetXRPLAuditTransactions(t *testing.T) {
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	txs := make([]xrplTransaction, 0)
+	for i := 0; i < 5; i++ {
+		txs = append(txs, newFakeXrplBridgeTx(
+			fmt.Sprintf("HASH%d", i), fakeCoreumAddress(byte(i)), defaultBridgeChainIndex, "10.5", txTime.Add(time.Duration(i)*time.Hour),
+		))
+	}
+	// not a bridge tx
+	txs = append(txs, newFakeXrplBridgeTx("HASH5", fakeCoreumAddress(5), "1", "10", txTime))
+	server := newFakeXrplServer(t, 2, txs, nil)
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	httpClient := NewHTTPClient(HTTPConfig{})
+	auditTxs, err := GetXRPLAuditTransactions(
+		ctx,
+		httpClient,
+		3,
+		2,
+		[]string{server.URL},
+		server.URL,
+		defaultXrplAccount,
+		defaultXrplCurrency,
+		defaultXrplIssuer,
+		ColonMemoCodec{BridgeChainIndex: defaultBridgeChainIndex},
+		0,
+		txTime.Add(24*time.Hour),
+		txTime,
+	)
+	require.NoError(t, err)
+	require.Len(t, auditTxs, 5)
+	// the txs are sorted by timestamp desc
+	for i, auditTx := range auditTxs {
+		j := 4 - i
+		require.Equal(t, AuditTx{
+			Hash:          fmt.Sprintf("HASH%d", j),
+			FromAddress:   "rSenderAccount",
+			ToAddress:     defaultXrplAccount,
+			TargetAddress: fakeCoreumAddress(byte(j)),
+			Amount:        big.NewInt(10_500000),
+			Memo:          fmt.Sprintf("%s:%s", fakeCoreumAddress(byte(j)), defaultBridgeChainIndex),
+			Timestamp:     txTime.Add(time.Duration(j) * time.Hour),
+			Height:        fakeXrplLedgerIndexAt(txTime.Add(time.Duration(j) * time.Hour)),
+			Result:        xrplTxResultSuccess,
+		}, auditTx)
+	}
+}
+
+func TestFilterXRPLBridgeTransactionsWithResults(t *testing.T) {
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	validatedTx := newFakeXrplBridgeTx("HASH1", fakeCoreumAddress(1), defaultBridgeChainIndex, "10", txTime)
+	// the tx of the not yet validated ledger has no meta
+	unvalidatedTx := newFakeXrplBridgeTx("HASH2", fakeCoreumAddress(2), defaultBridgeChainIndex, "20", txTime)
+	unvalidatedTx.Validated = false
+	unvalidatedTx.Meta = xrplMeta{}
+	// the failed payment has the result, but doesn't have the delivered amount
+	failedTx := newFakeXrplBridgeTx("HASH3", fakeCoreumAddress(3), defaultBridgeChainIndex, "30", txTime)
+	failedTx.Meta = xrplMeta{TransactionResult: "tecPATH_PARTIAL"}
+	unknownResultTx := newFakeXrplBridgeTx("HASH4", fakeCoreumAddress(4), defaultBridgeChainIndex, "40", txTime)
+	unknownResultTx.Meta.TransactionResult = ""
+	// the validated payment which delivered nothing isn't bridged
+	emptyTx := newFakeXrplBridgeTx("HASH5", fakeCoreumAddress(5), defaultBridgeChainIndex, "50", txTime)
+	emptyTx.Meta.DeliveredAmount = xrplMetaDeliveredAmount{}
+
+	auditTxs := filterXRPLBridgeTransactionsAndConvertToTxAudit(
+		ColonMemoCodec{BridgeChainIndex: defaultBridgeChainIndex},
+		[]xrplTransaction{validatedTx, unvalidatedTx, failedTx, unknownResultTx, emptyTx},
+	)
+	require.Equal(t, []string{"HASH1", "HASH2", "HASH3", "HASH4"},
+		lo.Map(auditTxs, func(tx AuditTx, _ int) string { return tx.Hash }),
+	)
+	require.Equal(t, []string{xrplTxResultSuccess, xrplTxResultUnknown, "tecPATH_PARTIAL", xrplTxResultUnknown},
+		lo.Map(auditTxs, func(tx AuditTx, _ int) string { return tx.Result }),
+	)
+	require.Equal(t, []*big.Int{big.NewInt(10_000000), big.NewInt(20_000000), big.NewInt(30_000000), big.NewInt(40_000000)},
+		lo.Map(auditTxs, func(tx AuditTx, _ int) *big.Int { return tx.Amount }),
+	)
+	require.Equal(t, []bool{false, true, false, false},
+		lo.Map(auditTxs, func(tx AuditTx, _ int) bool { return tx.Unvalidated }),
+	)
+	require.Equal(t, []bool{false, true, true, true}, lo.Map(auditTxs, func(tx AuditTx, _ int) bool {
+		return isUnvalidatedXrplTx(tx)
+	}))
+}
+
+func TestGetXRPLAuditTransactionsAtLedger(t *testing.T) {
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	server := newFakeXrplServer(t, 10, []xrplTransaction{
+		newFakeXrplBridgeTx("HASH1", fakeCoreumAddress(1), defaultBridgeChainIndex, "10", txTime),
+		newFakeXrplBridgeTx("HASH2", fakeCoreumAddress(2), defaultBridgeChainIndex, "20", txTime.Add(time.Hour)),
+		newFakeXrplBridgeTx("HASH3", fakeCoreumAddress(3), defaultBridgeChainIndex, "30", txTime.Add(2*time.Hour)),
+	}, nil)
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	httpClient := NewHTTPClient(HTTPConfig{})
+	auditTxs, err := GetXRPLAuditTransactions(
+		ctx,
+		httpClient,
+		3,
+		0,
+		[]string{server.URL},
+		server.URL,
+		defaultXrplAccount,
+		defaultXrplCurrency,
+		defaultXrplIssuer,
+		ColonMemoCodec{BridgeChainIndex: defaultBridgeChainIndex},
+		fakeXrplLedgerIndexAt(txTime.Add(time.Hour)),
+		txTime.Add(24*time.Hour),
+		txTime.Add(-time.Hour),
+	)
+	require.NoError(t, err)
+	// the tx of the later ledger is skipped
+	require.Equal(t, []string{"HASH2", "HASH1"}, lo.Map(auditTxs, func(auditTx AuditTx, _ int) string {
+		return auditTx.Hash
+	}))
+}
+
+func TestGetXRPLAuditTransactionsByHashes(t *testing.T) {
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	server := newFakeXrplServer(t, 10, []xrplTransaction{
+		newFakeXrplBridgeTx("HASH1", fakeCoreumAddress(1), defaultBridgeChainIndex, "10", txTime),
+		newFakeXrplBridgeTx("HASH2", fakeCoreumAddress(2), defaultBridgeChainIndex, "20", txTime),
+	}, nil)
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	httpClient := NewHTTPClient(HTTPConfig{})
+	auditTxs, err := GetXRPLAuditTransactionsByHashes(ctx, httpClient, server.URL, ColonMemoCodec{BridgeChainIndex: defaultBridgeChainIndex}, []string{"HASH2"})
+	require.NoError(t, err)
+	require.Len(t, auditTxs, 1)
+	require.Equal(t, "HASH2", auditTxs[0].Hash)
+	require.Equal(t, big.NewInt(20_000000), auditTxs[0].Amount)
+
+	// the missing tx isn't retried
+	httpClient = NewHTTPClient(HTTPConfig{MaxRetries: 3, MinBackoff: time.Minute, MaxBackoff: time.Minute})
+	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err = GetXRPLAuditTransactionsByHashes(timeoutCtx, httpClient, server.URL, ColonMemoCodec{BridgeChainIndex: defaultBridgeChainIndex}, []string{"MISSING"})
+	require.ErrorContains(t, err, "txnNotFound")
+	require.NotContains(t, err.Error(), "retries are exhausted")
+}
+
+func TestCrossCheckXRPLTxs(t *testing.T) {
+	txTime := time.Date(2023, time.Month(6), 1, 10, 0, 0, 0, time.UTC)
+	txs := make([]xrplTransaction, 0)
+	for i := 0; i < 10; i++ {
+		tx := newFakeXrplBridgeTx(fmt.Sprintf("HASH%d", i), fakeCoreumAddress(byte(i)), defaultBridgeChainIndex, "10", txTime)
+		// the txs are already fetched with the tx method
+		tx.Status = xrplResStatusSuccess
+		txs = append(txs, tx)
+	}
+	// the sample is the same for any order of the txs
+	sampleTxs := sampleXRPLTxs(lo.Reverse(append([]xrplTransaction{}, txs...)), 3)
+	require.Equal(t, []string{"HASH0", "HASH3", "HASH6"}, lo.Map(sampleTxs, func(tx xrplTransaction, _ int) string {
+		return tx.Hash
+	}))
+	require.Len(t, sampleXRPLTxs(txs, 20), 10)
+
+	server := newFakeXrplServer(t, 10, txs, nil)
+	otherTxs := append([]xrplTransaction{}, txs...)
+	otherTxs[3].Meta.DeliveredAmount.Value = big.NewFloat(11)
+	otherServer := newFakeXrplServer(t, 10, otherTxs, nil)
+
+	// the disagreement is reported, but doesn't fail the audit
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := logger.WithLogger(context.Background(), zap.New(core))
+	httpClient := NewHTTPClient(HTTPConfig{})
+	require.NoError(t, crossCheckXRPLTxs(ctx, httpClient, []string{server.URL, otherServer.URL}, txs, 3))
+	require.Equal(t, 1, logs.FilterMessage("Xrpl endpoints disagree on tx").Len())
+	require.Equal(t, 1, logs.FilterMessageSnippet("Xrpl endpoints disagree on 1 of 3 cross-checked txs: HASH3@").Len())
+}
+
+func TestGetXrplCurrencySupply(t *testing.T) {
+	server := newFakeXrplServer(t, 10, nil, []xrplCurrencySupply{
+		{Currency: "USD", Value: big.NewFloat(1)},
+		{Currency: defaultXrplCurrency, Value: big.NewFloat(1234.5)},
+	})
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	httpClient := NewHTTPClient(HTTPConfig{})
+	supply, err := GetXrplCurrencySupply(ctx, httpClient, server.URL, defaultXrplIssuer, defaultXrplCurrency)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1234_500000), supply)
+
+	_, err = GetXrplCurrencySupply(ctx, httpClient, server.URL, defaultXrplIssuer, "EUR")
+	require.Error(t, err)
+}
+
+func TestGetXrplCurrencySupplyAtLedger(t *testing.T) {
+	server := newFakeXrplServer(t, 10, nil, nil)
+	server.SetLedgerObligations(100, map[string]string{
+		"USD":               "1",
+		defaultXrplCurrency: "1234.5",
+	})
+
+	ctx := logger.WithLogger(context.Background(), zap.NewNop())
+	httpClient := NewHTTPClient(HTTPConfig{})
+	supply, err := GetXrplCurrencySupplyAtLedger(ctx, httpClient, server.URL, defaultXrplIssuer, defaultXrplCurrency, 100)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1234_500000), supply)
+
+	// the zero ledger index is the latest validated
+	server.SetLedgerObligations(0, map[string]string{defaultXrplCurrency: "2000"})
+	supply, err = GetXrplCurrencySupplyAtLedger(ctx, httpClient, server.URL, defaultXrplIssuer, defaultXrplCurrency, 0)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(2000_000000), supply)
+
+	_, err = GetXrplCurrencySupplyAtLedger(ctx, httpClient, server.URL, defaultXrplIssuer, "EUR", 100)
+	require.ErrorContains(t, err, "not found")
+
+	_, err = GetXrplCurrencySupplyAtLedger(ctx, httpClient, server.URL, defaultXrplIssuer, defaultXrplCurrency, 101)
+	require.ErrorContains(t, err, "lgrNotFound")
+}
+
+func FuzzDecodeXRPLBridgeMemo(f *testing.F) {
+	f.Add(hex.EncodeToString([]byte("core1abc:"+defaultBridgeChainIndex)), defaultBridgeChainIndex)
+	f.Add(hex.EncodeToString([]byte("core1abc:1")), defaultBridgeChainIndex)
+	f.Add("not hex", defaultBridgeChainIndex)
+	f.Add("", "")
+
+	f.Fuzz(func(t *testing.T, hexMemo, bridgeChainIndex string) {
+		address, memo, ok := decodeXRPLBridgeMemo(hexMemo, bridgeChainIndex)
+		if !ok {
+			require.Empty(t, address)
+			require.Empty(t, memo)
+			return
+		}
+		decodedMemo, err := hex.DecodeString(hexMemo)
+		require.NoError(t, err)
+		require.Equal(t, string(decodedMemo), memo)
+		require.Equal(t, address+":"+bridgeChainIndex, memo)
+
+		// the encoded memo is decoded back
+		address2, memo2, ok := decodeXRPLBridgeMemo(hex.EncodeToString([]byte(memo)), bridgeChainIndex)
+		require.True(t, ok)
+		require.Equal(t, address, address2)
+		require.Equal(t, memo, memo2)
+	})
+}